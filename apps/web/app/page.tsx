@@ -1,7 +1,7 @@
 'use client';
 
 import { useRef, useState, useEffect } from 'react';
-import { Box, Button, VStack, Text, HStack } from '@chakra-ui/react';
+import { Box, Button, VStack, Text, HStack, Input } from '@chakra-ui/react';
 import { Room, RoomEvent, RemoteTrackPublication, RemoteAudioTrack } from 'livekit-client';
 
 export default function OnAir() {
@@ -17,6 +17,12 @@ export default function OnAir() {
   const [isRecording, setIsRecording] = useState(false);
   const [myClientId, setMyClientId] = useState<string>('');
   const [dialogueRequester, setDialogueRequester] = useState<string>('');
+  const [dialogueTopic, setDialogueTopic] = useState('');
+  const [waitingStanding, setWaitingStanding] = useState<{position: number, waiting: number} | null>(null);
+  const [screeningMessages, setScreeningMessages] = useState<Array<{role: string, text: string}>>([]);
+  const [screeningInput, setScreeningInput] = useState('');
+  const [screeningDone, setScreeningDone] = useState(false);
+  const [mixerState, setMixerState] = useState<{state: string, buses: Array<{name: string, gain_db: number, active: boolean}>} | null>(null);
   const mediaRecorderRef = useRef<MediaRecorder | null>(null);
   const audioChunksRef = useRef<Blob[]>([]);
   const roomRef = useRef<Room | null>(null);
@@ -24,6 +30,7 @@ export default function OnAir() {
   const subtitleTimeoutRef = useRef<NodeJS.Timeout | null>(null);
   const typewriterTimeoutRef = useRef<NodeJS.Timeout | null>(null);
   const currentSubtitleIdRef = useRef<string>('');
+  const myClientIdRef = useRef<string>('');
 
   const API_BASE = process.env.NEXT_PUBLIC_API_BASE || 'http://localhost:8080';
 
@@ -48,7 +55,24 @@ export default function OnAir() {
         // クライアントIDを保存
         if (data.client_id) {
           setMyClientId(data.client_id);
+          myClientIdRef.current = data.client_id;
         }
+        // 待合室での順番
+        if (data.position) {
+          setWaitingStanding({ position: data.position, waiting: data.waiting });
+        }
+      } else if (data.type === 'dialogue_request_denied') {
+        console.log('Dialogue request denied:', data.reason);
+        if (data.reason === 'screened_out') {
+          // スクリーニングで放送に出せないと判断された
+          setDialogueRequested(false);
+          setWaitingStanding(null);
+          setScreeningMessages(prev => [...prev, { role: 'screener', text: '申し訳ありません、今回はお繋ぎできません。' }]);
+        }
+      } else if (data.type === 'screening_message') {
+        // 待合室でのスクリーナーからの返事
+        setScreeningMessages(prev => [...prev, { role: data.role, text: data.text }]);
+        setScreeningDone(data.done);
       } else if (data.type === 'dialogue_end_ack') {
         console.log('Dialogue end acknowledged');
         setDialogueActive(false);
@@ -84,6 +108,8 @@ export default function OnAir() {
     
     broadcastWebsocket.onopen = () => {
       console.log('Broadcast WebSocket connected');
+      // 接続時に現在のテーマを取得
+      fetchTheme();
     };
     
     broadcastWebsocket.onmessage = (event) => {
@@ -97,7 +123,12 @@ export default function OnAir() {
         console.log('Request ID:', messageData.id);
         console.log('Client ID:', messageData.client_id);
         setDialogueActive(true);
-        setDialogueRequested(false);
+        // 待合室にいる他のリスナーは待ち続ける
+        if (messageData.client_id === myClientIdRef.current) {
+          setDialogueRequested(false);
+          setWaitingStanding(null);
+          setScreeningMessages([]);
+        }
         // 対話をリクエストしたクライアントを記録
         if (messageData.client_id) {
           setDialogueRequester(messageData.client_id);
@@ -107,11 +138,26 @@ export default function OnAir() {
         }
       } else if (data.type === 'dialogue_ended') {
         console.log('Dialogue ended via broadcast');
+        // 待合室にいるリスナーはリクエストしたまま
         setDialogueActive(false);
-        setDialogueRequested(false);
         setDialogueRequester('');
+      } else if (data.type === 'dialogue_waiting') {
+        // 待合室の順番（自分の client_id を探す）
+        const waiting = data.data.waiting || [];
+        const me = waiting.find((caller: {client_id: string}) => caller.client_id === myClientIdRef.current);
+        setWaitingStanding(me ? { position: me.position, waiting: waiting.length } : null);
+      } else if (data.type === 'theme_changed') {
+        console.log('Theme changed:', data.data.title);
+        setTheme({ title: data.data.title, color: data.data.color });
+      } else if (data.type === 'mixer_state') {
+        console.log('Mixer state:', data.data.event);
+        setMixerState(data.data.mixer);
       } else if (data.type === 'subtitle') {
         console.log('Subtitle received:', data.data.text);
+        // 掛け合いでは話者名を付けて表示
+        const subtitleText = data.data.speaker_name
+          ? `${data.data.speaker_name}：${data.data.text}`
+          : data.data.text;
         
         // 既存のタイムアウトをクリア
         if (subtitleTimeoutRef.current) {
@@ -135,14 +181,14 @@ export default function OnAir() {
         }
         
         // 新しい字幕を設定
-        setSubtitles(data.data.text);
+        setSubtitles(subtitleText);
         
         // 新しい字幕IDを生成
         const subtitleId = `subtitle-${Date.now()}-${Math.random().toString(36).substr(2, 9)}`;
         currentSubtitleIdRef.current = subtitleId;
         
         // タイプライター効果で字幕を表示
-        startTypewriterEffect(data.data.text, subtitleId);
+        startTypewriterEffect(subtitleText, subtitleId);
         
         // 50秒後に現在の字幕をクリア（フォールバック用）
         subtitleTimeoutRef.current = setTimeout(() => {
@@ -238,7 +284,17 @@ export default function OnAir() {
     typeNextChar();
   };
 
-  // 対話状態確認関数
+  // テーマ取得関数
+  const fetchTheme = async () => {
+    try {
+      const response = await fetch(`${API_BASE}/v1/theme`);
+      const data = await response.json();
+      setTheme({ title: data.title, color: data.color });
+    } catch (error) {
+      console.error('Failed to fetch theme:', error);
+    }
+  };
+
   const checkDialogueStatus = async () => {
     try {
       const response = await fetch(`${API_BASE}/v1/dialogue/status`);
@@ -320,16 +376,27 @@ export default function OnAir() {
     try {
       const message = {
         type: 'dialogue_request',
-        kind: 'dialogue'
+        kind: 'dialogue',
+        topic: dialogueTopic.trim()
       };
       ws.send(JSON.stringify(message));
       setDialogueRequested(true);
+      setScreeningMessages([]);
+      setScreeningDone(false);
       console.log('Dialogue request sent');
     } catch (error) {
       console.error('Failed to send dialogue request:', error);
     }
   }
 
+  function sendScreeningMessage() {
+    const text = screeningInput.trim();
+    if (!ws || !text || screeningDone) return;
+    ws.send(JSON.stringify({ type: 'screening_message', text }));
+    setScreeningMessages(prev => [...prev, { role: 'caller', text }]);
+    setScreeningInput('');
+  }
+
   async function endDialogue() {
     if (!ws || !dialogueActive) return;
     
@@ -366,6 +433,12 @@ export default function OnAir() {
           <Text fontSize="xl" color="gray.300">
             {theme.title}
           </Text>
+          {mixerState && (
+            <Text fontSize="sm" color="gray.400" mt={1}>
+              {mixerState.buses.filter(bus => bus.active).map(bus => bus.name).join(' / ')}
+              {mixerState.state === 'ducked' && ' — DUCK'}
+            </Text>
+          )}
         </Box>
 
         <HStack gap={4} justify="center">
@@ -391,17 +464,15 @@ export default function OnAir() {
             </Button>
           )}
 
-          {!dialogueRequested && !dialogueActive ? (
+          {dialogueActive && dialogueRequester === myClientId ? (
             <Button 
-              onClick={requestDialogue}
-              disabled={!connected}
-              colorScheme="yellow"
+              onClick={endDialogue}
+              colorScheme="red"
               size="lg"
-              bg="yellow.500"
-              _hover={{ bg: "yellow.600" }}
-              _disabled={{ bg: "gray.500" }}
+              bg="red.500"
+              _hover={{ bg: "red.600" }}
             >
-              💬 対話リクエスト
+              🔚 対話終了
             </Button>
           ) : dialogueRequested ? (
             <Button 
@@ -410,22 +481,75 @@ export default function OnAir() {
               size="lg"
               bg="orange.500"
             >
-              ⏳ 対話待機中...
+              {waitingStanding
+                ? `⏳ 対話待機中（${waitingStanding.position}番目 / ${waitingStanding.waiting}人）`
+                : '⏳ 対話待機中...'}
             </Button>
           ) : (
-            <Button 
-              onClick={endDialogue}
-              colorScheme="red"
-              size="lg"
-              bg="red.500"
-              _hover={{ bg: "red.600" }}
-            >
-              🔚 対話終了
-            </Button>
+            <>
+              {/* 事前質問：DJと何について話したいか（任意） */}
+              <Input
+                value={dialogueTopic}
+                onChange={(e) => setDialogueTopic(e.target.value)}
+                placeholder="何について話したい？（任意）"
+                maxLength={200}
+                size="lg"
+                width="xs"
+                bg="gray.800"
+                borderColor="gray.600"
+                color="white"
+                _placeholder={{ color: "gray.400" }}
+              />
+              <Button 
+                onClick={requestDialogue}
+                disabled={!connected}
+                colorScheme="yellow"
+                size="lg"
+                bg="yellow.500"
+                _hover={{ bg: "yellow.600" }}
+                _disabled={{ bg: "gray.500" }}
+              >
+                💬 対話リクエスト
+              </Button>
+            </>
           )}
 
         </HStack>
 
+        {/* 待合室：放送前にスタッフ（スクリーナー）と文字でやりとり */}
+        {screeningMessages.length > 0 && (
+          <Box bg="blackAlpha.600" p={4} borderRadius="md" border="1px solid" borderColor="whiteAlpha.200">
+            <Text fontSize="md" fontWeight="bold" mb={2} color="orange.200">
+              📞 オンエア前のスタッフとのやりとり
+            </Text>
+            <VStack gap={2} align="stretch" mb={3}>
+              {screeningMessages.map((m, i) => (
+                <Text key={i} fontSize="sm" color={m.role === 'caller' ? 'white' : 'orange.100'} textAlign={m.role === 'caller' ? 'right' : 'left'}>
+                  {m.text}
+                </Text>
+              ))}
+            </VStack>
+            {dialogueRequested && !screeningDone && (
+              <HStack gap={2}>
+                <Input
+                  value={screeningInput}
+                  onChange={(e) => setScreeningInput(e.target.value)}
+                  onKeyDown={(e) => { if (e.key === 'Enter') sendScreeningMessage(); }}
+                  placeholder="スタッフに返事をする"
+                  maxLength={500}
+                  bg="gray.800"
+                  borderColor="gray.600"
+                  color="white"
+                  _placeholder={{ color: "gray.400" }}
+                />
+                <Button onClick={sendScreeningMessage} bg="orange.500" _hover={{ bg: "orange.600" }}>
+                  送信
+                </Button>
+              </HStack>
+            )}
+          </Box>
+        )}
+
         <Box 
           bg="blackAlpha.600" 
           p={6} 
@@ -0,0 +1,37 @@
+-- SCHEDULE: 曜日ルール・日付指定の上書き・複数時間の枠
+-- day_of_week / date がどちらも NULL の行は毎日の基本枠
+-- 優先度: date（特番・祝日） > day_of_week > 毎日
+ALTER TABLE schedule
+ADD COLUMN IF NOT EXISTS hours INTEGER DEFAULT 1 NOT NULL;
+ALTER TABLE schedule
+ADD COLUMN IF NOT EXISTS day_of_week INTEGER;
+-- 0=日曜 .. 6=土曜
+ALTER TABLE schedule
+ADD COLUMN IF NOT EXISTS date DATE;
+ALTER TABLE schedule
+ADD COLUMN IF NOT EXISTS title TEXT;
+-- 番組表に表示する枠名
+-- 起動のたびに重複して投入されていた毎日の基本枠を整理（同じ開始時の最古の行を残す）
+DELETE FROM schedule a USING schedule b
+WHERE a.channel_id = b.channel_id
+    AND a.hour = b.hour
+    AND a.day_of_week IS NULL
+    AND b.day_of_week IS NULL
+    AND a.date IS NULL
+    AND b.date IS NULL
+    AND (a.created_at, a.id::text) > (b.created_at, b.id::text);
+ALTER TABLE schedule DROP CONSTRAINT IF EXISTS schedule_rule_check;
+ALTER TABLE schedule
+ADD CONSTRAINT schedule_rule_check CHECK (
+        hours >= 1
+        AND hour + hours <= 24
+        AND (
+            day_of_week IS NULL
+            OR day_of_week BETWEEN 0 AND 6
+        )
+        AND NOT (
+            day_of_week IS NOT NULL
+            AND date IS NOT NULL
+        )
+    );
+CREATE INDEX IF NOT EXISTS idx_schedule_channel_date ON schedule(channel_id, date);
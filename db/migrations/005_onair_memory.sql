@@ -0,0 +1,14 @@
+-- 放送の記憶（Host Agentの台本生成プロンプトに含める）
+-- script: 放送した台本 / caller: リスナーの投稿・通話 / summary: それ以前の時間帯の要約
+CREATE TABLE IF NOT EXISTS onair_memory (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    channel_id UUID REFERENCES channel(id) ON DELETE CASCADE,
+    kind TEXT CHECK (kind IN ('script', 'caller', 'summary')) NOT NULL,
+    topic TEXT,
+    text TEXT NOT NULL,
+    -- summary の対象期間
+    period_start TIMESTAMPTZ,
+    period_end TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_onair_memory_channel_kind ON onair_memory(channel_id, kind, created_at DESC);
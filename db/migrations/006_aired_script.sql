@@ -0,0 +1,11 @@
+-- 放送した台本（埋め込みベクトルで最近の台本との繰り返しを検出）
+CREATE TABLE IF NOT EXISTS aired_script (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    channel_id UUID REFERENCES channel(id) ON DELETE CASCADE,
+    topic TEXT,
+    text TEXT NOT NULL,
+    embed VECTOR(1536),
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS aired_script_embed_hnsw ON aired_script USING hnsw (embed vector_cosine_ops);
+CREATE INDEX IF NOT EXISTS idx_aired_script_channel_created ON aired_script(channel_id, created_at DESC);
@@ -0,0 +1,5 @@
+-- 放送で紹介した投稿（台本で紹介済みのものは候補から外す）
+ALTER TABLE submission
+ADD COLUMN IF NOT EXISTS featured_at TIMESTAMPTZ;
+ALTER TABLE submission
+ADD COLUMN IF NOT EXISTS featured_topic TEXT;
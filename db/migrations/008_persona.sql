@@ -0,0 +1,17 @@
+-- DJのペルソナ（名前・話し方・口癖・声・避ける話題）
+CREATE TABLE IF NOT EXISTS persona (
+    id TEXT PRIMARY KEY,
+    -- "aoi" など（schedule.persona から参照）
+    name TEXT NOT NULL,
+    style TEXT,
+    catchphrases JSONB NOT NULL DEFAULT '[]',
+    tts_voice TEXT,
+    -- OpenAI TTS の voice（nova など）
+    realtime_voice TEXT,
+    -- Realtime API の voice（marin など）
+    forbidden_topics JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMPTZ DEFAULT now()
+);
+-- 枠を担当するペルソナ（NULL なら既定のDJ）
+ALTER TABLE schedule
+ADD COLUMN IF NOT EXISTS persona TEXT;
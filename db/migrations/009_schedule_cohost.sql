@@ -0,0 +1,3 @@
+-- 掛け合いの相手のペルソナ（NULL ならDJ1人で進行）
+ALTER TABLE schedule
+ADD COLUMN IF NOT EXISTS cohost TEXT;
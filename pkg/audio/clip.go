@@ -0,0 +1,150 @@
+package audio
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Clip メモリ上のPCMをフレーム単位で再生するSource（ジングル・スイーパー・ベッド用）
+type Clip struct {
+	mu      sync.Mutex
+	samples []int16
+	frame   int
+	pos     int
+	loop    bool
+}
+
+// NewClip samples は format のPCM16。loop が true の場合は末尾まで来たら先頭に戻る
+func NewClip(samples []int16, format Format, frameDuration time.Duration, loop bool) *Clip {
+	return &Clip{
+		samples: samples,
+		frame:   format.Samples(frameDuration),
+		loop:    loop,
+	}
+}
+
+func (c *Clip) ReadFrame() []int16 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.samples) == 0 || c.frame <= 0 {
+		return nil
+	}
+	if c.pos >= len(c.samples) {
+		if !c.loop {
+			return nil
+		}
+		c.pos = 0
+	}
+
+	frame := make([]int16, c.frame)
+	for i := range frame {
+		if c.pos >= len(c.samples) {
+			if !c.loop {
+				break
+			}
+			c.pos = 0
+		}
+		frame[i] = c.samples[c.pos]
+		c.pos++
+	}
+	return frame
+}
+
+// Done 再生し終えたか（ループ再生では常に false）
+func (c *Clip) Done() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.loop && c.pos >= len(c.samples)
+}
+
+// Sweep スイーパー用の上昇スイープ音（ホワッシュ）を生成
+//
+// 音源ファイルが用意されていない場合のセグメント間のつなぎに使う。
+func Sweep(format Format, d time.Duration) []int16 {
+	n := format.Samples(d) / format.Channels
+	mono := make([]int16, n)
+
+	const (
+		startHz = 300.0
+		endHz   = 3000.0
+		peak    = 0.25 * math.MaxInt16
+	)
+	phase := 0.0
+	rnd := uint32(1)
+	for i := range mono {
+		t := float64(i) / float64(n)
+		freq := startHz * math.Pow(endHz/startHz, t)
+		phase += 2 * math.Pi * freq / float64(format.SampleRate)
+
+		// 軽いノイズを混ぜて風切り音らしくする
+		rnd = rnd*1664525 + 1013904223
+		noise := float64(int32(rnd>>16)-32768) / 32768
+
+		// 立ち上がり・立ち下がりのエンベロープ
+		env := math.Sin(math.Pi * t)
+		mono[i] = int16(peak * env * (0.7*math.Sin(phase) + 0.3*noise))
+	}
+	return Upmix(mono, format.Channels)
+}
+
+// Pad 音楽ベッド用の穏やかな和音（ループ可能）を生成
+//
+// 整数Hzの正弦波とゆっくりしたうねりで構成し、d が4秒の倍数なら継ぎ目なくループする。
+// ベッド用の音源が用意されていない場合に使う。
+func Pad(format Format, d time.Duration) []int16 {
+	n := format.Samples(d) / format.Channels
+	mono := make([]int16, n)
+
+	chord := []float64{220, 277, 330, 440}
+	const (
+		lfoHz = 0.25
+		peak  = 0.06 * math.MaxInt16
+	)
+	for i := range mono {
+		t := float64(i) / float64(format.SampleRate)
+		v := 0.0
+		for _, f := range chord {
+			v += math.Sin(2 * math.Pi * f * t)
+		}
+		swell := 0.75 + 0.25*math.Sin(2*math.Pi*lfoHz*t)
+		mono[i] = int16(peak * swell * v / float64(len(chord)))
+	}
+	return Upmix(mono, format.Channels)
+}
+
+// timeSignalSpacing 時報の予報音の間隔
+const timeSignalSpacing = 500 * time.Millisecond
+
+// TimeSignalLead 時報の鳴り始めから正報音までの時間（正時のこの時間前に鳴らし始める）
+const TimeSignalLead = 3 * timeSignalSpacing
+
+// TimeSignal 時報（440Hzの予報音3回と、TimeSignalLead の位置から880Hzの正報音）を生成
+func TimeSignal(format Format) []int16 {
+	const (
+		pip     = 100 * time.Millisecond
+		spacing = timeSignalSpacing
+		long    = 800 * time.Millisecond
+		peak    = 0.3 * math.MaxInt16
+	)
+	rate := float64(format.SampleRate)
+	mono := make([]int16, format.Samples(TimeSignalLead+long)/format.Channels)
+
+	tone := func(at time.Duration, d time.Duration, freq float64) {
+		start := format.Samples(at) / format.Channels
+		n := format.Samples(d) / format.Channels
+		for i := 0; i < n && start+i < len(mono); i++ {
+			t := float64(i) / rate
+			// 末尾に向けて減衰させてクリックを防ぐ
+			env := 1 - float64(i)/float64(n)
+			mono[start+i] = int16(peak * env * math.Sin(2*math.Pi*freq*t))
+		}
+	}
+	for i := 0; i < 3; i++ {
+		tone(time.Duration(i)*spacing, pip, 440)
+	}
+	tone(TimeSignalLead, long, 880)
+
+	return Upmix(mono, format.Channels)
+}
@@ -0,0 +1,55 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClipPlaysOnceAndPadsLastFrame(t *testing.T) {
+	format := Format{SampleRate: 1000, Channels: 1}
+	c := NewClip([]int16{1, 2, 3, 4, 5}, format, 4*time.Millisecond, false)
+
+	if got := c.ReadFrame(); len(got) != 4 || got[3] != 4 {
+		t.Fatalf("first frame = %v", got)
+	}
+	if got := c.ReadFrame(); len(got) != 4 || got[0] != 5 || got[1] != 0 {
+		t.Fatalf("last frame = %v", got)
+	}
+	if !c.Done() || c.ReadFrame() != nil {
+		t.Fatal("clip should be done")
+	}
+}
+
+func TestClipLoops(t *testing.T) {
+	format := Format{SampleRate: 1000, Channels: 1}
+	c := NewClip([]int16{1, 2, 3}, format, 4*time.Millisecond, true)
+
+	if got := c.ReadFrame(); got[3] != 1 {
+		t.Fatalf("looped frame = %v", got)
+	}
+	if c.Done() {
+		t.Fatal("looping clip should never be done")
+	}
+}
+
+func TestSweepLength(t *testing.T) {
+	got := Sweep(FormatRealtime, 500*time.Millisecond)
+	if len(got) != 12000 {
+		t.Fatalf("sweep = %d samples, want 12000", len(got))
+	}
+	if got[0] != 0 {
+		t.Errorf("sweep should start from silence, got %d", got[0])
+	}
+}
+
+func TestTimeSignalLength(t *testing.T) {
+	got := TimeSignal(Format{SampleRate: 8000, Channels: 2})
+	if len(got) != 2*8000*23/10 {
+		t.Fatalf("time signal = %d samples, want %d", len(got), 2*8000*23/10)
+	}
+	// 正報音は TimeSignalLead の位置から鳴り始める
+	lead := Format{SampleRate: 8000, Channels: 2}.Samples(TimeSignalLead)
+	if got[lead-1] != 0 || got[lead+2] == 0 {
+		t.Errorf("long tone does not start at %v", TimeSignalLead)
+	}
+}
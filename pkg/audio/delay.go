@@ -0,0 +1,143 @@
+package audio
+
+import (
+	"sync"
+	"time"
+)
+
+// quietPeak 追いつく時に捨ててよい静かなフレームの最大振幅（約-36dBFS）
+const quietPeak = 512
+
+// maxCatchUp 静かなフレームが来なくても、これ以上遅れたままなら残りをまとめて捨てる
+const maxCatchUp = 30 * time.Second
+
+// DelayState 送出の遅延の状態
+type DelayState struct {
+	Target   time.Duration `json:"target"`   // 目標の遅延（0なら生に戻す）
+	Buffered time.Duration `json:"buffered"` // 実際に遅れている長さ
+	Dumps    int           `json:"dumps"`    // これまでのダンプ回数
+}
+
+// Delay 番組出力を遅らせて送る Sink（放送事故の対策。遅延の間の音声は Dump で捨てられる）
+//
+// 遅延を作る間（Set の直後・Dump の後）は filler を送り、生に戻す時は
+// 静かなフレームを捨てて少しずつ追いつく。
+type Delay struct {
+	next          Sink
+	frameDuration time.Duration
+
+	mu      sync.Mutex
+	target  int       // 遅らせるフレーム数
+	queue   [][]int16 // まだ送っていないフレーム（古い順）
+	filler  Source
+	lagging int // 追いつこうとしているフレーム数
+	dumps   int
+}
+
+// NewDelay frameDuration は書き込まれる1フレームの長さ（ミキサーのフレームと同じ）
+func NewDelay(next Sink, frameDuration time.Duration) *Delay {
+	return &Delay{next: next, frameDuration: frameDuration}
+}
+
+// Set 遅延を d にする（増やす分は filler を送って作り、0 にすると生に戻る）
+func (d *Delay) Set(delay time.Duration, filler Source) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.target = int(delay / d.frameDuration)
+	d.filler = filler
+	d.lagging = 0
+}
+
+// Dump 遅延の間にたまった音声を送らずに捨て、filler を送りながら遅延を作り直す
+//
+// 捨てた長さを返す。
+func (d *Delay) Dump(filler Source) time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dumped := time.Duration(len(d.queue)) * d.frameDuration
+	d.queue = nil
+	d.filler = filler
+	d.lagging = 0
+	d.dumps++
+	return dumped
+}
+
+// State 現在の遅延
+func (d *Delay) State() DelayState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return DelayState{
+		Target:   time.Duration(d.target) * d.frameDuration,
+		Buffered: time.Duration(len(d.queue)) * d.frameDuration,
+		Dumps:    d.dumps,
+	}
+}
+
+func (d *Delay) WriteSample(sample []int16) error {
+	return d.next.WriteSample(d.shift(sample))
+}
+
+// shift sample を入れ、代わりに送るフレームを取り出す
+func (d *Delay) shift(sample []int16) []int16 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.target == 0 && len(d.queue) == 0 {
+		return sample
+	}
+	d.queue = append(d.queue, append([]int16(nil), sample...))
+
+	excess := len(d.queue) - d.target
+	if excess <= 0 {
+		// 遅延を作っている間はつなぎの音を送る
+		return d.fillerFrame(len(sample))
+	}
+
+	if excess > 1 {
+		// 目標より遅れている：静かなフレームを捨てて追いつく
+		for len(d.queue)-d.target > 1 && peak(d.queue[0]) <= quietPeak {
+			d.queue = d.queue[1:]
+		}
+		if d.target == 0 && len(d.queue) == 2 {
+			// 生に戻す時は最後の1フレームの遅れも残さない（残すと遅れたまま毎フレームをコピーし続ける）
+			d.queue = d.queue[1:]
+		}
+		d.lagging++
+		if time.Duration(d.lagging)*d.frameDuration >= maxCatchUp {
+			d.queue = d.queue[len(d.queue)-d.target-1:]
+		}
+	}
+	if len(d.queue)-d.target <= 1 {
+		d.lagging = 0
+	}
+
+	out := d.queue[0]
+	d.queue = d.queue[1:]
+	if len(d.queue) == 0 {
+		d.queue = nil
+	}
+	return out
+}
+
+// fillerFrame filler の次のフレーム（終わった・未設定なら無音）
+func (d *Delay) fillerFrame(samples int) []int16 {
+	frame := make([]int16, samples)
+	if d.filler != nil {
+		copy(frame, d.filler.ReadFrame())
+	}
+	return frame
+}
+
+func peak(samples []int16) int {
+	p := 0
+	for _, s := range samples {
+		v := int(s)
+		if v < 0 {
+			v = -v
+		}
+		if v > p {
+			p = v
+		}
+	}
+	return p
+}
@@ -0,0 +1,93 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+// collect Delay が次に送ったフレームの先頭サンプル
+type collect struct{ firsts []int16 }
+
+func (c *collect) WriteSample(sample []int16) error {
+	c.firsts = append(c.firsts, sample[0])
+	return nil
+}
+
+func frame(v int16) []int16 { return []int16{v, v} }
+
+func TestDelayHoldsFramesAndFillsTheGap(t *testing.T) {
+	out := &collect{}
+	d := NewDelay(out, 10*time.Millisecond)
+	filler := NewClip([]int16{-1}, Format{SampleRate: 100, Channels: 2}, 10*time.Millisecond, true)
+	d.Set(30*time.Millisecond, filler)
+
+	for v := int16(1); v <= 5; v++ {
+		d.WriteSample(frame(v))
+	}
+	want := []int16{-1, -1, -1, 1, 2}
+	for i, v := range want {
+		if out.firsts[i] != v {
+			t.Fatalf("sent %v, want %v", out.firsts, want)
+		}
+	}
+	if got := d.State().Buffered; got != 30*time.Millisecond {
+		t.Errorf("buffered = %v, want 30ms", got)
+	}
+}
+
+func TestDelayDumpDiscardsBufferedAudio(t *testing.T) {
+	out := &collect{}
+	d := NewDelay(out, 10*time.Millisecond)
+	d.Set(20*time.Millisecond, nil)
+	for v := int16(1); v <= 4; v++ {
+		d.WriteSample(frame(v))
+	}
+
+	if dumped := d.Dump(nil); dumped != 20*time.Millisecond {
+		t.Fatalf("dumped = %v, want 20ms", dumped)
+	}
+	for v := int16(5); v <= 7; v++ {
+		d.WriteSample(frame(v))
+	}
+	// 3, 4 は送られず、遅延を作り直す間は無音
+	want := []int16{0, 0, 1, 2, 0, 0, 5}
+	for i, v := range want {
+		if out.firsts[i] != v {
+			t.Fatalf("sent %v, want %v", out.firsts, want)
+		}
+	}
+	if d.State().Dumps != 1 {
+		t.Errorf("dumps = %d, want 1", d.State().Dumps)
+	}
+}
+
+func TestDelayCatchesUpOnQuietFrames(t *testing.T) {
+	out := &collect{}
+	d := NewDelay(out, 10*time.Millisecond)
+	d.Set(30*time.Millisecond, nil)
+	d.WriteSample(frame(1000))
+	d.WriteSample(frame(0))
+	d.WriteSample(frame(0))
+
+	d.Set(0, nil)
+	d.WriteSample(frame(2000))
+	if got := d.State().Buffered; got != 30*time.Millisecond {
+		t.Fatalf("loud frames should not be dropped, buffered = %v", got)
+	}
+	d.WriteSample(frame(3000))
+
+	// 先頭の音は送り、静かなフレームと最後の1フレームを捨てて生に戻る
+	want := []int16{0, 0, 0, 1000, 3000}
+	for i, v := range want {
+		if out.firsts[i] != v {
+			t.Fatalf("sent %v, want %v", out.firsts, want)
+		}
+	}
+	if got := d.State().Buffered; got != 0 {
+		t.Errorf("buffered = %v, want 0 after catching up", got)
+	}
+	d.WriteSample(frame(4000))
+	if got := out.firsts[len(out.firsts)-1]; got != 4000 {
+		t.Errorf("sent %d, want live frame 4000", got)
+	}
+}
@@ -0,0 +1,226 @@
+package audio
+
+import (
+	"fmt"
+	"time"
+)
+
+// Format PCM16 ストリームのフォーマット（サンプルレートとチャンネル数）
+type Format struct {
+	SampleRate int `json:"sample_rate"`
+	Channels   int `json:"channels"`
+}
+
+var (
+	// FormatRealtime OpenAI Realtime / TTS(pcm) の入出力フォーマット
+	FormatRealtime = Format{SampleRate: 24000, Channels: 1}
+	// FormatBrowser ブラウザのマイク入力（AudioContextの既定値）
+	FormatBrowser = Format{SampleRate: 48000, Channels: 1}
+	// FormatTelephone 電話音声
+	FormatTelephone = Format{SampleRate: 8000, Channels: 1}
+)
+
+func (f Format) Validate() error {
+	if f.SampleRate <= 0 {
+		return fmt.Errorf("invalid sample rate: %d", f.SampleRate)
+	}
+	if f.Channels != 1 && f.Channels != 2 {
+		return fmt.Errorf("unsupported channel count: %d", f.Channels)
+	}
+	return nil
+}
+
+// Samples 指定した長さに含まれるサンプル数（全チャンネル合計）
+func (f Format) Samples(d time.Duration) int {
+	return int(int64(f.SampleRate) * int64(d) / int64(time.Second) * int64(f.Channels))
+}
+
+// Duration サンプル数（全チャンネル合計）に対応する長さ
+func (f Format) Duration(samples int) time.Duration {
+	if f.SampleRate == 0 || f.Channels == 0 {
+		return 0
+	}
+	return time.Duration(int64(samples/f.Channels) * int64(time.Second) / int64(f.SampleRate))
+}
+
+func (f Format) String() string {
+	return fmt.Sprintf("%dHz/%dch", f.SampleRate, f.Channels)
+}
+
+// Downmix インターリーブされたマルチチャンネルPCMをモノラルに変換
+//
+// 末尾の揃わないフレームは捨てる（チャンクをまたぐストリームは Converter で変換する）。
+func Downmix(samples []int16, channels int) []int16 {
+	if channels <= 1 {
+		return samples
+	}
+
+	mono := make([]int16, len(samples)/channels)
+	for i := range mono {
+		sum := 0
+		for c := 0; c < channels; c++ {
+			sum += int(samples[i*channels+c])
+		}
+		mono[i] = int16(sum / channels)
+	}
+	return mono
+}
+
+// Upmix モノラルPCMを指定チャンネル数に複製
+func Upmix(mono []int16, channels int) []int16 {
+	if channels <= 1 {
+		return mono
+	}
+
+	out := make([]int16, len(mono)*channels)
+	for i, s := range mono {
+		for c := 0; c < channels; c++ {
+			out[i*channels+c] = s
+		}
+	}
+	return out
+}
+
+// Resampler モノラルPCMのサンプルレート変換（線形補間）
+//
+// チャンク境界をまたいで位相を保持するため、ストリームごとに1つ使う。
+// ダウンサンプル時は変換比に応じた移動平均で簡易的にエイリアシングを抑える。
+type Resampler struct {
+	from, to int
+	pos      int64   // 次の出力位置 × to（prev を 0、入力先頭を 1 とした位置）
+	prev     int16   // 前チャンク最後のサンプル
+	window   []int16 // ローパス用の直近入力
+	taps     int
+}
+
+func NewResampler(from, to int) *Resampler {
+	taps := 1
+	if from > to {
+		taps = (from + to - 1) / to
+	}
+	return &Resampler{
+		from: from,
+		to:   to,
+		pos:  int64(to),
+		taps: taps,
+	}
+}
+
+// Process 入力チャンクを変換して出力サンプルを返す
+func (r *Resampler) Process(in []int16) []int16 {
+	if r.from == r.to || len(in) == 0 {
+		return in
+	}
+
+	in = r.lowpass(in)
+
+	to := int64(r.to)
+	out := make([]int16, 0, len(in)*r.to/r.from+1)
+	for {
+		i := int(r.pos / to)
+		if i >= len(in) {
+			break
+		}
+		frac := float64(r.pos%to) / float64(to)
+
+		// prev, in[0], in[1], ... の i 番目と i+1 番目を補間
+		a := r.prev
+		if i > 0 {
+			a = in[i-1]
+		}
+		b := in[i]
+		out = append(out, int16(float64(a)+(float64(b)-float64(a))*frac))
+		r.pos += int64(r.from)
+	}
+
+	r.pos -= int64(len(in)) * to
+	r.prev = in[len(in)-1]
+	return out
+}
+
+// Reset ストリームの状態を初期化
+func (r *Resampler) Reset() {
+	r.pos = int64(r.to)
+	r.prev = 0
+	r.window = r.window[:0]
+}
+
+func (r *Resampler) lowpass(in []int16) []int16 {
+	if r.taps <= 1 {
+		return in
+	}
+
+	out := make([]int16, len(in))
+	for i, s := range in {
+		r.window = append(r.window, s)
+		if len(r.window) > r.taps {
+			r.window = r.window[1:]
+		}
+		sum := 0
+		for _, w := range r.window {
+			sum += int(w)
+		}
+		out[i] = int16(sum / len(r.window))
+	}
+	return out
+}
+
+// Converter フォーマット間の変換（ダウンミックス→リサンプル→アップミックス）
+type Converter struct {
+	from, to  Format
+	resampler *Resampler
+	carry     []byte  // ConvertBytes のチャンクで割れたサンプルの端数
+	partial   []int16 // チャンクで割れたマルチチャンネルのフレームの端数
+}
+
+func NewConverter(from, to Format) *Converter {
+	return &Converter{
+		from:      from,
+		to:        to,
+		resampler: NewResampler(from.SampleRate, to.SampleRate),
+	}
+}
+
+// Convert PCM16 サンプルを変換
+func (c *Converter) Convert(samples []int16) []int16 {
+	if c.from == c.to {
+		return samples
+	}
+
+	if ch := c.from.Channels; ch > 1 {
+		// フレームの途中で切れていれば、残りのチャンネルは次のチャンクとまとめてダウンミックスする
+		if len(c.partial) > 0 {
+			samples = append(append([]int16(nil), c.partial...), samples...)
+		}
+		n := len(samples) - len(samples)%ch
+		c.partial = append(c.partial[:0], samples[n:]...)
+		samples = samples[:n]
+	}
+
+	mono := Downmix(samples, c.from.Channels)
+	mono = c.resampler.Process(mono)
+	return Upmix(mono, c.to.Channels)
+}
+
+// ConvertBytes little-endian PCM16 バイト列を変換
+//
+// サンプルの途中で切れたチャンクの端数は次の呼び出しに回す。
+func (c *Converter) ConvertBytes(pcm []byte) []byte {
+	if c.from == c.to && len(c.carry) == 0 && len(pcm)%2 == 0 {
+		return pcm
+	}
+	return SamplesToBytes(c.convertAligned(pcm))
+}
+
+// convertAligned 端数を引き継いでバイト列をサンプルにし、変換する
+func (c *Converter) convertAligned(pcm []byte) []int16 {
+	samples, rest := alignBytes(c.carry, pcm)
+	c.carry = rest
+	return c.Convert(samples)
+}
+
+func (c *Converter) Reset() {
+	c.resampler.Reset()
+	c.carry = nil
+	c.partial = nil
+}
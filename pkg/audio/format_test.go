@@ -0,0 +1,117 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFormatSamplesAndDuration(t *testing.T) {
+	f := Format{SampleRate: 48000, Channels: 2}
+
+	if got := f.Samples(20 * time.Millisecond); got != 1920 {
+		t.Errorf("samples = %d, want 1920", got)
+	}
+	if got := f.Duration(1920); got != 20*time.Millisecond {
+		t.Errorf("duration = %v, want 20ms", got)
+	}
+}
+
+func TestResamplerOutputLength(t *testing.T) {
+	cases := []struct {
+		from, to int
+		in, want int
+	}{
+		{48000, 24000, 4800, 2400},
+		{8000, 24000, 800, 2400},
+		{44100, 24000, 4410, 2400},
+	}
+	for _, c := range cases {
+		out := NewResampler(c.from, c.to).Process(make([]int16, c.in))
+		// 補間のため末尾の数サンプルは次のチャンクに持ち越される
+		if d := len(out) - c.want; d < -3 || d > 1 {
+			t.Errorf("%d->%d: got %d samples, want ~%d", c.from, c.to, len(out), c.want)
+		}
+	}
+}
+
+func TestResamplerIsContinuousAcrossChunks(t *testing.T) {
+	in := make([]int16, 960)
+	for i := range in {
+		in[i] = int16(i * 10)
+	}
+
+	whole := NewResampler(8000, 24000).Process(in)
+
+	r := NewResampler(8000, 24000)
+	var chunked []int16
+	for i := 0; i < len(in); i += 160 {
+		chunked = append(chunked, r.Process(in[i:i+160])...)
+	}
+
+	if !reflect.DeepEqual(whole, chunked) {
+		t.Fatalf("chunked output differs from one-shot output (%d vs %d samples)", len(chunked), len(whole))
+	}
+}
+
+func TestConverterDownmixesStereo(t *testing.T) {
+	c := NewConverter(Format{SampleRate: 24000, Channels: 2}, FormatRealtime)
+
+	got := c.Convert([]int16{100, 300, -200, 0})
+	want := []int16{200, -100}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("converted = %v, want %v", got, want)
+	}
+}
+
+func TestConverterKeepsPartialFrames(t *testing.T) {
+	c := NewConverter(Format{SampleRate: 24000, Channels: 2}, FormatRealtime)
+
+	// フレームの途中で切れたチャンク
+	got := c.Convert([]int16{100, 300, -200})
+	got = append(got, c.Convert([]int16{0, 50})...)
+	got = append(got, c.Convert([]int16{150})...)
+	want := []int16{200, -100, 100}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("converted = %v, want %v", got, want)
+	}
+
+	c.Convert([]int16{1000})
+	c.Reset()
+	if got := c.Convert([]int16{10, 30}); !reflect.DeepEqual(got, []int16{20}) {
+		t.Errorf("after reset converted = %v, want [20]", got)
+	}
+}
+
+func TestDecodeWAV(t *testing.T) {
+	samples := []int16{1, -2, 3, -4}
+	data := SamplesToBytes(samples)
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+len(data)))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1))     // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(2))     // channels
+	binary.Write(&buf, binary.LittleEndian, uint32(44100)) // sample rate
+	binary.Write(&buf, binary.LittleEndian, uint32(44100*4))
+	binary.Write(&buf, binary.LittleEndian, uint16(4))
+	binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+
+	format, got, err := DecodeWAV(&buf)
+	if err != nil {
+		t.Fatalf("DecodeWAV: %v", err)
+	}
+	if format != (Format{SampleRate: 44100, Channels: 2}) {
+		t.Errorf("format = %v", format)
+	}
+	if !reflect.DeepEqual(got, samples) {
+		t.Errorf("samples = %v, want %v", got, samples)
+	}
+}
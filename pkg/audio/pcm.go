@@ -0,0 +1,53 @@
+package audio
+
+// BytesToSamples little-endian の PCM16 バイト列を int16 サンプルに変換
+func BytesToSamples(b []byte) []int16 {
+	samples := make([]int16, len(b)/2)
+	for i := range samples {
+		samples[i] = int16(uint16(b[2*i]) | uint16(b[2*i+1])<<8)
+	}
+	return samples
+}
+
+// alignBytes 前回の端数バイトと pcm をつなぎ、サンプル単位で変換する
+//
+// チャンクがサンプルの途中で切れていれば、残りの1バイトを次回に回す端数として返す
+// （捨てると以降のサンプルがずれてノイズになる）。
+func alignBytes(carry, pcm []byte) ([]int16, []byte) {
+	if len(carry) > 0 {
+		pcm = append(append([]byte(nil), carry...), pcm...)
+	}
+	n := len(pcm) &^ 1
+	var rest []byte
+	if n < len(pcm) {
+		rest = []byte{pcm[n]}
+	}
+	return BytesToSamples(pcm[:n]), rest
+}
+
+// SamplesToBytes int16 サンプルを little-endian の PCM16 バイト列に変換
+func SamplesToBytes(samples []int16) []byte {
+	b := make([]byte, len(samples)*2)
+	for i, s := range samples {
+		b[2*i] = byte(s)
+		b[2*i+1] = byte(uint16(s) >> 8)
+	}
+	return b
+}
+
+// Attenuate サンプルにゲインを掛ける（クリップ付き）
+func Attenuate(samples []int16, gain float64) {
+	for i, s := range samples {
+		samples[i] = clip16(float64(s) * gain)
+	}
+}
+
+func clip16(v float64) int16 {
+	if v > 32767 {
+		return 32767
+	}
+	if v < -32768 {
+		return -32768
+	}
+	return int16(v)
+}
@@ -0,0 +1,33 @@
+package audio
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSamplesRoundTrip(t *testing.T) {
+	samples := []int16{0, 1, -1, 32767, -32768, 1234}
+
+	got := BytesToSamples(SamplesToBytes(samples))
+	if !reflect.DeepEqual(got, samples) {
+		t.Fatalf("round trip = %v, want %v", got, samples)
+	}
+}
+
+func TestBytesToSamplesLittleEndian(t *testing.T) {
+	got := BytesToSamples([]byte{0x34, 0x12, 0xff, 0xff, 0x01})
+	want := []int16{0x1234, -1}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("samples = %v, want %v", got, want)
+	}
+}
+
+func TestAttenuateClips(t *testing.T) {
+	samples := []int16{1000, -1000, 30000, -30000}
+
+	Attenuate(samples, 2.0)
+	want := []int16{2000, -2000, 32767, -32768}
+	if !reflect.DeepEqual(samples, want) {
+		t.Fatalf("attenuated = %v, want %v", samples, want)
+	}
+}
@@ -0,0 +1,320 @@
+package audio
+
+import (
+	"context"
+	"encoding/base64"
+	"log"
+	"sync"
+	"time"
+)
+
+// Source フレーム単位で音声を供給するもの（Mixerのバス入力）
+//
+// 再生するものがない時は nil を返す。
+type Source interface {
+	ReadFrame() []int16
+}
+
+// Sink 再生フレームの書き込み先（LiveKitのPCMLocalTrackなど）
+type Sink interface {
+	WriteSample(sample []int16) error
+}
+
+// SinkFunc 関数をSinkとして扱うためのアダプタ
+type SinkFunc func(sample []int16) error
+
+func (f SinkFunc) WriteSample(sample []int16) error {
+	return f(sample)
+}
+
+type PlayerState string
+
+const (
+	PlayerStateIdle      PlayerState = "idle"
+	PlayerStateBuffering PlayerState = "buffering"
+	PlayerStatePlaying   PlayerState = "playing"
+	PlayerStatePaused    PlayerState = "paused"
+)
+
+// PlayerConfig 再生エンジンの設定
+type PlayerConfig struct {
+	Format        Format        // 出力フォーマット
+	FrameDuration time.Duration // 1フレームの長さ（20ms）
+	Prebuffer     time.Duration // 再生開始前に溜めるジッタバッファ量
+	Gain          float64       // 出力ゲイン（1.0 = 等倍）
+}
+
+// DefaultPlayerConfig 24kHz mono / 20msフレーム / 200msプリバッファ / -6dB
+func DefaultPlayerConfig() PlayerConfig {
+	return PlayerConfig{
+		Format:        FormatRealtime,
+		FrameDuration: 20 * time.Millisecond,
+		Prebuffer:     200 * time.Millisecond,
+		Gain:          0.5,
+	}
+}
+
+// PlayerStatus 再生状態のスナップショット
+type PlayerStatus struct {
+	State      PlayerState `json:"state"`
+	PositionMs int64       `json:"position_ms"`
+	BufferedMs int64       `json:"buffered_ms"`
+	Underruns  int         `json:"underruns"`
+}
+
+// Player 実時間でフレームを送出するPCM再生エンジン
+//
+// 書き込まれたPCMはバッファに溜められ、FrameDurationごとに1フレームずつSinkへ送られる。
+// Prebuffer分溜まるまで（または書き込みが途絶えるまで）再生を始めないことで、
+// Realtime APIのバースト的なデルタを吸収する。
+type Player struct {
+	mu        sync.Mutex
+	cfg       PlayerConfig
+	sink      Sink
+	buf       []int16
+	state     PlayerState
+	played    int64 // 再生済みサンプル数
+	lastWrite time.Time
+	starved   bool // 再生中にバッファが尽きた
+	underruns int
+	onIdle    func()
+	carry     []byte                // Write のチャンクで割れたサンプルの端数
+	inputs    map[Format]*Converter // 入力フォーマットごとの変換器（端数も入力ごとに持つ）
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func NewPlayer(sink Sink, cfg PlayerConfig) *Player {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Player{
+		cfg:    cfg,
+		sink:   sink,
+		state:  PlayerStateIdle,
+		inputs: make(map[Format]*Converter),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start 再生クロックを開始
+func (p *Player) Start() {
+	go func() {
+		ticker := time.NewTicker(p.cfg.FrameDuration)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.ctx.Done():
+				return
+			case now := <-ticker.C:
+				p.tick(now)
+			}
+		}
+	}()
+}
+
+// Close 再生クロックを停止
+func (p *Player) Close() {
+	p.cancel()
+}
+
+// SetOnIdle 再生するものがなくなった時のコールバックを設定
+func (p *Player) SetOnIdle(fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onIdle = fn
+}
+
+// Write little-endian PCM16 をバッファに追加
+//
+// サンプルの途中で切れたチャンクの端数は次の Write に回す。
+func (p *Player) Write(pcm []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	samples, rest := alignBytes(p.carry, pcm)
+	p.carry = rest
+	p.appendLocked(samples)
+}
+
+// WriteFrom 別フォーマットのPCM16を出力フォーマットに変換してバッファに追加
+func (p *Player) WriteFrom(pcm []byte, from Format) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conv, ok := p.inputs[from]
+	if !ok {
+		conv = NewConverter(from, p.cfg.Format)
+		p.inputs[from] = conv
+	}
+	p.appendLocked(conv.convertAligned(pcm))
+}
+
+// appendLocked サンプルをバッファに追加（ロック保持中に呼ぶ）
+func (p *Player) appendLocked(samples []int16) {
+	p.buf = append(p.buf, samples...)
+	p.lastWrite = time.Now()
+	if p.state == PlayerStateIdle {
+		p.state = PlayerStateBuffering
+	}
+}
+
+// Format 出力フォーマット
+func (p *Player) Format() Format {
+	return p.cfg.Format
+}
+
+// WriteB64Delta Base64エンコードされたPCM16をバッファに追加
+func (p *Player) WriteB64Delta(b64 string) error {
+	raw, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		log.Printf("Failed to decode base64 audio data: %v", err)
+		return err
+	}
+
+	p.Write(raw)
+	return nil
+}
+
+// Pause 再生を一時停止（バッファは保持）
+func (p *Player) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state = PlayerStatePaused
+}
+
+// Resume 一時停止から再開
+func (p *Player) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.state != PlayerStatePaused {
+		return
+	}
+	if len(p.buf) > 0 {
+		p.state = PlayerStateBuffering
+	} else {
+		p.state = PlayerStateIdle
+	}
+}
+
+// Flush バッファを破棄して発話を打ち切る
+func (p *Player) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	log.Printf("Flushing playout buffer (%v)", p.durationOf(len(p.buf)))
+	p.buf = p.buf[:0]
+	p.starved = false
+	p.carry = nil
+	for _, conv := range p.inputs {
+		conv.Reset()
+	}
+	if p.state != PlayerStatePaused {
+		p.state = PlayerStateIdle
+	}
+}
+
+// Position これまでに送出した音声の長さ
+func (p *Player) Position() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.durationOf(int(p.played))
+}
+
+// Buffered まだ送出されていない音声の長さ
+func (p *Player) Buffered() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.durationOf(len(p.buf))
+}
+
+func (p *Player) Status() PlayerStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return PlayerStatus{
+		State:      p.state,
+		PositionMs: p.durationOf(int(p.played)).Milliseconds(),
+		BufferedMs: p.durationOf(len(p.buf)).Milliseconds(),
+		Underruns:  p.underruns,
+	}
+}
+
+func (p *Player) frameSamples() int {
+	return p.cfg.Format.Samples(p.cfg.FrameDuration)
+}
+
+func (p *Player) durationOf(samples int) time.Duration {
+	return p.cfg.Format.Duration(samples)
+}
+
+// ReadFrame 1フレーム分を取り出す（Mixerのバスとして使う場合。Startは呼ばない）
+func (p *Player) ReadFrame() []int16 {
+	frame, onIdle := p.pull(time.Now())
+	if onIdle != nil {
+		go onIdle()
+	}
+	return frame
+}
+
+// tick 1フレーム分の時間経過を処理
+func (p *Player) tick(now time.Time) {
+	frame, onIdle := p.pull(now)
+
+	if frame != nil {
+		if err := p.sink.WriteSample(frame); err != nil {
+			log.Printf("Failed to write PCM16 sample: %v", err)
+		}
+	}
+	if onIdle != nil {
+		onIdle()
+	}
+}
+
+// pull 状態を進めて送出すべきフレームを返す
+func (p *Player) pull(now time.Time) ([]int16, func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var frame []int16
+	var onIdle func()
+
+	switch p.state {
+	case PlayerStateBuffering:
+		waited := now.Sub(p.lastWrite) >= p.cfg.Prebuffer
+		if p.durationOf(len(p.buf)) >= p.cfg.Prebuffer || (len(p.buf) > 0 && waited) {
+			if p.starved {
+				p.underruns++
+				p.starved = false
+			}
+			p.state = PlayerStatePlaying
+			frame = p.nextFrame()
+		} else if len(p.buf) == 0 && waited {
+			p.state = PlayerStateIdle
+			p.starved = false
+			onIdle = p.onIdle
+		}
+	case PlayerStatePlaying:
+		frame = p.nextFrame()
+	}
+
+	return frame, onIdle
+}
+
+// nextFrame バッファから1フレームを取り出す（ロック保持中に呼ぶ）
+func (p *Player) nextFrame() []int16 {
+	n := p.frameSamples()
+	frame := make([]int16, n)
+	copied := copy(frame, p.buf)
+	p.buf = p.buf[copied:]
+	p.played += int64(copied)
+
+	Attenuate(frame, p.cfg.Gain)
+
+	if len(p.buf) == 0 {
+		p.state = PlayerStateBuffering
+		p.starved = true
+	}
+	return frame
+}
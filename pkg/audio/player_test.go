@@ -0,0 +1,143 @@
+package audio
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type recordingSink struct {
+	frames [][]int16
+}
+
+func (s *recordingSink) WriteSample(sample []int16) error {
+	s.frames = append(s.frames, sample)
+	return nil
+}
+
+func testPlayer() (*Player, *recordingSink) {
+	sink := &recordingSink{}
+	p := NewPlayer(sink, PlayerConfig{
+		Format:        Format{SampleRate: 1000, Channels: 1},
+		FrameDuration: 10 * time.Millisecond, // 10 samples / frame
+		Prebuffer:     30 * time.Millisecond,
+		Gain:          1.0,
+	})
+	return p, sink
+}
+
+func TestPlayerPrebuffersBeforePlaying(t *testing.T) {
+	p, sink := testPlayer()
+	now := time.Now()
+
+	p.Write(make([]byte, 2*20)) // 20ms
+	p.tick(now)
+	if len(sink.frames) != 0 {
+		t.Fatalf("expected no output before prebuffer is filled, got %d frames", len(sink.frames))
+	}
+
+	p.Write(make([]byte, 2*20)) // 40ms total
+	p.tick(now)
+	if len(sink.frames) != 1 {
+		t.Fatalf("expected playback to start, got %d frames", len(sink.frames))
+	}
+	if got := p.Position(); got != 10*time.Millisecond {
+		t.Errorf("position = %v, want 10ms", got)
+	}
+	if got := p.Buffered(); got != 30*time.Millisecond {
+		t.Errorf("buffered = %v, want 30ms", got)
+	}
+}
+
+func TestPlayerStartsShortClipAfterWriteGap(t *testing.T) {
+	p, sink := testPlayer()
+
+	p.Write(make([]byte, 2*5))
+	p.tick(time.Now().Add(time.Second))
+	if len(sink.frames) != 1 || len(sink.frames[0]) != 10 {
+		t.Fatalf("expected one padded frame, got %v", sink.frames)
+	}
+	// 無音で埋めた分は再生位置に含めない
+	if got := p.Position(); got != 5*time.Millisecond {
+		t.Errorf("position = %v, want 5ms", got)
+	}
+}
+
+func TestPlayerPauseResumeFlush(t *testing.T) {
+	p, sink := testPlayer()
+	now := time.Now()
+
+	p.Write(make([]byte, 2*100))
+	p.tick(now)
+	p.Pause()
+	p.tick(now)
+	if len(sink.frames) != 1 {
+		t.Fatalf("expected paused player to stop output, got %d frames", len(sink.frames))
+	}
+
+	p.Resume()
+	p.tick(now)
+	if len(sink.frames) != 2 {
+		t.Fatalf("expected output after resume, got %d frames", len(sink.frames))
+	}
+
+	p.Flush()
+	p.tick(now)
+	if len(sink.frames) != 2 || p.Buffered() != 0 {
+		t.Fatalf("expected flush to discard audio, frames=%d buffered=%v", len(sink.frames), p.Buffered())
+	}
+	if st := p.Status().State; st != PlayerStateIdle {
+		t.Errorf("state = %s, want idle", st)
+	}
+}
+
+func TestPlayerKeepsOddBytesAcrossWrites(t *testing.T) {
+	p, _ := testPlayer()
+	want := []int16{1000, -2000, 3000, -4000}
+	pcm := SamplesToBytes(want)
+
+	// サンプルの途中で切れたチャンク（端数は入力ごとに持つ）
+	other := Format{SampleRate: 1000, Channels: 2}
+	p.Write(pcm[:3])
+	p.WriteFrom(SamplesToBytes([]int16{500, 700})[:1], other)
+	p.Write(pcm[3:7])
+	p.WriteFrom(SamplesToBytes([]int16{500, 700})[1:], other)
+	p.Write(pcm[7:])
+
+	got := append([]int16(nil), p.buf...)
+	wantBuf := []int16{1000, -2000, 3000, 600, -4000}
+	if !reflect.DeepEqual(got, wantBuf) {
+		t.Fatalf("buffer = %v, want %v", got, wantBuf)
+	}
+
+	p.Write(pcm[:1])
+	p.Flush()
+	p.Write(pcm[:2])
+	if got := p.buf; !reflect.DeepEqual(got, []int16{1000}) {
+		t.Errorf("after flush buffer = %v, want [1000]", got)
+	}
+}
+
+func TestPlayerCountsUnderrunAndGoesIdle(t *testing.T) {
+	p, _ := testPlayer()
+	now := time.Now()
+	idle := false
+	p.SetOnIdle(func() { idle = true })
+
+	p.Write(make([]byte, 2*30))
+	for i := 0; i < 3; i++ {
+		p.tick(now)
+	}
+	p.Write(make([]byte, 2*30))
+	p.tick(now)
+	if got := p.Status().Underruns; got != 1 {
+		t.Errorf("underruns = %d, want 1", got)
+	}
+
+	p.tick(now)
+	p.tick(now)
+	p.tick(now.Add(time.Second))
+	if !idle {
+		t.Error("expected idle callback once the buffer drained")
+	}
+}
@@ -0,0 +1,70 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// DecodeWAV 16bit リニアPCMのWAVを読み込み、フォーマットとサンプルを返す
+func DecodeWAV(r io.Reader) (Format, []int16, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return Format{}, nil, fmt.Errorf("failed to read WAV header: %w", err)
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return Format{}, nil, fmt.Errorf("not a RIFF/WAVE file")
+	}
+
+	var format Format
+	var haveFormat bool
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return Format{}, nil, fmt.Errorf("failed to read WAV chunk: %w", err)
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "fmt ":
+			body := make([]byte, size)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return Format{}, nil, fmt.Errorf("failed to read fmt chunk: %w", err)
+			}
+			if len(body) < 16 {
+				return Format{}, nil, fmt.Errorf("fmt chunk too short")
+			}
+			audioFormat := binary.LittleEndian.Uint16(body[0:2])
+			bitsPerSample := binary.LittleEndian.Uint16(body[14:16])
+			if audioFormat != 1 || bitsPerSample != 16 {
+				return Format{}, nil, fmt.Errorf("unsupported WAV encoding (format=%d, bits=%d)", audioFormat, bitsPerSample)
+			}
+			format = Format{
+				SampleRate: int(binary.LittleEndian.Uint32(body[4:8])),
+				Channels:   int(binary.LittleEndian.Uint16(body[2:4])),
+			}
+			if err := format.Validate(); err != nil {
+				return Format{}, nil, err
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return Format{}, nil, fmt.Errorf("data chunk before fmt chunk")
+			}
+			data := make([]byte, size)
+			n, err := io.ReadFull(r, data)
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return Format{}, nil, fmt.Errorf("failed to read data chunk: %w", err)
+			}
+			return format, BytesToSamples(data[:n]), nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return Format{}, nil, fmt.Errorf("failed to skip %q chunk: %w", id, err)
+			}
+		}
+		if size%2 == 1 && id == "fmt " {
+			io.CopyN(io.Discard, r, 1)
+		}
+	}
+}
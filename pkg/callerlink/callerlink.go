@@ -0,0 +1,425 @@
+// Package callerlink API と Host の間で通話者の音声・コミット・通話の状態遷移を運ぶ常時接続のストリーム
+//
+// API が Host の /caller/stream に WebSocket でつなぎ、1本の接続でフレームを順番に送る。
+// 音声はバイナリメッセージ（ヘッダ＋PCM）、それ以外は JSON のテキストメッセージ。
+// Host はフレームを受け取った順に処理してから ack を返し、API は ack の届いていない
+// フレームが Window 件に達したら送るのを待つ（Host の処理が詰まった分だけ API 側で止まる）。
+// 逆向きには Host で起きた状態遷移を control フレームで送り、API が処理結果を ack で返す。
+package callerlink
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/radio24/pkg/dialogue"
+)
+
+// Path Host 側のエンドポイント
+const Path = "/caller/stream"
+
+// DefaultWindow ack を待たずに送れるフレーム数（20msの音声で約0.6秒分）
+const DefaultWindow = 32
+
+// Kind フレームの種類
+type Kind string
+
+const (
+	KindAudio    Kind = "audio"    // 通話者の音声（PCM16）
+	KindCommit   Kind = "commit"   // 音声入力のコミット
+	KindDialogue Kind = "dialogue" // APIで起きた通話の状態遷移
+	KindControl  Kind = "control"  // Host → API：Hostで起きた通話の状態遷移
+	KindAck      Kind = "ack"      // Seq のフレームを処理した（control には API が返す）
+)
+
+// Frame ストリームで送る1件のメッセージ
+type Frame struct {
+	Kind       Kind                 `json:"kind"`
+	Seq        uint64               `json:"seq"`                   // 接続ごとに1から振る通し番号
+	SampleRate int                  `json:"sample_rate,omitempty"` // audio：0ならHost側で24kHzとして扱う
+	Channels   int                  `json:"channels,omitempty"`    // audio：0ならmono
+	Audio      []byte               `json:"-"`
+	Dialogue   *dialogue.Transition `json:"dialogue,omitempty"`
+	Error      string               `json:"error,omitempty"`  // ack：処理に失敗した理由
+	Status     int                  `json:"status,omitempty"` // control の ack：API での処理結果（HTTP と同じステータスコード）
+}
+
+var (
+	// ErrNotConnected Host とのストリームがつながっていない
+	ErrNotConnected = errors.New("caller stream not connected")
+	// ErrBackpressure Host の処理が追いつかず、時間内に送れなかった
+	ErrBackpressure = errors.New("caller stream backpressure")
+)
+
+// audioHeader 音声フレームのヘッダ（seq 8バイト・サンプルレート 4バイト・チャンネル数 2バイト）
+const audioHeader = 14
+
+func encode(f Frame) (int, []byte, error) {
+	if f.Kind == KindAudio {
+		data := make([]byte, audioHeader+len(f.Audio))
+		binary.BigEndian.PutUint64(data[0:], f.Seq)
+		binary.BigEndian.PutUint32(data[8:], uint32(f.SampleRate))
+		binary.BigEndian.PutUint16(data[12:], uint16(f.Channels))
+		copy(data[audioHeader:], f.Audio)
+		return websocket.BinaryMessage, data, nil
+	}
+	data, err := json.Marshal(f)
+	return websocket.TextMessage, data, err
+}
+
+func decode(messageType int, data []byte) (Frame, error) {
+	if messageType == websocket.BinaryMessage {
+		if len(data) < audioHeader {
+			return Frame{}, fmt.Errorf("short audio frame: %d bytes", len(data))
+		}
+		return Frame{
+			Kind:       KindAudio,
+			Seq:        binary.BigEndian.Uint64(data[0:]),
+			SampleRate: int(binary.BigEndian.Uint32(data[8:])),
+			Channels:   int(binary.BigEndian.Uint16(data[12:])),
+			Audio:      data[audioHeader:],
+		}, nil
+	}
+	var f Frame
+	if err := json.Unmarshal(data, &f); err != nil {
+		return Frame{}, fmt.Errorf("invalid frame: %w", err)
+	}
+	return f, nil
+}
+
+// URL HTTP の接続先（http://host:8080）からストリームの URL を作る
+func URL(base string) string {
+	base = strings.TrimRight(base, "/")
+	switch {
+	case strings.HasPrefix(base, "https://"):
+		base = "wss://" + strings.TrimPrefix(base, "https://")
+	case strings.HasPrefix(base, "http://"):
+		base = "ws://" + strings.TrimPrefix(base, "http://")
+	}
+	return base + Path
+}
+
+// Client API 側：Host へのストリームを張り続け、フレームを順番に送る
+type Client struct {
+	URL    string
+	Window int
+	// Control Host から届いた control フレームの処理（ステータスコードと失敗の理由を ack で返す）
+	Control func(f Frame) (int, error)
+
+	mu      sync.Mutex // conn・credits・seq の入れ替え
+	conn    *websocket.Conn
+	credits chan struct{} // 残りの送信枠（ack が届くと戻る）
+	closed  chan struct{} // 今の接続が切れたら閉じる
+	seq     uint64
+	wmu     sync.Mutex // 番号を振ってから書き込むまでを1フレームずつ
+}
+
+func NewClient(url string, window int) *Client {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Client{URL: url, Window: window}
+}
+
+// Connected ストリームがつながっているか
+func (c *Client) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil
+}
+
+// Run ctx が終わるまで接続を保つ（切れたら1秒〜10秒の間隔でつなぎ直す）
+func (c *Client) Run(ctx context.Context) {
+	wait := time.Second
+	for ctx.Err() == nil {
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, c.URL, nil)
+		if err != nil {
+			log.Printf("Caller stream: failed to connect to %s: %v (retrying in %v)", c.URL, err, wait)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return
+			}
+			if wait *= 2; wait > 10*time.Second {
+				wait = 10 * time.Second
+			}
+			continue
+		}
+		wait = time.Second
+		log.Printf("Caller stream connected to %s", c.URL)
+
+		credits := make(chan struct{}, c.Window)
+		for i := 0; i < c.Window; i++ {
+			credits <- struct{}{}
+		}
+		closed := make(chan struct{})
+		c.mu.Lock()
+		c.conn, c.credits, c.closed, c.seq = conn, credits, closed, 0
+		c.mu.Unlock()
+
+		stop := context.AfterFunc(ctx, func() { conn.Close() })
+		err = c.read(conn, credits)
+		stop()
+
+		c.mu.Lock()
+		c.conn = nil
+		c.mu.Unlock()
+		close(closed)
+		conn.Close()
+		if ctx.Err() == nil {
+			log.Printf("Caller stream disconnected: %v", err)
+		}
+	}
+}
+
+// read Host からの ack を受け取って送信枠を戻し、control フレームは処理して ack を返す
+func (c *Client) read(conn *websocket.Conn, credits chan struct{}) error {
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		f, err := decode(messageType, data)
+		if err == nil && f.Kind == KindControl {
+			if err := c.handleControl(conn, f); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil || f.Kind != KindAck {
+			log.Printf("Caller stream: unexpected message from host: %v", err)
+			continue
+		}
+		if f.Error != "" {
+			log.Printf("Caller stream: host failed to handle frame %d: %s", f.Seq, f.Error)
+		}
+		select {
+		case credits <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// handleControl Host からの control フレームを処理して ack を返す
+func (c *Client) handleControl(conn *websocket.Conn, f Frame) error {
+	ack := Frame{Kind: KindAck, Seq: f.Seq, Status: http.StatusNotImplemented, Error: "control frames are not handled"}
+	if c.Control != nil {
+		status, err := c.Control(f)
+		ack.Status, ack.Error = status, ""
+		if err != nil {
+			ack.Error = err.Error()
+		}
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.WriteJSON(ack); err != nil {
+		return fmt.Errorf("failed to ack control frame %d: %w", f.Seq, err)
+	}
+	return nil
+}
+
+// Send フレームを送る（Seq はここで振る）
+//
+// ack を待っているフレームが Window 件に達していれば枠が空くまで待ち、
+// ctx が先に終われば ErrBackpressure を返す。つながっていなければ ErrNotConnected。
+func (c *Client) Send(ctx context.Context, f Frame) error {
+	c.mu.Lock()
+	conn, credits, closed := c.conn, c.credits, c.closed
+	c.mu.Unlock()
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	select {
+	case <-credits:
+	case <-closed:
+		return ErrNotConnected
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %d frames awaiting ack", ErrBackpressure, c.Window)
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.mu.Lock()
+	if c.conn != conn {
+		// 待っている間につなぎ直した
+		c.mu.Unlock()
+		return ErrNotConnected
+	}
+	c.seq++
+	f.Seq = c.seq
+	c.mu.Unlock()
+
+	messageType, data, err := encode(f)
+	if err != nil {
+		return err
+	}
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.WriteMessage(messageType, data); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to send %s frame: %w", f.Kind, err)
+	}
+	return nil
+}
+
+// Server Host 側：API からのストリームを受け付ける
+//
+// API からのフレームは届いた順に handle してから ack を返す（handle のエラーは ack の Error で伝え、
+// ストリームは続ける）。同じ接続で Host から API へ control フレームも送れる。
+type Server struct {
+	handle   func(Frame) error
+	upgrader websocket.Upgrader
+
+	mu   sync.Mutex // conn の入れ替え
+	conn *serverConn
+}
+
+// serverConn API からの1本の接続
+type serverConn struct {
+	ws      *websocket.Conn
+	wmu     sync.Mutex // 書き込みを1フレームずつ
+	mu      sync.Mutex // seq・pending
+	seq     uint64
+	pending map[uint64]chan Frame // API からの ack を待っている control フレーム
+	closed  chan struct{}
+}
+
+func NewServer(handle func(Frame) error) *Server {
+	return &Server{
+		handle:   handle,
+		upgrader: websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }},
+	}
+}
+
+// Connected API からのストリームがつながっているか
+func (s *Server) Connected() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn != nil
+}
+
+// Send API へ control フレームを送り、API からの ack を返す（Seq はここで振る）
+//
+// つながっていなければ ErrNotConnected。ack が届く前に ctx が終われば ctx のエラーを返す
+// （API が処理したかどうかはわからない）。
+func (s *Server) Send(ctx context.Context, f Frame) (Frame, error) {
+	s.mu.Lock()
+	c := s.conn
+	s.mu.Unlock()
+	if c == nil {
+		return Frame{}, ErrNotConnected
+	}
+
+	c.mu.Lock()
+	c.seq++
+	f.Seq = c.seq
+	acked := make(chan Frame, 1)
+	c.pending[f.Seq] = acked
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, f.Seq)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(f); err != nil {
+		c.ws.Close()
+		return Frame{}, fmt.Errorf("failed to send %s frame: %w", f.Kind, err)
+	}
+	select {
+	case ack := <-acked:
+		return ack, nil
+	case <-c.closed:
+		return Frame{}, ErrNotConnected
+	case <-ctx.Done():
+		return Frame{}, fmt.Errorf("no ack for %s frame %d: %w", f.Kind, f.Seq, ctx.Err())
+	}
+}
+
+func (c *serverConn) write(f Frame) error {
+	messageType, data, err := encode(f)
+	if err != nil {
+		return err
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.ws.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	return c.ws.WriteMessage(messageType, data)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Caller stream upgrade failed: %v", err)
+		return
+	}
+	defer ws.Close()
+	log.Printf("Caller stream opened from %s", r.RemoteAddr)
+
+	c := &serverConn{ws: ws, pending: map[uint64]chan Frame{}, closed: make(chan struct{})}
+	s.mu.Lock()
+	s.conn = c
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		if s.conn == c {
+			s.conn = nil
+		}
+		s.mu.Unlock()
+		close(c.closed)
+	}()
+
+	var last uint64
+	for {
+		messageType, data, err := ws.ReadMessage()
+		if err != nil {
+			log.Printf("Caller stream closed: %v", err)
+			return
+		}
+		f, err := decode(messageType, data)
+		if err != nil {
+			// 送信枠を戻すため、読めなかったフレームにも ack を返す
+			log.Printf("Caller stream: %v", err)
+			if err := c.write(Frame{Kind: KindAck, Error: err.Error()}); err != nil {
+				return
+			}
+			continue
+		}
+		if f.Kind == KindAck {
+			// Host から送った control フレームへの ack
+			c.mu.Lock()
+			acked, ok := c.pending[f.Seq]
+			c.mu.Unlock()
+			if ok {
+				select {
+				case acked <- f:
+				default:
+				}
+			}
+			continue
+		}
+		if f.Seq != last+1 {
+			log.Printf("Caller stream: frame %d arrived after %d", f.Seq, last)
+		}
+		last = f.Seq
+
+		ack := Frame{Kind: KindAck, Seq: f.Seq}
+		if err := s.handle(f); err != nil {
+			ack.Error = err.Error()
+		}
+		if err := c.write(ack); err != nil {
+			log.Printf("Caller stream: failed to ack frame %d: %v", f.Seq, err)
+			return
+		}
+	}
+}
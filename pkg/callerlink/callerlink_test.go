@@ -0,0 +1,168 @@
+package callerlink
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/radio24/pkg/dialogue"
+)
+
+// connect httptest の Host につないだ Client
+func connect(t *testing.T, window int, handle func(Frame) error) *Client {
+	t.Helper()
+	c := NewClient("", window)
+	link(t, NewServer(handle), c)
+	return c
+}
+
+// link httptest で動かした host に c をつなぐ
+func link(t *testing.T, host *Server, c *Client) {
+	t.Helper()
+	srv := httptest.NewServer(host)
+	c.URL = URL(srv.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		srv.Close()
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !c.Connected() || !host.Connected() {
+		if time.Now().After(deadline) {
+			t.Fatal("caller stream did not connect")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFramesArriveInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var got []Frame
+	received := make(chan struct{}, 16)
+	c := connect(t, DefaultWindow, func(f Frame) error {
+		mu.Lock()
+		got = append(got, f)
+		mu.Unlock()
+		received <- struct{}{}
+		return nil
+	})
+
+	ctx := context.Background()
+	frames := []Frame{
+		{Kind: KindAudio, SampleRate: 48000, Channels: 2, Audio: []byte{1, 2, 3, 4}},
+		{Kind: KindAudio, Audio: []byte{5, 6}},
+		{Kind: KindCommit},
+		{Kind: KindDialogue, Dialogue: &dialogue.Transition{ID: "req-1", Event: dialogue.EventWrapUp}},
+	}
+	for _, f := range frames {
+		if err := c.Send(ctx, f); err != nil {
+			t.Fatalf("Send(%s) = %v", f.Kind, err)
+		}
+	}
+	for range frames {
+		select {
+		case <-received:
+		case <-time.After(2 * time.Second):
+			t.Fatal("frames not delivered")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, f := range got {
+		if f.Seq != uint64(i+1) || f.Kind != frames[i].Kind {
+			t.Errorf("frame %d = %s #%d, want %s #%d", i, f.Kind, f.Seq, frames[i].Kind, i+1)
+		}
+	}
+	if got[0].SampleRate != 48000 || got[0].Channels != 2 || string(got[0].Audio) != "\x01\x02\x03\x04" {
+		t.Errorf("audio frame = %+v", got[0])
+	}
+	if got[3].Dialogue == nil || got[3].Dialogue.ID != "req-1" {
+		t.Errorf("dialogue frame = %+v", got[3])
+	}
+}
+
+func TestSendWaitsForAcks(t *testing.T) {
+	release := make(chan struct{})
+	c := connect(t, 2, func(f Frame) error {
+		<-release
+		return nil
+	})
+	defer close(release)
+
+	ctx := context.Background()
+	c.Send(ctx, Frame{Kind: KindAudio, Audio: []byte{0, 0}})
+	c.Send(ctx, Frame{Kind: KindAudio, Audio: []byte{0, 0}})
+
+	// Host が処理しない間は Window を超えて送らない
+	short, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := c.Send(short, Frame{Kind: KindAudio, Audio: []byte{0, 0}}); !errors.Is(err, ErrBackpressure) {
+		t.Fatalf("Send() while host is stalled = %v", err)
+	}
+
+	release <- struct{}{}
+	wait, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := c.Send(wait, Frame{Kind: KindCommit}); err != nil {
+		t.Errorf("Send() after ack = %v", err)
+	}
+}
+
+func TestSendWithoutConnection(t *testing.T) {
+	c := NewClient("ws://127.0.0.1:1"+Path, 0)
+	if err := c.Send(context.Background(), Frame{Kind: KindCommit}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Send() = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestControlFramesAckedByAPI(t *testing.T) {
+	host := NewServer(func(Frame) error { return nil })
+	var got []dialogue.Event
+	c := NewClient("", 0)
+	c.Control = func(f Frame) (int, error) {
+		got = append(got, f.Dialogue.Event)
+		if f.Dialogue.Event == dialogue.EventAccept {
+			return http.StatusConflict, errors.New("not waiting")
+		}
+		return http.StatusOK, nil
+	}
+	link(t, host, c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ack, err := host.Send(ctx, Frame{Kind: KindControl, Dialogue: &dialogue.Transition{ID: "req-1", Event: dialogue.EventAccept}})
+	if err != nil || ack.Status != http.StatusConflict || ack.Error != "not waiting" {
+		t.Fatalf("Send(accept) = %+v, %v; want 409", ack, err)
+	}
+	ack, err = host.Send(ctx, Frame{Kind: KindControl, Dialogue: &dialogue.Transition{ID: "req-1", Event: dialogue.EventEnd}})
+	if err != nil || ack.Status != http.StatusOK || ack.Seq != 2 {
+		t.Fatalf("Send(end) = %+v, %v; want 200 for frame 2", ack, err)
+	}
+	if len(got) != 2 || got[1] != dialogue.EventEnd {
+		t.Errorf("API handled %v", got)
+	}
+
+	// API からのフレームも同じ接続で届く
+	if err := c.Send(ctx, Frame{Kind: KindCommit}); err != nil {
+		t.Errorf("Send(commit) = %v", err)
+	}
+}
+
+func TestServerSendWithoutConnection(t *testing.T) {
+	host := NewServer(func(Frame) error { return nil })
+	if _, err := host.Send(context.Background(), Frame{Kind: KindControl}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Send() = %v, want ErrNotConnected", err)
+	}
+}
@@ -0,0 +1,380 @@
+package dialogue
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultIdleTimeout 通話の音声が途切れてから終了するまでの時間（API・Host共通）
+const DefaultIdleTimeout = 3 * time.Minute
+
+// DefaultStuckTimeout 接続中・終了処理中のまま進まない通話を打ち切るまでの時間
+const DefaultStuckTimeout = time.Minute
+
+// State 通話（対話モード）の状態
+type State string
+
+const (
+	Requested  State = "requested"   // リスナーがリクエストし、キューで待っている
+	Connecting State = "connecting"  // Hostがキューから取り出し、Realtimeに接続中
+	OnAir      State = "on_air"      // 通話を放送中
+	WrappingUp State = "wrapping_up" // 終了が決まり、通話音声を下げて締めの挨拶中
+	Ended      State = "ended"
+)
+
+// Event 状態を進めるイベント
+type Event string
+
+const (
+	EventRequest   Event = "request"   // → requested（API：リスナーのリクエスト）
+	EventAccept    Event = "accept"    // requested → connecting（Host：キューから取り出した）
+	EventConnected Event = "connected" // connecting → on_air（Host：Realtimeの準備ができた）
+	EventWrapUp    Event = "wrap_up"   // on_air → wrapping_up（終了の要求・切断・無音タイムアウト）
+	EventEnd       Event = "end"       // → ended（締めの挨拶を終えた・接続の失敗など）
+)
+
+// 終了の理由
+const (
+	ReasonRequesterEnded        = "requester_ended"        // リクエストしたリスナーが終了した
+	ReasonRequesterDisconnected = "requester_disconnected" // リクエストしたリスナーの接続が切れた
+	ReasonTimeout               = "timeout"                // 無音のまま IdleTimeout が経った
+	ReasonConnectFailed         = "connect_failed"         // Realtimeに接続できなかった
+	ReasonConnectionLost        = "connection_lost"        // 通話中にRealtimeの接続が切れた
+	ReasonHostEnded             = "host_ended"             // Hostの /dialogue/end で終了した
+	ReasonScreenedOut           = "screened_out"           // 放送前のスクリーニングで不適切と判断した
+	ReasonRejected              = "rejected"               // APIが通話を受け付けなかった（リクエストを取り下げた後など）
+	ReasonHostLost              = "host_lost"              // Hostとの接続が切れたまま戻らなかった
+)
+
+// transitions 状態ごとに受け付けるイベントと遷移先
+//
+// 空の状態からの accept は、リクエストを知らない側（Host）が待合室から取り出した通話を始めるためのもの。
+var transitions = map[State]map[Event]State{
+	"":         {EventRequest: Requested, EventAccept: Connecting},
+	Requested:  {EventAccept: Connecting, EventEnd: Ended},
+	Connecting: {EventConnected: OnAir, EventWrapUp: WrappingUp, EventEnd: Ended},
+	OnAir:      {EventWrapUp: WrappingUp, EventEnd: Ended},
+	WrappingUp: {EventEnd: Ended},
+}
+
+// ErrInvalidTransition 今の状態では受け付けないイベント
+var ErrInvalidTransition = errors.New("invalid dialogue transition")
+
+// ErrBusy 別の通話が進行中
+var ErrBusy = errors.New("another dialogue is in progress")
+
+// ErrNotWaiting 待合室にいない（もう放送に出た・取り下げた）
+var ErrNotWaiting = errors.New("dialogue request is not waiting")
+
+// Session 1件の通話
+type Session struct {
+	ID           string    `json:"id"` // 対話リクエストのID（キューのアイテムID）
+	ClientID     string    `json:"client_id"`
+	State        State     `json:"state"`
+	Reason       string    `json:"reason,omitempty"`  // 終了の理由
+	Topic        string    `json:"topic,omitempty"`   // リクエスト時の事前質問「何について話したい？」への答え
+	Summary      string    `json:"summary,omitempty"` // 放送前のスクリーニングでまとめた話の内容（DJに渡す）
+	Cleared      bool      `json:"cleared,omitempty"` // 放送前のスクリーニングの判定を通った（通るまでは放送に出さない）
+	Picked       bool      `json:"picked,omitempty"`  // 待合室で次に出る人として選ばれた
+	RequestedAt  time.Time `json:"requested_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// Live 接続中・放送中・終了処理中のいずれか（同時に1件まで）
+func (s Session) Live() bool {
+	return s.State == Connecting || s.State == OnAir || s.State == WrappingUp
+}
+
+// Transition 状態遷移の通知（API と Host はお互いに自分で起こした遷移を送り、相手側で Apply する）
+type Transition struct {
+	ID       string    `json:"id"`
+	ClientID string    `json:"client_id,omitempty"`
+	Event    Event     `json:"event"`
+	From     State     `json:"from"`
+	To       State     `json:"to"`
+	Reason   string    `json:"reason,omitempty"`
+	Topic    string    `json:"topic,omitempty"` // request の時だけ
+	At       time.Time `json:"at"`
+}
+
+// Machine 通話の状態を管理する（API・Hostがそれぞれ1つ持ち、遷移を送りあって揃える）
+//
+// リクエスト中の通話は複数あってよく（待合室）、接続中・放送中・終了処理中は1件だけ。
+// 終わった通話は直近の1件だけ残す（状態確認用）。
+type Machine struct {
+	IdleTimeout  time.Duration // 放送中に音声が途切れてから終了処理に進めるまで（0なら無効）
+	StuckTimeout time.Duration // 接続中・終了処理中のまま進まない通話を終了するまで（0なら無効）
+	// OwnsRequests リクエストを受け付ける側（API）。待合室にいない通話の accept は受け付けない
+	OwnsRequests bool
+	OnTransition func(t Transition) // Fire・Expire で起きた遷移（Apply では呼ばない。ロックの外で呼ぶ）
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+	last     *Session
+	now      func() time.Time
+}
+
+func NewMachine(idleTimeout time.Duration) *Machine {
+	return &Machine{IdleTimeout: idleTimeout, StuckTimeout: DefaultStuckTimeout, sessions: map[string]*Session{}, now: time.Now}
+}
+
+// Request リスナーの通話リクエストを受け付ける（topic は事前質問への答え。空でもよい）
+func (m *Machine) Request(id, clientID, topic string) (Transition, error) {
+	return m.fire(Transition{ID: id, ClientID: clientID, Event: EventRequest, Topic: topic})
+}
+
+// Fire id の通話でイベントを起こす
+func (m *Machine) Fire(id string, event Event, reason string) (Transition, error) {
+	return m.fire(Transition{ID: id, Event: event, Reason: reason})
+}
+
+// Accept 待合室から選んだ通話の接続を始める
+func (m *Machine) Accept(id, clientID string) (Transition, error) {
+	return m.fire(Transition{ID: id, ClientID: clientID, Event: EventAccept})
+}
+
+func (m *Machine) fire(t Transition) (Transition, error) {
+	m.mu.Lock()
+	t.At = m.now()
+	t, err := m.applyLocked(t)
+	onTransition := m.OnTransition
+	m.mu.Unlock()
+
+	if err == nil && onTransition != nil {
+		onTransition(t)
+	}
+	return t, err
+}
+
+// Apply 相手側で起きた遷移を反映する（重複や順序の入れ替わりで受け付けられない遷移は ErrInvalidTransition）
+func (m *Machine) Apply(t Transition) (Transition, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t.At.IsZero() {
+		t.At = m.now()
+	}
+	return m.applyLocked(t)
+}
+
+func (m *Machine) applyLocked(t Transition) (Transition, error) {
+	s := m.sessions[t.ID]
+	var from State
+	if s != nil {
+		from = s.State
+	}
+	if s == nil && t.Event == EventAccept && m.OwnsRequests {
+		// 取り下げた・終わったリクエストを接続中の通話として作り直さない
+		return t, fmt.Errorf("%w: %s", ErrNotWaiting, t.ID)
+	}
+	to, ok := transitions[from][t.Event]
+	if !ok {
+		return t, fmt.Errorf("%w: %s on %q (%s)", ErrInvalidTransition, t.Event, from, t.ID)
+	}
+	if to == Connecting {
+		if live, ok := m.liveLocked(); ok && live.ID != t.ID {
+			return t, fmt.Errorf("%w: %s", ErrBusy, live.ID)
+		}
+	}
+
+	if s == nil {
+		s = &Session{ID: t.ID, ClientID: t.ClientID, Topic: t.Topic, RequestedAt: t.At}
+		m.sessions[t.ID] = s
+	}
+	if t.ClientID != "" {
+		s.ClientID = t.ClientID
+	}
+	s.State = to
+	s.Picked = false
+	s.UpdatedAt = t.At
+	s.LastActivity = t.At
+	if t.Reason != "" {
+		s.Reason = t.Reason
+	}
+	if to == Ended {
+		delete(m.sessions, t.ID)
+		m.last = s
+	}
+
+	t.From, t.To, t.ClientID, t.Topic = from, to, s.ClientID, s.Topic
+	return t, nil
+}
+
+// Touch 通話の音声が流れた（無音タイムアウトを延ばす）
+func (m *Machine) Touch(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[id]; ok {
+		s.LastActivity = m.now()
+	}
+}
+
+// Expire 進まなくなった通話を打ち切る
+//
+// 放送中のまま IdleTimeout 以上音声が途切れている通話は終了処理に進め、
+// 接続中・終了処理中のまま StuckTimeout 以上経った通話は終了する。
+func (m *Machine) Expire() []Transition {
+	m.mu.Lock()
+	events := map[string]Event{}
+	now := m.now()
+	for id, s := range m.sessions {
+		switch {
+		case s.State == OnAir && m.IdleTimeout > 0 && now.Sub(s.LastActivity) >= m.IdleTimeout:
+			events[id] = EventWrapUp
+		case (s.State == Connecting || s.State == WrappingUp) && m.StuckTimeout > 0 && now.Sub(s.UpdatedAt) >= m.StuckTimeout:
+			events[id] = EventEnd
+		}
+	}
+	m.mu.Unlock()
+
+	var expired []Transition
+	for id, event := range events {
+		if t, err := m.Fire(id, event, ReasonTimeout); err == nil {
+			expired = append(expired, t)
+		}
+	}
+	return expired
+}
+
+// Abandon clientID の通話を打ち切る（待っているリクエストは終了、進行中の通話は終了処理へ）
+func (m *Machine) Abandon(clientID, reason string) []Transition {
+	m.mu.Lock()
+	events := map[string]Event{}
+	for id, s := range m.sessions {
+		switch {
+		case s.ClientID != clientID:
+		case s.State == Requested:
+			events[id] = EventEnd
+		case s.State == OnAir || s.State == Connecting:
+			events[id] = EventWrapUp
+		}
+	}
+	m.mu.Unlock()
+
+	var abandoned []Transition
+	for id, event := range events {
+		if t, err := m.Fire(id, event, reason); err == nil {
+			abandoned = append(abandoned, t)
+		}
+	}
+	return abandoned
+}
+
+// Live 接続中・放送中・終了処理中の通話
+func (m *Machine) Live() (Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.liveLocked()
+}
+
+func (m *Machine) liveLocked() (Session, bool) {
+	for _, s := range m.sessions {
+		if s.Live() {
+			return *s, true
+		}
+	}
+	return Session{}, false
+}
+
+// Get id の通話（終わったものは直近の1件だけ）
+func (m *Machine) Get(id string) (Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[id]; ok {
+		return *s, true
+	}
+	if m.last != nil && m.last.ID == id {
+		return *m.last, true
+	}
+	return Session{}, false
+}
+
+// Status 状態確認用：進行中の通話（なければ直近に終わった通話）とリクエスト待ちの件数
+func (m *Machine) Status() (current *Session, waiting int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.sessions {
+		switch {
+		case s.Live():
+			live := *s
+			current = &live
+		case s.State == Requested:
+			waiting++
+		}
+	}
+	if current == nil && m.last != nil {
+		last := *m.last
+		current = &last
+	}
+	return current, waiting
+}
+
+// Waiting 待合室：リクエストして待っている通話（選ばれた人が先頭、あとはリクエスト順）
+func (m *Machine) Waiting() []Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.waitingLocked()
+}
+
+func (m *Machine) waitingLocked() []Session {
+	var waiting []Session
+	for _, s := range m.sessions {
+		if s.State == Requested {
+			waiting = append(waiting, *s)
+		}
+	}
+	sort.Slice(waiting, func(i, j int) bool {
+		a, b := waiting[i], waiting[j]
+		if a.Picked != b.Picked {
+			return a.Picked
+		}
+		if !a.RequestedAt.Equal(b.RequestedAt) {
+			return a.RequestedAt.Before(b.RequestedAt)
+		}
+		return a.ID < b.ID
+	})
+	return waiting
+}
+
+// Next 次に放送に出す通話（待合室でスクリーニングを通った人のうち先頭）
+func (m *Machine) Next() (Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.waitingLocked() {
+		if s.Cleared {
+			return s, true
+		}
+	}
+	return Session{}, false
+}
+
+// Pick 待合室の id を次に出す人に選ぶ（選び直すと前に選んだ人は元の順番に戻る）
+func (m *Machine) Pick(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok || s.State != Requested {
+		return fmt.Errorf("%w: %s", ErrNotWaiting, id)
+	}
+	for _, other := range m.sessions {
+		other.Picked = false
+	}
+	s.Picked = true
+	return nil
+}
+
+// Screen 待合室の id が放送前のスクリーニングを通った（まとめた話の内容を付け、放送に出せるようにする）
+func (m *Machine) Screen(id, summary string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok || s.State != Requested {
+		return fmt.Errorf("%w: %s", ErrNotWaiting, id)
+	}
+	s.Summary = summary
+	s.Cleared = true
+	return nil
+}
@@ -0,0 +1,237 @@
+package dialogue
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCallLifecycle(t *testing.T) {
+	m := NewMachine(DefaultIdleTimeout)
+	var fired []State
+	m.OnTransition = func(t Transition) { fired = append(fired, t.To) }
+
+	m.Request("req-1", "client-1", "")
+	m.Fire("req-1", EventAccept, "")
+	m.Fire("req-1", EventConnected, "")
+	if s, ok := m.Live(); !ok || s.State != OnAir || s.ClientID != "client-1" {
+		t.Fatalf("live = %+v, %v", s, ok)
+	}
+	m.Fire("req-1", EventWrapUp, ReasonRequesterEnded)
+	m.Fire("req-1", EventEnd, "")
+
+	want := []State{Requested, Connecting, OnAir, WrappingUp, Ended}
+	if len(fired) != len(want) {
+		t.Fatalf("fired = %v, want %v", fired, want)
+	}
+	for i := range want {
+		if fired[i] != want[i] {
+			t.Fatalf("fired = %v, want %v", fired, want)
+		}
+	}
+	if _, ok := m.Live(); ok {
+		t.Error("session still live after end")
+	}
+	if s, ok := m.Get("req-1"); !ok || s.State != Ended || s.Reason != ReasonRequesterEnded {
+		t.Errorf("ended session = %+v, %v", s, ok)
+	}
+}
+
+func TestInvalidTransitionsAreRejected(t *testing.T) {
+	m := NewMachine(DefaultIdleTimeout)
+	if _, err := m.Fire("req-1", EventConnected, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("connected before accept = %v", err)
+	}
+
+	m.Accept("req-1", "client-1")
+	m.Request("req-2", "client-2", "")
+	if _, err := m.Fire("req-2", EventAccept, ""); !errors.Is(err, ErrBusy) {
+		t.Errorf("second accept while live = %v", err)
+	}
+
+	m.Fire("req-1", EventEnd, ReasonConnectFailed)
+	if _, err := m.Fire("req-1", EventWrapUp, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("wrap up after end = %v", err)
+	}
+	if _, err := m.Fire("req-2", EventAccept, ""); err != nil {
+		t.Errorf("accept after previous call ended = %v", err)
+	}
+}
+
+func TestApplyMirrorsRemoteTransitions(t *testing.T) {
+	host := NewMachine(DefaultIdleTimeout)
+	api := NewMachine(DefaultIdleTimeout)
+	host.OnTransition = func(t Transition) {
+		if _, err := api.Apply(t); err != nil {
+			panic(err)
+		}
+	}
+	calls := 0
+	api.OnTransition = func(Transition) { calls++ }
+
+	host.Accept("req-1", "client-1")
+	host.Fire("req-1", EventConnected, "")
+	if s, ok := api.Live(); !ok || s.State != OnAir || s.ClientID != "client-1" {
+		t.Fatalf("api live = %+v, %v", s, ok)
+	}
+	if calls != 0 {
+		t.Errorf("Apply fired %d local transitions", calls)
+	}
+
+	// 重複して届いた遷移は受け付けない
+	if _, err := api.Apply(Transition{ID: "req-1", Event: EventConnected}); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("duplicate apply = %v", err)
+	}
+}
+
+func TestExpireWrapsUpIdleCall(t *testing.T) {
+	now := time.Now()
+	m := NewMachine(time.Minute)
+	m.now = func() time.Time { return now }
+
+	m.Accept("req-1", "client-1")
+	m.Fire("req-1", EventConnected, "")
+	now = now.Add(50 * time.Second)
+	m.Touch("req-1")
+	now = now.Add(50 * time.Second)
+	if expired := m.Expire(); len(expired) != 0 {
+		t.Fatalf("expired %v despite activity", expired)
+	}
+
+	now = now.Add(20 * time.Second)
+	expired := m.Expire()
+	if len(expired) != 1 || expired[0].To != WrappingUp || expired[0].Reason != ReasonTimeout {
+		t.Errorf("expired = %+v", expired)
+	}
+}
+
+func TestExpireEndsStuckCalls(t *testing.T) {
+	now := time.Now()
+	m := NewMachine(0)
+	m.now = func() time.Time { return now }
+
+	m.Accept("req-1", "client-1")
+	now = now.Add(DefaultStuckTimeout - time.Second)
+	if expired := m.Expire(); len(expired) != 0 {
+		t.Fatalf("expired %v before the stuck timeout", expired)
+	}
+	now = now.Add(time.Second)
+	if expired := m.Expire(); len(expired) != 1 || expired[0].From != Connecting || expired[0].To != Ended {
+		t.Fatalf("expired = %+v, want connecting call ended", expired)
+	}
+
+	m.Accept("req-2", "client-2")
+	m.Fire("req-2", EventConnected, "")
+	m.Fire("req-2", EventWrapUp, ReasonRequesterEnded)
+	now = now.Add(DefaultStuckTimeout)
+	if expired := m.Expire(); len(expired) != 1 || expired[0].From != WrappingUp || expired[0].To != Ended {
+		t.Errorf("expired = %+v, want wrapping up call ended", expired)
+	}
+}
+
+func TestOwnerRejectsAcceptForUnknownRequest(t *testing.T) {
+	api := NewMachine(DefaultIdleTimeout)
+	api.OwnsRequests = true
+
+	// 取り下げたリクエストに Host の accept が後から届いても作り直さない
+	api.Request("req-1", "client-1", "")
+	api.Fire("req-1", EventEnd, ReasonRequesterEnded)
+	if _, err := api.Apply(Transition{ID: "req-1", ClientID: "client-1", Event: EventAccept}); !errors.Is(err, ErrNotWaiting) {
+		t.Fatalf("accept for withdrawn request = %v, want ErrNotWaiting", err)
+	}
+	if _, ok := api.Live(); ok {
+		t.Fatal("withdrawn request became a live call")
+	}
+
+	api.Request("req-2", "client-2", "")
+	if _, err := api.Apply(Transition{ID: "req-2", Event: EventAccept}); err != nil {
+		t.Errorf("accept for waiting request = %v", err)
+	}
+}
+
+func TestAbandonEndsRequestsAndWrapsUpLiveCall(t *testing.T) {
+	m := NewMachine(DefaultIdleTimeout)
+	m.Request("req-1", "client-1", "")
+	m.Accept("req-2", "client-2")
+	m.Fire("req-2", EventConnected, "")
+
+	if got := m.Abandon("client-1", ReasonRequesterDisconnected); len(got) != 1 || got[0].To != Ended {
+		t.Errorf("abandon waiting request = %+v", got)
+	}
+	if got := m.Abandon("client-2", ReasonRequesterDisconnected); len(got) != 1 || got[0].To != WrappingUp {
+		t.Errorf("abandon live call = %+v", got)
+	}
+	if current, waiting := m.Status(); current == nil || current.ID != "req-2" || waiting != 0 {
+		t.Errorf("status = %+v, %d", current, waiting)
+	}
+}
+
+func TestWaitingRoomOrderAndPick(t *testing.T) {
+	now := time.Now()
+	m := NewMachine(DefaultIdleTimeout)
+	m.now = func() time.Time { return now }
+
+	m.Request("req-1", "client-1", "")
+	now = now.Add(time.Second)
+	m.Request("req-2", "client-2", "週末のライブについて")
+	now = now.Add(time.Second)
+	m.Request("req-3", "client-3", "")
+	for _, id := range []string{"req-1", "req-2", "req-3"} {
+		m.Screen(id, "")
+	}
+
+	if next, ok := m.Next(); !ok || next.ID != "req-1" {
+		t.Fatalf("next = %+v, %v, want first come", next, ok)
+	}
+
+	// 選んだ人が先頭に来て、残りはリクエスト順のまま
+	if err := m.Pick("req-3"); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, s := range m.Waiting() {
+		ids = append(ids, s.ID)
+	}
+	if fmt.Sprint(ids) != "[req-3 req-1 req-2]" {
+		t.Errorf("waiting = %v", ids)
+	}
+
+	// 選ばれた人が出たら元の順番に戻る
+	m.Accept("req-3", "client-3")
+	if next, _ := m.Next(); next.ID != "req-1" || next.Picked {
+		t.Errorf("next after picked caller went on air = %+v", next)
+	}
+	if err := m.Pick("req-3"); !errors.Is(err, ErrNotWaiting) {
+		t.Errorf("pick of caller not waiting = %v", err)
+	}
+	if err := m.Screen("req-2", "週末のライブの感想を話したい"); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := m.Get("req-2"); s.Topic != "週末のライブについて" || s.Summary != "週末のライブの感想を話したい" {
+		t.Errorf("topic = %q, summary = %q", s.Topic, s.Summary)
+	}
+}
+
+func TestNextSkipsCallersNotScreened(t *testing.T) {
+	now := time.Now()
+	m := NewMachine(DefaultIdleTimeout)
+	m.now = func() time.Time { return now }
+
+	m.Request("req-1", "client-1", "")
+	now = now.Add(time.Second)
+	m.Request("req-2", "client-2", "")
+	m.Pick("req-1")
+
+	if next, ok := m.Next(); ok {
+		t.Fatalf("next = %+v before anyone was screened", next)
+	}
+	m.Screen("req-2", "")
+	if next, ok := m.Next(); !ok || next.ID != "req-2" {
+		t.Fatalf("next = %+v, %v, want the screened caller even if another was picked", next, ok)
+	}
+	m.Screen("req-1", "")
+	if next, _ := m.Next(); next.ID != "req-1" {
+		t.Errorf("next = %+v, want picked caller once screened", next)
+	}
+}
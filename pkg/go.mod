@@ -0,0 +1,5 @@
+module github.com/radio24/pkg
+
+go 1.23.0
+
+require github.com/gorilla/websocket v1.5.3
@@ -0,0 +1,178 @@
+package llm
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"io"
+	"math"
+	"sync"
+	"unicode/utf8"
+)
+
+// FakeReply Fake が返す既定の台本（以前のテストモードと同じ文）
+const FakeReply = "テストモードです。ラジオ24をお聞きいただき、ありがとうございます。"
+
+// EmbeddingDimensions 埋め込みの次元（text-embedding-3-small と同じ）
+const EmbeddingDimensions = 1536
+
+// fakeSpeechPerRune Fake のTTSが1文字あたりに返す無音の長さ（24kHzで20ms）
+const fakeSpeechPerRune = 480
+
+// ErrSessionClosed Close 済みのセッションを読み書きした
+var ErrSessionClosed = errors.New("realtime session closed")
+
+// Fake ネットワークを使わない決定的な実装（テスト・オフライン用）
+//
+// チャットは Reply があればその結果、なければ FakeReply（JSON指定時は "{}"）を返す。
+// TTSは文字数に比例した長さの無音、埋め込みは文字のバイグラムをハッシュした
+// ベクトルなので、同じ文は同じ・似た文は近いベクトルになる。
+type Fake struct {
+	Reply func(req ChatRequest) (string, error)
+
+	mu    sync.Mutex
+	chats []ChatRequest
+}
+
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+func (f *Fake) Complete(ctx context.Context, req ChatRequest) (string, error) {
+	f.mu.Lock()
+	f.chats = append(f.chats, req)
+	reply := f.Reply
+	f.mu.Unlock()
+
+	if reply != nil {
+		return reply(req)
+	}
+	if req.JSON {
+		return "{}", nil
+	}
+	return FakeReply, nil
+}
+
+// Chats これまでに受けたチャットのリクエスト
+func (f *Fake) Chats() []ChatRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ChatRequest(nil), f.chats...)
+}
+
+func (f *Fake) Synthesize(ctx context.Context, req SpeechRequest) ([]byte, error) {
+	speed := req.Speed
+	if speed <= 0 {
+		speed = 1.0
+	}
+	samples := int(float64(utf8.RuneCountInString(req.Text)*fakeSpeechPerRune) / speed)
+	return make([]byte, samples*2), nil
+}
+
+func (f *Fake) SynthesizeStream(ctx context.Context, req SpeechRequest, w io.Writer) error {
+	pcm, _ := f.Synthesize(ctx, req)
+	for len(pcm) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := min(streamChunk, len(pcm))
+		if _, err := w.Write(pcm[:n]); err != nil {
+			return err
+		}
+		pcm = pcm[n:]
+	}
+	return nil
+}
+
+func (f *Fake) Embed(ctx context.Context, text string) ([]float64, error) {
+	vec := make([]float64, EmbeddingDimensions)
+	runes := []rune(text)
+	for i := range runes {
+		end := i + 2
+		if end > len(runes) {
+			end = len(runes)
+		}
+		h := fnv.New32a()
+		h.Write([]byte(string(runes[i:end])))
+		vec[h.Sum32()%EmbeddingDimensions]++
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vec {
+			vec[i] /= norm
+		}
+	}
+	return vec, nil
+}
+
+func (f *Fake) Connect(ctx context.Context) (RealtimeSession, error) {
+	return &fakeSession{fake: f, events: make(chan []byte, 64), done: make(chan struct{})}, nil
+}
+
+// fakeSession 応答の要求（response.create / 音声のコミット）に
+// FakeReply の音声・字幕・完了イベントを返すセッション
+type fakeSession struct {
+	fake      *Fake
+	events    chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *fakeSession) WriteJSON(v any) error {
+	select {
+	case <-s.done:
+		return ErrSessionClosed
+	default:
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var event struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case "session.update":
+		s.emit(map[string]any{"type": "session.updated"})
+	case "response.create", "input_audio_buffer.commit":
+		pcm, _ := s.fake.Synthesize(context.Background(), SpeechRequest{Text: FakeReply})
+		s.emit(map[string]any{"type": "response.output_audio.delta", "delta": base64.StdEncoding.EncodeToString(pcm)})
+		s.emit(map[string]any{"type": "response.content_part.done", "content_part": map[string]any{"type": "audio", "text": FakeReply}})
+		s.emit(map[string]any{"type": "response.done"})
+	}
+	return nil
+}
+
+func (s *fakeSession) emit(event map[string]any) {
+	data, _ := json.Marshal(event)
+	select {
+	case s.events <- data:
+	case <-s.done:
+	}
+}
+
+func (s *fakeSession) ReadJSON(v any) error {
+	select {
+	case data := <-s.events:
+		return json.Unmarshal(data, v)
+	case <-s.done:
+		return ErrSessionClosed
+	}
+}
+
+func (s *fakeSession) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return nil
+}
@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// ChatRequest 1往復のチャット（システムプロンプト＋ユーザー入力）
+type ChatRequest struct {
+	System      string
+	Prompt      string
+	MaxTokens   int
+	Temperature float64
+	TopP        float64 // 0 なら指定しない
+	JSON        bool    // JSONオブジェクトで返させる
+}
+
+// SpeechRequest 読み上げ（TTS）
+type SpeechRequest struct {
+	Text  string
+	Voice string
+	Speed float64 // 0 なら 1.0
+}
+
+// Chat テキスト生成
+type Chat interface {
+	Complete(ctx context.Context, req ChatRequest) (string, error)
+}
+
+// TTS 音声合成（24kHz mono s16 のPCM）
+type TTS interface {
+	Synthesize(ctx context.Context, req SpeechRequest) ([]byte, error)
+	// SynthesizeStream 受信したそばからPCMを w に書き込む（チャンクは奇数バイトのこともある）
+	SynthesizeStream(ctx context.Context, req SpeechRequest, w io.Writer) error
+}
+
+// Embedder 埋め込みベクトル（pgvector の vector(1536) に入る次元）
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float64, error)
+}
+
+// RealtimeSession Realtime APIのセッション（イベントをJSONでやり取りする）
+//
+// *websocket.Conn はそのままこのインターフェースを満たす。
+type RealtimeSession interface {
+	WriteJSON(v any) error
+	ReadJSON(v any) error
+	Close() error
+}
+
+// Realtime 音声対話のセッションを開くもの
+type Realtime interface {
+	Connect(ctx context.Context) (RealtimeSession, error)
+}
+
+// Provider 実装一式
+type Provider struct {
+	Name       string // "openai" | "fake"
+	Chat       Chat
+	TTS        TTS
+	Embeddings Embedder
+	Realtime   Realtime
+	TTSModel   string // 合成結果のキャッシュキーに使うTTSのモデル名
+}
+
+// IsFake 実際のモデルを使わないプロバイダか（要約など結果を残す処理は避ける）
+func (p Provider) IsFake() bool {
+	return p.Name == "fake"
+}
+
+// NewOpenAIProvider OpenAIの実装一式
+func NewOpenAIProvider(c *OpenAI) Provider {
+	return Provider{Name: "openai", Chat: c, TTS: c, Embeddings: c, Realtime: c, TTSModel: c.TTS.Model}
+}
+
+// NewFakeProvider ネットワークを使わない決定的な実装一式
+func NewFakeProvider(f *Fake) Provider {
+	return Provider{Name: "fake", Chat: f, TTS: f, Embeddings: f, Realtime: f, TTSModel: "fake"}
+}
+
+// FromEnv 環境変数からプロバイダを選ぶ
+//
+// LLM_PROVIDER=fake なら Fake、openai なら（キーがなくても）OpenAI互換の実装。
+// 未指定の場合は、OPENAI_API_KEY が未設定・プレースホルダ（your-openai-api-key…, test-mode）で
+// OPENAI_BASE_URL も未設定なら Fake を使う（テストモード）。
+func FromEnv() Provider {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if strings.HasPrefix(apiKey, "your-openai-api-key") || apiKey == "test-mode" {
+		apiKey = ""
+	}
+
+	provider := os.Getenv("LLM_PROVIDER")
+	if provider == "fake" || (provider == "" && apiKey == "" && os.Getenv("OPENAI_BASE_URL") == "") {
+		log.Println("LLM provider: fake (test mode)")
+		return NewFakeProvider(NewFake())
+	}
+
+	c := OpenAIFromEnv(apiKey)
+	log.Printf("LLM provider: openai (chat: %s %s)", c.Chat.BaseURL, c.Chat.Model)
+	return NewOpenAIProvider(c)
+}
@@ -0,0 +1,222 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestOpenAIComplete(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("request = %s %s", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"choices":[{"message":{"content":"こんにちは"}}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewOpenAI("sk-test")
+	c.Chat.BaseURL = srv.URL
+	text, err := c.Complete(context.Background(), ChatRequest{System: "DJ", Prompt: "挨拶", MaxTokens: 50, JSON: true})
+	if err != nil || text != "こんにちは" {
+		t.Fatalf("Complete() = %q, %v", text, err)
+	}
+	if got["model"] != "gpt-4o-mini" || got["response_format"] == nil || got["top_p"] != nil {
+		t.Errorf("request body = %v", got)
+	}
+}
+
+func TestOpenAIStreamsSpeech(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 最初のチャンクを送った後、クライアントが読むまで次を送らない
+		w.Write([]byte{1, 2, 3})
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	c := NewOpenAI("sk-test")
+	c.TTS.BaseURL = srv.URL
+	ctx, cancel := context.WithCancel(context.Background())
+	got := make(chan []byte, 1)
+	go c.SynthesizeStream(ctx, SpeechRequest{Text: "こんにちは"}, writerFunc(func(p []byte) (int, error) {
+		got <- append([]byte(nil), p...)
+		cancel()
+		return len(p), nil
+	}))
+
+	if chunk := <-got; !bytes.Equal(chunk, []byte{1, 2, 3}) {
+		t.Errorf("first chunk = %v", chunk)
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func TestOpenAIErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	c := NewOpenAI("sk-test")
+	c.Embedding.BaseURL = srv.URL
+	if _, err := c.Embed(context.Background(), "text"); err == nil {
+		t.Error("expected error for non-200 response")
+	}
+}
+
+func TestOpenAIFromEnvPointsAtCompatibleServer(t *testing.T) {
+	var path, model, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		path, model, auth = r.URL.Path, body["model"].(string), r.Header.Get("X-Api-Key")
+		w.Write([]byte(`{"data":[{"embedding":[0.5,0.5]}]}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("OPENAI_BASE_URL", "http://unused.invalid/v1")
+	t.Setenv("OPENAI_EMBEDDING_BASE_URL", srv.URL+"/v1/")
+	t.Setenv("OPENAI_EMBEDDING_MODEL", "nomic-embed-text")
+	t.Setenv("OPENAI_AUTH_HEADER", "X-Api-Key")
+
+	c := OpenAIFromEnv("local-key")
+	if c.Chat.BaseURL != "http://unused.invalid/v1" || c.Chat.Model != "gpt-4o-mini" {
+		t.Errorf("chat endpoint = %+v", c.Chat)
+	}
+	if _, err := c.Embed(context.Background(), "text"); err != nil {
+		t.Fatalf("Embed() = %v", err)
+	}
+	if path != "/v1/embeddings" || model != "nomic-embed-text" || auth != "local-key" {
+		t.Errorf("request = %s model=%s auth=%q", path, model, auth)
+	}
+}
+
+func TestOpenAIRealtimeURL(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	c := NewOpenAI("")
+	c.Realtime.BaseURL = srv.URL + "/v1"
+	c.Realtime.Model = "local-realtime"
+	s, err := c.Connect(context.Background())
+	if err != nil {
+		t.Fatalf("Connect() = %v", err)
+	}
+	s.Close()
+	if got.URL.Path != "/v1/realtime" || got.URL.Query().Get("model") != "local-realtime" || got.Header.Get("Authorization") != "" {
+		t.Errorf("request = %s %v", got.URL, got.Header)
+	}
+}
+
+func TestFromEnvSelectsProvider(t *testing.T) {
+	cases := []struct {
+		provider, key, base string
+		want                string
+	}{
+		{"", "", "", "fake"},
+		{"", "your-openai-api-key-here", "", "fake"},
+		{"", "sk-live", "", "openai"},
+		{"", "", "http://localhost:8000/v1", "openai"},
+		{"openai", "", "", "openai"},
+		{"fake", "sk-live", "", "fake"},
+	}
+	for _, c := range cases {
+		t.Setenv("LLM_PROVIDER", c.provider)
+		t.Setenv("OPENAI_API_KEY", c.key)
+		t.Setenv("OPENAI_BASE_URL", c.base)
+		if got := FromEnv().Name; got != c.want {
+			t.Errorf("FromEnv(%q, %q, %q) = %s, want %s", c.provider, c.key, c.base, got, c.want)
+		}
+	}
+}
+
+func TestFakeIsDeterministic(t *testing.T) {
+	f := NewFake()
+	ctx := context.Background()
+
+	if text, _ := f.Complete(ctx, ChatRequest{Prompt: "天気"}); text != FakeReply {
+		t.Errorf("Complete() = %q", text)
+	}
+	if text, _ := f.Complete(ctx, ChatRequest{JSON: true}); text != "{}" {
+		t.Errorf("Complete(JSON) = %q", text)
+	}
+	if n := len(f.Chats()); n != 2 {
+		t.Errorf("chats = %d, want 2", n)
+	}
+
+	pcm, _ := f.Synthesize(ctx, SpeechRequest{Text: "こんにちは"})
+	if len(pcm) != 5*fakeSpeechPerRune*2 {
+		t.Errorf("pcm = %d bytes", len(pcm))
+	}
+	var streamed bytes.Buffer
+	f.SynthesizeStream(ctx, SpeechRequest{Text: "こんにちは"}, &streamed)
+	if streamed.Len() != len(pcm) {
+		t.Errorf("streamed = %d bytes, want %d", streamed.Len(), len(pcm))
+	}
+
+	a, _ := f.Embed(ctx, "今日は晴れです")
+	b, _ := f.Embed(ctx, "今日は晴れです")
+	c, _ := f.Embed(ctx, "明日のニュース")
+	if len(a) != EmbeddingDimensions {
+		t.Fatalf("dimensions = %d", len(a))
+	}
+	if sim := dot(a, b); sim < 0.999 {
+		t.Errorf("same text similarity = %f, want 1", sim)
+	}
+	if sim := dot(a, c); sim > 0.5 {
+		t.Errorf("different text similarity = %f, want low", sim)
+	}
+}
+
+func TestFakeRealtimeRespondsToResponseCreate(t *testing.T) {
+	s, _ := NewFake().Connect(context.Background())
+
+	s.WriteJSON(map[string]any{"type": "session.update"})
+	s.WriteJSON(map[string]any{"type": "response.create"})
+
+	var types []string
+	for i := 0; i < 4; i++ {
+		var msg map[string]interface{}
+		if err := s.ReadJSON(&msg); err != nil {
+			t.Fatalf("ReadJSON() = %v", err)
+		}
+		types = append(types, msg["type"].(string))
+	}
+	want := []string{"session.updated", "response.output_audio.delta", "response.content_part.done", "response.done"}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("events = %v, want %v", types, want)
+		}
+	}
+
+	s.Close()
+	var msg map[string]interface{}
+	if err := s.ReadJSON(&msg); err != ErrSessionClosed {
+		t.Errorf("ReadJSON after Close = %v", err)
+	}
+}
+
+func dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,282 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// OpenAIBaseURL 既定の接続先
+const OpenAIBaseURL = "https://api.openai.com/v1"
+
+// streamChunk TTSのストリーミングで一度に読む大きさ（24kHz monoで約100ms）
+const streamChunk = 4800
+
+// Endpoint 1種類のAPI（チャット・TTS・埋め込み・Realtime）の接続先
+//
+// OpenAI互換のサーバー（llama.cpp・vLLM など）を指す場合は BaseURL と Model を変える。
+// Realtime の BaseURL は http(s) でも ws(s) でもよく、接続時に ws(s) にして /realtime を付ける。
+type Endpoint struct {
+	BaseURL    string // https://api.openai.com/v1
+	Model      string
+	APIKey     string        // 空ならヘッダを付けない（認証のないローカルサーバー）
+	AuthHeader string        // 空なら Authorization: Bearer <key>。それ以外のヘッダ名ではキーをそのまま送る
+	Timeout    time.Duration // 1回のリクエストの上限（TTSは音声を読み終えるまで）。0なら無制限
+}
+
+// withTimeout Timeout を付けた ctx
+func (e Endpoint) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, e.Timeout)
+}
+
+// APIError 200以外のレスポンス
+type APIError struct {
+	Status int
+	Body   string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("OpenAI API error: %d - %s", e.Status, e.Body)
+}
+
+// authorize 認証ヘッダを付ける
+func (e Endpoint) authorize(header http.Header) {
+	if e.APIKey == "" {
+		return
+	}
+	if e.AuthHeader == "" || strings.EqualFold(e.AuthHeader, "Authorization") {
+		header.Set("Authorization", "Bearer "+e.APIKey)
+		return
+	}
+	header.Set(e.AuthHeader, e.APIKey)
+}
+
+// OpenAI OpenAI API（および互換サーバー）の実装
+type OpenAI struct {
+	Chat      Endpoint
+	TTS       Endpoint
+	Embedding Endpoint
+	Realtime  Endpoint
+	client    *http.Client
+}
+
+func NewOpenAI(apiKey string) *OpenAI {
+	endpoint := func(model string, timeout time.Duration) Endpoint {
+		return Endpoint{BaseURL: OpenAIBaseURL, Model: model, APIKey: apiKey, Timeout: timeout}
+	}
+	return &OpenAI{
+		Chat:      endpoint("gpt-4o-mini", 30*time.Second),
+		TTS:       endpoint("tts-1", 30*time.Second),
+		Embedding: endpoint("text-embedding-3-small", 10*time.Second),
+		Realtime:  endpoint("gpt-realtime", 10*time.Second), // 接続とキー発行まで
+		client:    &http.Client{},
+	}
+}
+
+// OpenAIFromEnv 環境変数で接続先を上書きした OpenAI
+//
+// OPENAI_BASE_URL・OPENAI_AUTH_HEADER は全エンドポイント共通、
+// OPENAI_{CHAT,TTS,EMBEDDING,REALTIME}_{BASE_URL,MODEL,TIMEOUT} でエンドポイントごとに上書きする
+// （TIMEOUT は "45s" のような time.Duration の書式）。
+func OpenAIFromEnv(apiKey string) *OpenAI {
+	c := NewOpenAI(apiKey)
+	for name, e := range map[string]*Endpoint{
+		"CHAT":      &c.Chat,
+		"TTS":       &c.TTS,
+		"EMBEDDING": &c.Embedding,
+		"REALTIME":  &c.Realtime,
+	} {
+		if v := os.Getenv("OPENAI_BASE_URL"); v != "" {
+			e.BaseURL = v
+		}
+		if v := os.Getenv("OPENAI_" + name + "_BASE_URL"); v != "" {
+			e.BaseURL = v
+		}
+		if v := os.Getenv("OPENAI_" + name + "_MODEL"); v != "" {
+			e.Model = v
+		}
+		if d, err := time.ParseDuration(os.Getenv("OPENAI_" + name + "_TIMEOUT")); err == nil && d > 0 {
+			e.Timeout = d
+		}
+		e.BaseURL = strings.TrimRight(e.BaseURL, "/")
+		e.AuthHeader = os.Getenv("OPENAI_AUTH_HEADER")
+	}
+	return c
+}
+
+func (c *OpenAI) Complete(ctx context.Context, req ChatRequest) (string, error) {
+	body := map[string]interface{}{
+		"model": c.Chat.Model,
+		"messages": []map[string]string{
+			{"role": "system", "content": req.System},
+			{"role": "user", "content": req.Prompt},
+		},
+		"max_tokens":  req.MaxTokens,
+		"temperature": req.Temperature,
+	}
+	if req.TopP > 0 {
+		body["top_p"] = req.TopP
+	}
+	if req.JSON {
+		body["response_format"] = map[string]string{"type": "json_object"}
+	}
+
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := c.postJSON(ctx, c.Chat, "/chat/completions", body, &result); err != nil {
+		return "", err
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("no choices in response")
+	}
+	return result.Choices[0].Message.Content, nil
+}
+
+func (c *OpenAI) Synthesize(ctx context.Context, req SpeechRequest) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.SynthesizeStream(ctx, req, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *OpenAI) SynthesizeStream(ctx context.Context, req SpeechRequest, w io.Writer) error {
+	speed := req.Speed
+	if speed == 0 {
+		speed = 1.0
+	}
+	body := map[string]interface{}{
+		"model":           c.TTS.Model,
+		"input":           req.Text,
+		"voice":           req.Voice,
+		"response_format": "pcm",
+		"speed":           speed,
+	}
+
+	ctx, cancel := c.TTS.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.post(ctx, c.TTS, "/audio/speech", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// チャンク転送で届いた分から書き込む
+	if _, err := io.CopyBuffer(w, resp.Body, make([]byte, streamChunk)); err != nil {
+		return fmt.Errorf("failed to stream speech: %w", err)
+	}
+	return nil
+}
+
+func (c *OpenAI) Embed(ctx context.Context, text string) ([]float64, error) {
+	body := map[string]interface{}{
+		"model": c.Embedding.Model,
+		"input": text,
+	}
+
+	var result struct {
+		Data []struct {
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := c.postJSON(ctx, c.Embedding, "/embeddings", body, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("no embedding returned")
+	}
+	return result.Data[0].Embedding, nil
+}
+
+func (c *OpenAI) Connect(ctx context.Context) (RealtimeSession, error) {
+	header := http.Header{}
+	c.Realtime.authorize(header)
+
+	ctx, cancel := c.Realtime.withTimeout(ctx)
+	defer cancel()
+	url := realtimeURL(c.Realtime.BaseURL) + "/realtime?model=" + c.Realtime.Model
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to realtime API: %w", err)
+	}
+	return conn, nil
+}
+
+// ClientSecret ブラウザから Realtime に直接つなぐための短命キーを発行（レスポンスをそのまま返す）
+func (c *OpenAI) ClientSecret(ctx context.Context, body interface{}) ([]byte, error) {
+	ctx, cancel := c.Realtime.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.post(ctx, c.Realtime, "/realtime/client_secrets", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// realtimeURL http(s) の BaseURL を ws(s) にする
+func realtimeURL(base string) string {
+	switch {
+	case strings.HasPrefix(base, "https://"):
+		return "wss://" + strings.TrimPrefix(base, "https://")
+	case strings.HasPrefix(base, "http://"):
+		return "ws://" + strings.TrimPrefix(base, "http://")
+	}
+	return base
+}
+
+func (c *OpenAI) postJSON(ctx context.Context, e Endpoint, path string, body, out interface{}) error {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.post(ctx, e, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// post リクエストを送り、200以外はエラーにする（呼び出し側で Body を閉じる）
+func (c *OpenAI) post(ctx context.Context, e Endpoint, path string, body interface{}) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.BaseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	e.authorize(req.Header)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{Status: resp.StatusCode, Body: string(msg)}
+	}
+	return resp, nil
+}
@@ -0,0 +1,258 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 上流の障害が続いているので呼び出さなかった
+var ErrCircuitOpen = errors.New("upstream circuit open")
+
+// RetryPolicy 一時的な失敗のリトライ（指数バックオフ＋ジッター）
+type RetryPolicy struct {
+	Attempts  int           // 最初の1回を含む試行回数
+	BaseDelay time.Duration // 1回目のリトライまでの待ち時間（以降2倍ずつ）
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy 0.5秒・1秒と待って3回まで試す
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+// backoff n 回目（1始まり）のリトライまでの待ち時間
+//
+// 上限付きの指数バックオフの半分〜全部の範囲でランダムにし、
+// 複数の呼び出しが同時にリトライして上流に集中しないようにする。
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.BaseDelay << (n - 1)
+	if d > p.MaxDelay || d <= 0 {
+		d = p.MaxDelay
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// Do op をリトライしながら実行（リトライしても無駄なエラーはそのまま返す）
+func (p RetryPolicy) Do(ctx context.Context, op func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = op(); err == nil || attempt >= p.Attempts || !Retryable(ctx, err) {
+			return err
+		}
+		wait := p.backoff(attempt)
+		log.Printf("LLM: retrying in %v after error: %v", wait, err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// Retryable リトライで直る見込みのあるエラーか
+//
+// レート制限・タイムアウト・5xx と通信エラーはリトライし、
+// それ以外の4xx（リクエストの誤り・認証）と呼び出し側のキャンセルはしない。
+func Retryable(ctx context.Context, err error) bool {
+	var partial *partialError
+	if ctx.Err() != nil || errors.Is(err, ErrCircuitOpen) || errors.As(err, &partial) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Status == http.StatusTooManyRequests ||
+			apiErr.Status == http.StatusRequestTimeout ||
+			apiErr.Status >= 500
+	}
+	return true
+}
+
+// BreakerState サーキットブレーカーの状態
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // 通常
+	BreakerOpen     BreakerState = "open"      // 障害中（呼び出さずに失敗させる）
+	BreakerHalfOpen BreakerState = "half_open" // 冷却期間が明け、次の1回で回復を確かめる
+)
+
+// Breaker 上流の失敗が続いたら一定時間呼び出しを止めるサーキットブレーカー
+//
+// リトライしても失敗した呼び出しが Threshold 回続くと open になり、Cooldown の間は
+// ErrCircuitOpen ですぐ失敗させる。冷却期間が明けたら1回だけ通し（half_open）、
+// 成功すれば closed に戻り、失敗すればまた open になる。
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+	OnChange  func(state BreakerState) // 状態が変わった時（ロックの外で呼ぶ）
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{Threshold: threshold, Cooldown: cooldown, state: BreakerClosed, now: time.Now}
+}
+
+// State 現在の状態（冷却期間が明けていれば half_open）
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateLocked()
+}
+
+func (b *Breaker) stateLocked() BreakerState {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.Cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Allow 呼び出してよいか（open の間と、回復の確認中は ErrCircuitOpen）
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.stateLocked() {
+	case BreakerOpen:
+		return ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Record 呼び出しの結果を記録
+//
+// 呼び出し側のキャンセルとリクエスト自体の誤り（429・408以外の4xx）は上流の障害として数えない。
+func (b *Breaker) Record(ctx context.Context, err error) {
+	var apiErr *APIError
+	if ctx.Err() != nil || (errors.As(err, &apiErr) && apiErr.Status < 500 && !Retryable(ctx, apiErr)) {
+		b.mu.Lock()
+		b.probing = false
+		b.mu.Unlock()
+		return
+	}
+
+	b.mu.Lock()
+	prev := b.stateLocked()
+	wasProbe := b.probing
+	b.probing = false
+	if err == nil {
+		b.failures = 0
+		b.state = BreakerClosed
+	} else {
+		b.failures++
+		if wasProbe || b.failures >= b.Threshold {
+			b.state = BreakerOpen
+			b.openedAt = b.now()
+		}
+	}
+	state, failures := b.state, b.failures
+	onChange := b.OnChange
+	b.mu.Unlock()
+
+	// 回復の確認に失敗して open に戻った時も知らせる
+	if (state != prev || wasProbe) && onChange != nil {
+		log.Printf("LLM: upstream circuit %s (consecutive failures: %d)", state, failures)
+		onChange(state)
+	}
+}
+
+// call ブレーカーを通し、リトライしながら op を実行
+func call(ctx context.Context, policy RetryPolicy, breaker *Breaker, op func() error) error {
+	if breaker == nil {
+		return policy.Do(ctx, op)
+	}
+	if err := breaker.Allow(); err != nil {
+		return err
+	}
+	err := policy.Do(ctx, op)
+	breaker.Record(ctx, err)
+	return err
+}
+
+// Resilient チャット・TTS・埋め込みにリトライとサーキットブレーカーを付けたプロバイダ
+//
+// breaker は nil でもよい（リトライだけ）。Realtime は通話側で再接続するのでそのまま。
+func Resilient(p Provider, policy RetryPolicy, breaker *Breaker) Provider {
+	r := &resilient{chat: p.Chat, tts: p.TTS, embeddings: p.Embeddings, policy: policy, breaker: breaker}
+	p.Chat, p.TTS, p.Embeddings = r, r, r
+	return p
+}
+
+type resilient struct {
+	chat       Chat
+	tts        TTS
+	embeddings Embedder
+	policy     RetryPolicy
+	breaker    *Breaker
+}
+
+func (r *resilient) Complete(ctx context.Context, req ChatRequest) (string, error) {
+	var text string
+	err := call(ctx, r.policy, r.breaker, func() (err error) {
+		text, err = r.chat.Complete(ctx, req)
+		return err
+	})
+	return text, err
+}
+
+func (r *resilient) Synthesize(ctx context.Context, req SpeechRequest) ([]byte, error) {
+	var pcm []byte
+	err := call(ctx, r.policy, r.breaker, func() (err error) {
+		pcm, err = r.tts.Synthesize(ctx, req)
+		return err
+	})
+	return pcm, err
+}
+
+// SynthesizeStream 何も書き込む前の失敗だけリトライする（途中からやり直すと音声が重なるため）
+func (r *resilient) SynthesizeStream(ctx context.Context, req SpeechRequest, w io.Writer) error {
+	cw := &countingWriter{w: w}
+	return call(ctx, r.policy, r.breaker, func() error {
+		err := r.tts.SynthesizeStream(ctx, req, cw)
+		if err != nil && cw.n > 0 {
+			return &partialError{err}
+		}
+		return err
+	})
+}
+
+func (r *resilient) Embed(ctx context.Context, text string) ([]float64, error) {
+	var vec []float64
+	err := call(ctx, r.policy, r.breaker, func() (err error) {
+		vec, err = r.embeddings.Embed(ctx, text)
+		return err
+	})
+	return vec, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// partialError 音声を途中まで書き込んでから失敗した（リトライしない）
+type partialError struct{ err error }
+
+func (e *partialError) Error() string { return e.err.Error() }
+func (e *partialError) Unwrap() error { return e.err }
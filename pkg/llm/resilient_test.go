@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fastRetry = RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+func TestResilientRetriesServerErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"こんにちは"}}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewOpenAI("sk-test")
+	c.Chat.BaseURL = srv.URL
+	p := Resilient(NewOpenAIProvider(c), fastRetry, nil)
+	text, err := p.Chat.Complete(context.Background(), ChatRequest{Prompt: "挨拶"})
+	if err != nil || text != "こんにちは" || calls != 3 {
+		t.Errorf("Complete() = %q, %v after %d calls", text, err, calls)
+	}
+}
+
+func TestResilientDoesNotRetryBadRequest(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "invalid voice", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	c := NewOpenAI("sk-test")
+	c.TTS.BaseURL = srv.URL
+	breaker := NewBreaker(1, time.Minute)
+	p := Resilient(NewOpenAIProvider(c), fastRetry, breaker)
+	if _, err := p.TTS.Synthesize(context.Background(), SpeechRequest{Text: "こんにちは"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+	if s := breaker.State(); s != BreakerClosed {
+		t.Errorf("bad request opened the circuit: %s", s)
+	}
+}
+
+func TestEndpointTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c := NewOpenAI("sk-test")
+	c.Embedding.BaseURL = srv.URL
+	c.Embedding.Timeout = 20 * time.Millisecond
+	start := time.Now()
+	if _, err := c.Embed(context.Background(), "text"); err == nil {
+		t.Fatal("expected timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("timeout took %v", elapsed)
+	}
+}
+
+// flakyTTS 途中まで書き込んでから失敗するTTS
+type flakyTTS struct {
+	Fake
+	calls int
+}
+
+func (f *flakyTTS) SynthesizeStream(ctx context.Context, req SpeechRequest, w io.Writer) error {
+	f.calls++
+	w.Write([]byte{1, 2})
+	return errors.New("connection reset")
+}
+
+func TestResilientDoesNotRetryPartialSpeech(t *testing.T) {
+	tts := &flakyTTS{}
+	p := Resilient(Provider{TTS: tts}, fastRetry, nil)
+	if err := p.TTS.SynthesizeStream(context.Background(), SpeechRequest{Text: "こんにちは"}, io.Discard); err == nil {
+		t.Fatal("expected error")
+	}
+	if tts.calls != 1 {
+		t.Errorf("calls = %d, want 1 (retrying would repeat the audio)", tts.calls)
+	}
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(2, 30*time.Second)
+	b.now = func() time.Time { return now }
+	var changes []BreakerState
+	b.OnChange = func(s BreakerState) { changes = append(changes, s) }
+
+	ctx := context.Background()
+	failure := errors.New("connection refused")
+	b.Record(ctx, failure)
+	if b.State() != BreakerClosed {
+		t.Fatalf("opened after 1 failure")
+	}
+	b.Record(ctx, failure)
+	if err := b.Allow(); err != ErrCircuitOpen {
+		t.Fatalf("Allow() while open = %v", err)
+	}
+
+	// 冷却期間が明けたら1回だけ通す
+	now = now.Add(30 * time.Second)
+	if b.State() != BreakerHalfOpen || b.Allow() != nil {
+		t.Fatalf("probe not allowed after cooldown")
+	}
+	if b.Allow() != ErrCircuitOpen {
+		t.Errorf("second call allowed while probing")
+	}
+	b.Record(ctx, nil)
+	if b.State() != BreakerClosed || b.Allow() != nil {
+		t.Errorf("not closed after successful probe")
+	}
+
+	want := []BreakerState{BreakerOpen, BreakerClosed}
+	if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+}
+
+func TestBreakerFailsFastWhileOpen(t *testing.T) {
+	tts := &flakyTTS{}
+	breaker := NewBreaker(1, time.Minute)
+	p := Resilient(Provider{TTS: tts}, fastRetry, breaker)
+
+	p.TTS.SynthesizeStream(context.Background(), SpeechRequest{Text: "こんにちは"}, io.Discard)
+	err := p.TTS.SynthesizeStream(context.Background(), SpeechRequest{Text: "こんにちは"}, io.Discard)
+	if !errors.Is(err, ErrCircuitOpen) || tts.calls != 1 {
+		t.Errorf("err = %v after %d calls, want fail fast", err, tts.calls)
+	}
+}
@@ -0,0 +1,363 @@
+package mixer
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/radio24/pkg/audio"
+)
+
+// 標準のバス名
+const (
+	BusVoice  = "voice"  // DJ（TTS / Realtime）
+	BusCaller = "caller" // リスナーの通話音声
+	BusMusic  = "music"  // 音楽・ベッド
+	BusFX     = "fx"     // ジングル・スイーパー
+)
+
+// duckRamp ダッキングのかかり・戻りにかける時間
+const duckRamp = 150 * time.Millisecond
+
+// バスゲインの設定範囲（dB）
+const (
+	MinBusGain = -60.0
+	MaxBusGain = 6.0
+)
+
+// BusState バスの状態
+type BusState struct {
+	Name     string  `json:"name"`
+	GainDB   float64 `json:"gain_db"`
+	Fader    float64 `json:"fader"`
+	Duckable bool    `json:"duckable"`
+	Active   bool    `json:"active"` // 音源が接続されているか
+}
+
+// State ミキサー全体の状態のスナップショット
+type State struct {
+	State          MixerState `json:"state"`
+	DuckLevelDB    float64    `json:"duck_level_db"`
+	DuckDurationMs int64      `json:"duck_duration_ms"`
+	Buses          []BusState `json:"buses"`
+}
+
+// bus ミキサーへの1系統の入力
+type bus struct {
+	src      audio.Source
+	duckable bool    // ダッキング対象か（音楽など）
+	gainDB   float64 // バスのゲイン（dB）
+	fader    float64 // 現在のフェーダー位置（0.0〜1.0）
+	target   float64
+	step     float64 // 1サンプルあたりの変化量
+	left     int     // フェード完了までの残りサンプル数
+	onDone   func()  // フェード完了時のコールバック
+}
+
+type scheduledCrossfade struct {
+	at       time.Time
+	from, to string
+	duration time.Duration
+}
+
+// AddBus 入力バスを追加（フェーダーは全開の状態で追加される）
+func (m *Mixer) AddBus(name string, src audio.Source, duckable bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.buses[name]; !ok {
+		m.order = append(m.order, name)
+	}
+	m.buses[name] = &bus{src: src, duckable: duckable, fader: 1.0, target: 1.0}
+}
+
+// SetBusGain バスのゲインを設定（MinBusGain〜MaxBusGain dBに制限）
+func (m *Mixer) SetBusGain(name string, db float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.buses[name]
+	if !ok {
+		return fmt.Errorf("unknown bus: %s", name)
+	}
+	b.gainDB = math.Max(MinBusGain, math.Min(MaxBusGain, db))
+	log.Printf("Mixer: Bus %s gain set to %.1fdB", name, b.gainDB)
+	return nil
+}
+
+// HasBus name のバスがあるか
+func (m *Mixer) HasBus(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.buses[name]
+	return ok
+}
+
+// Snapshot 現在の状態を返す
+func (m *Mixer) Snapshot() State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	st := State{
+		State:          m.state,
+		DuckLevelDB:    m.duckLevel,
+		DuckDurationMs: m.duckDuration.Milliseconds(),
+		Buses:          make([]BusState, 0, len(m.order)),
+	}
+	for _, name := range m.order {
+		b := m.buses[name]
+		st.Buses = append(st.Buses, BusState{
+			Name:     name,
+			GainDB:   b.gainDB,
+			Fader:    b.fader,
+			Duckable: b.duckable,
+			Active:   b.src != nil,
+		})
+	}
+	return st
+}
+
+// Play バスの音源を差し替えてフェードインで再生を始める
+func (m *Mixer) Play(name string, src audio.Source, fadeIn time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.buses[name]
+	if !ok {
+		return fmt.Errorf("unknown bus: %s", name)
+	}
+	b.src = src
+	b.fader = 0
+	m.startFade(b, 1.0, fadeIn, nil)
+	return nil
+}
+
+// StopBus フェードアウトしてからバスの音源を外す
+func (m *Mixer) StopBus(name string, fadeOut time.Duration) error {
+	return m.FadeOut(name, fadeOut, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if b, ok := m.buses[name]; ok && b.target == 0 {
+			b.src = nil
+		}
+	})
+}
+
+// FadeIn フェーダーを0から全開まで上げる
+func (m *Mixer) FadeIn(name string, d time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.buses[name]
+	if !ok {
+		return fmt.Errorf("unknown bus: %s", name)
+	}
+	b.fader = 0
+	m.startFade(b, 1.0, d, nil)
+	return nil
+}
+
+// FadeOut フェーダーを0まで下げる。下がりきったら done を呼ぶ
+func (m *Mixer) FadeOut(name string, d time.Duration, done func()) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.buses[name]
+	if !ok {
+		return fmt.Errorf("unknown bus: %s", name)
+	}
+	m.startFade(b, 0, d, done)
+	return nil
+}
+
+// Crossfade from を下げながら to を上げる
+func (m *Mixer) Crossfade(from, to string, d time.Duration) error {
+	if err := m.FadeOut(from, d, nil); err != nil {
+		return err
+	}
+	return m.FadeIn(to, d)
+}
+
+// ScheduleCrossfade 指定時刻にクロスフェードを開始する
+func (m *Mixer) ScheduleCrossfade(at time.Time, from, to string, d time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, name := range []string{from, to} {
+		if _, ok := m.buses[name]; !ok {
+			return fmt.Errorf("unknown bus: %s", name)
+		}
+	}
+	m.pending = append(m.pending, scheduledCrossfade{at: at, from: from, to: to, duration: d})
+	log.Printf("Mixer: Crossfade %s -> %s scheduled at %s", from, to, at.Format(time.RFC3339))
+	return nil
+}
+
+// startFade m.mu を保持した状態で呼ぶ
+//
+// 前のフェードが終わる前に置き換える場合も、そのコールバックは捨てずに次のフレームで呼ぶ。
+func (m *Mixer) startFade(b *bus, target float64, d time.Duration, done func()) {
+	if b.onDone != nil {
+		m.superseded = append(m.superseded, b.onDone)
+	}
+	b.target = target
+	b.onDone = done
+
+	n := m.format.Samples(d)
+	if n <= 0 {
+		b.fader = target
+		b.left = 0
+		return
+	}
+	b.step = (target - b.fader) / float64(n)
+	b.left = n
+}
+
+// Run ミキサーのクロックを開始し、frameDuration ごとにミックス結果を sink へ書き込む
+//
+// 入力がすべて無音でも無音フレームを送り続ける。
+func (m *Mixer) Run(sink audio.Sink, format audio.Format, frameDuration time.Duration) {
+	m.mu.Lock()
+	if m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.running = true
+	m.format = format
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(frameDuration)
+		defer ticker.Stop()
+
+		samples := format.Samples(frameDuration)
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case now := <-ticker.C:
+				frame := m.mixFrame(now, samples)
+				if err := sink.WriteSample(frame); err != nil {
+					log.Printf("Mixer: failed to write frame: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// mixFrame 全バスから1フレームずつ読み出してミックスする
+func (m *Mixer) mixFrame(now time.Time, samples int) []int16 {
+	m.mu.Lock()
+	m.startScheduled(now)
+	type input struct {
+		bus   *bus
+		src   audio.Source
+		frame []int16
+	}
+	inputs := make([]input, 0, len(m.order))
+	for _, name := range m.order {
+		b := m.buses[name]
+		inputs = append(inputs, input{bus: b, src: b.src})
+	}
+	m.mu.Unlock()
+
+	// Source は独自のロックを持つため、ミキサーのロック外で読む
+	for i := range inputs {
+		if inputs[i].src != nil {
+			inputs[i].frame = inputs[i].src.ReadFrame()
+		}
+	}
+
+	m.mu.Lock()
+	duckTarget := 1.0
+	if m.state == MixerStateDucked {
+		duckTarget = dbToGain(m.duckLevel)
+	}
+	duckStep := (1.0 - dbToGain(m.duckLevel)) / math.Max(1, float64(m.format.Samples(duckRamp)))
+
+	acc := make([]float64, samples)
+	done := m.superseded
+	m.superseded = nil
+	for _, in := range inputs {
+		b := in.bus
+		gain := dbToGain(b.gainDB)
+		duck := m.duckGain
+		for i := 0; i < samples; i++ {
+			if b.left > 0 {
+				b.left--
+				b.fader += b.step
+				if b.left == 0 {
+					b.fader = b.target
+				}
+			}
+			if b.duckable {
+				duck = approach(duck, duckTarget, duckStep)
+			}
+			if i < len(in.frame) {
+				g := b.fader * gain
+				if b.duckable {
+					g *= duck
+				}
+				acc[i] += float64(in.frame[i]) * g
+			}
+		}
+		if b.left == 0 && b.onDone != nil {
+			done = append(done, b.onDone)
+			b.onDone = nil
+		}
+	}
+	m.duckGain = approach(m.duckGain, duckTarget, duckStep*float64(samples))
+	m.mu.Unlock()
+
+	for _, fn := range done {
+		fn()
+	}
+
+	out := make([]int16, samples)
+	for i, v := range acc {
+		out[i] = clip16(v)
+	}
+	return out
+}
+
+// startScheduled 時刻に達した予約クロスフェードを開始する（m.mu を保持した状態で呼ぶ）
+func (m *Mixer) startScheduled(now time.Time) {
+	remaining := m.pending[:0]
+	for _, s := range m.pending {
+		if now.Before(s.at) {
+			remaining = append(remaining, s)
+			continue
+		}
+		from, to := m.buses[s.from], m.buses[s.to]
+		if from == nil || to == nil {
+			continue
+		}
+		m.startFade(from, 0, s.duration, nil)
+		to.fader = 0
+		m.startFade(to, 1.0, s.duration, nil)
+		log.Printf("Mixer: Crossfade %s -> %s (%v)", s.from, s.to, s.duration)
+	}
+	m.pending = remaining
+}
+
+func approach(v, target, step float64) float64 {
+	if v < target {
+		return math.Min(v+step, target)
+	}
+	return math.Max(v-step, target)
+}
+
+func dbToGain(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+func clip16(v float64) int16 {
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(v)
+}
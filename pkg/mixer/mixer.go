@@ -5,6 +5,8 @@ import (
 	"log"
 	"sync"
 	"time"
+
+	"github.com/radio24/pkg/audio"
 )
 
 type MixerState string
@@ -19,6 +21,14 @@ type Mixer struct {
 	state        MixerState
 	duckLevel    float64 // -12dB to -18dB
 	duckDuration time.Duration
+	duckGain     float64 // 現在のダッキング倍率（クリックを避けるため徐々に追従）
+	buses        map[string]*bus
+	order        []string
+	pending      []scheduledCrossfade
+	superseded   []func() // 次のフェードに置き換えられたフェードの完了コールバック（次のフレームで呼ぶ）
+	format       audio.Format
+	running      bool
+	onDuckChange func(ducked bool)
 	ctx          context.Context
 	cancel       context.CancelFunc
 }
@@ -29,21 +39,36 @@ func NewMixer() *Mixer {
 		state:        MixerStateNormal,
 		duckLevel:    -15.0,            // -15dB
 		duckDuration: 30 * time.Second, // 30秒間ダッキング
+		duckGain:     1.0,
+		buses:        make(map[string]*bus),
+		format:       audio.FormatRealtime,
 		ctx:          ctx,
 		cancel:       cancel,
 	}
 }
 
-func (m *Mixer) DuckOn() {
+// SetOnDuckChange ダッキングのON/OFF（自動解除を含む）を通知するコールバックを設定
+func (m *Mixer) SetOnDuckChange(fn func(ducked bool)) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.onDuckChange = fn
+}
 
+func (m *Mixer) DuckOn() {
+	m.mu.Lock()
 	if m.state == MixerStateDucked {
+		m.mu.Unlock()
 		return // 既にダッキング中
 	}
 
 	m.state = MixerStateDucked
 	log.Printf("Mixer: Ducking ON (%.1fdB)", m.duckLevel)
+	notify := m.onDuckChange
+	m.mu.Unlock()
+
+	if notify != nil {
+		notify(true)
+	}
 
 	// 自動的にダッキング解除するタイマーを設定
 	go func() {
@@ -58,14 +83,19 @@ func (m *Mixer) DuckOn() {
 
 func (m *Mixer) DuckOff() {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	if m.state == MixerStateNormal {
+		m.mu.Unlock()
 		return // 既に通常状態
 	}
 
 	m.state = MixerStateNormal
 	log.Printf("Mixer: Ducking OFF (normal level)")
+	notify := m.onDuckChange
+	m.mu.Unlock()
+
+	if notify != nil {
+		notify(false)
+	}
 }
 
 func (m *Mixer) GetState() MixerState {
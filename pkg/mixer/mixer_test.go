@@ -0,0 +1,194 @@
+package mixer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMixerDuckOnOff(t *testing.T) {
+	m := NewMixer()
+	defer m.Stop()
+
+	if m.IsDucked() {
+		t.Fatal("new mixer should not be ducked")
+	}
+
+	m.DuckOn()
+	if m.GetState() != MixerStateDucked {
+		t.Fatalf("state = %s, want ducked", m.GetState())
+	}
+
+	m.DuckOff()
+	if m.GetState() != MixerStateNormal {
+		t.Fatalf("state = %s, want normal", m.GetState())
+	}
+}
+
+func TestMixerDuckReleasesAfterDuration(t *testing.T) {
+	m := NewMixer()
+	defer m.Stop()
+
+	m.SetDuckDuration(10 * time.Millisecond)
+	m.DuckOn()
+
+	deadline := time.Now().Add(time.Second)
+	for m.IsDucked() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if m.IsDucked() {
+		t.Fatal("expected ducking to be released automatically")
+	}
+}
+
+func TestMixerDuckLevelIsClamped(t *testing.T) {
+	m := NewMixer()
+	defer m.Stop()
+
+	m.SetDuckLevel(-60)
+	if got := m.GetDuckLevel(); got != -30 {
+		t.Errorf("duck level = %.1f, want -30", got)
+	}
+
+	m.SetDuckLevel(6)
+	if got := m.GetDuckLevel(); got != 0 {
+		t.Errorf("duck level = %.1f, want 0", got)
+	}
+}
+
+type constSource struct{ value int16 }
+
+func (s constSource) ReadFrame() []int16 {
+	frame := make([]int16, 480)
+	for i := range frame {
+		frame[i] = s.value
+	}
+	return frame
+}
+
+func TestMixerSumsBuses(t *testing.T) {
+	m := NewMixer()
+	defer m.Stop()
+
+	m.AddBus(BusVoice, constSource{1000}, false)
+	m.AddBus(BusMusic, constSource{500}, false)
+
+	frame := m.mixFrame(time.Now(), 480)
+	if frame[0] != 1500 || frame[479] != 1500 {
+		t.Fatalf("mixed = %d..%d, want 1500", frame[0], frame[479])
+	}
+}
+
+func TestMixerFadeOutCallsDone(t *testing.T) {
+	m := NewMixer()
+	defer m.Stop()
+
+	m.AddBus(BusVoice, constSource{1000}, false)
+
+	done := false
+	m.FadeOut(BusVoice, 20*time.Millisecond, func() { done = true })
+
+	frame := m.mixFrame(time.Now(), 480)
+	if frame[0] >= 1000 || frame[479] != 0 {
+		t.Fatalf("fade out = %d..%d, want ramp to 0", frame[0], frame[479])
+	}
+	if !done {
+		t.Fatal("done callback was not called")
+	}
+
+	if frame := m.mixFrame(time.Now(), 480); frame[0] != 0 {
+		t.Fatalf("after fade = %d, want 0", frame[0])
+	}
+}
+
+func TestMixerSupersededFadeStillCallsDone(t *testing.T) {
+	m := NewMixer()
+	defer m.Stop()
+
+	m.AddBus(BusVoice, constSource{1000}, false)
+
+	first := false
+	m.FadeOut(BusVoice, time.Second, func() { first = true })
+	m.FadeIn(BusVoice, 0)
+
+	m.mixFrame(time.Now(), 480)
+	if !first {
+		t.Fatal("callback of the replaced fade was lost")
+	}
+}
+
+func TestMixerScheduledCrossfade(t *testing.T) {
+	m := NewMixer()
+	defer m.Stop()
+
+	m.AddBus(BusVoice, constSource{1000}, false)
+	m.AddBus(BusMusic, constSource{-1000}, false)
+	m.FadeOut(BusMusic, 0, nil)
+
+	at := time.Now().Add(time.Second)
+	if err := m.ScheduleCrossfade(at, BusVoice, BusMusic, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if frame := m.mixFrame(at.Add(-time.Millisecond), 480); frame[479] != 1000 {
+		t.Fatalf("before schedule = %d, want 1000", frame[479])
+	}
+	if frame := m.mixFrame(at, 480); frame[479] != -1000 {
+		t.Fatalf("after crossfade = %d, want -1000", frame[479])
+	}
+}
+
+func TestMixerDuckAttenuatesDuckableBuses(t *testing.T) {
+	m := NewMixer()
+	defer m.Stop()
+
+	m.AddBus(BusVoice, constSource{1000}, false)
+	m.AddBus(BusMusic, constSource{1000}, true)
+	m.SetDuckLevel(-20)
+	m.DuckOn()
+
+	var frame []int16
+	for i := 0; i < 10; i++ {
+		frame = m.mixFrame(time.Now(), 480)
+	}
+	if frame[479] != 1100 {
+		t.Fatalf("ducked mix = %d, want 1100", frame[479])
+	}
+}
+
+func TestMixerBusGain(t *testing.T) {
+	m := NewMixer()
+	defer m.Stop()
+
+	m.AddBus(BusVoice, constSource{1000}, false)
+	if err := m.SetBusGain(BusVoice, -20); err != nil {
+		t.Fatal(err)
+	}
+	if frame := m.mixFrame(time.Now(), 480); frame[0] != 100 {
+		t.Fatalf("mixed = %d, want 100", frame[0])
+	}
+
+	if err := m.SetBusGain("unknown", 0); err == nil {
+		t.Fatal("expected error for unknown bus")
+	}
+
+	m.SetBusGain(BusVoice, 40)
+	if got := m.Snapshot().Buses[0].GainDB; got != MaxBusGain {
+		t.Errorf("gain = %.1f, want %.1f", got, MaxBusGain)
+	}
+}
+
+func TestMixerNotifiesDuckChange(t *testing.T) {
+	m := NewMixer()
+	defer m.Stop()
+
+	var events []bool
+	m.SetOnDuckChange(func(ducked bool) { events = append(events, ducked) })
+
+	m.DuckOn()
+	m.DuckOn()
+	m.DuckOff()
+
+	if len(events) != 2 || !events[0] || events[1] {
+		t.Fatalf("events = %v, want [true false]", events)
+	}
+}
@@ -10,6 +10,7 @@ require (
 	github.com/jackc/pgx/v5 v5.7.6
 	github.com/joho/godotenv v1.5.1
 	github.com/livekit/protocol v1.20.0
+	github.com/radio24/pkg v0.0.0
 )
 
 require (
@@ -34,3 +35,5 @@ require (
 	google.golang.org/protobuf v1.34.2 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/radio24/pkg => ../../pkg
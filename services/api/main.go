@@ -2,13 +2,17 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,7 +23,17 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/radio24/api/internal/livekit"
 	"github.com/radio24/api/pkg/broadcast"
+	"github.com/radio24/api/pkg/clock"
+	"github.com/radio24/api/pkg/memory"
+	"github.com/radio24/api/pkg/persona"
 	"github.com/radio24/api/pkg/queue"
+	"github.com/radio24/api/pkg/schedule"
+	"github.com/radio24/api/pkg/screener"
+	"github.com/radio24/api/pkg/scripts"
+	"github.com/radio24/api/pkg/submissions"
+	"github.com/radio24/pkg/callerlink"
+	"github.com/radio24/pkg/dialogue"
+	"github.com/radio24/pkg/llm"
 )
 
 type EphemeralResp struct {
@@ -34,6 +48,18 @@ type Submission struct {
 	Type string `json:"type"`
 }
 
+// ScheduleSlot 時間割の1枠（Program Directorが参照する）
+type ScheduleSlot struct {
+	Channel string `json:"channel"`
+	Hour    int    `json:"hour"`
+	Block   string `json:"block"`
+	Prompt  string `json:"prompt"`
+	Title   string `json:"title,omitempty"`
+	EntryID string `json:"entry_id"`
+	Persona string `json:"persona,omitempty"`
+	CoHost  string `json:"cohost,omitempty"`
+}
+
 type Theme struct {
 	Title string `json:"title"`
 	Color string `json:"color"`
@@ -42,21 +68,57 @@ type Theme struct {
 var db *sql.DB
 var tokenGenerator *livekit.TokenGenerator
 var pttQueue *queue.Queue
+var scheduleStore *schedule.Store
+var memoryStore *memory.Store
+var scriptStore *scripts.Store
+var submissionStore *submissions.Store
+var personaStore *persona.Store
+var llmProvider llm.Provider // 埋め込み・要約（OpenAI、テストモードでは Fake）
 var broadcastHub *broadcast.Hub
 var dialogueConnections map[string]*websocket.Conn
 var clientConnections map[string]*websocket.Conn // クライアントIDとWebSocket接続のマッピング
 
-// DialogueState 対話モードの状態管理
-type DialogueState struct {
-	Active       bool
-	ClientID     string
-	StartedAt    time.Time
-	LastActivity time.Time
-	RequestedBy  string // 対話をリクエストしたクライアントのID
-}
+// dialogues 通話（対話モード）の状態（Hostと遷移を送りあって揃える。無音タイムアウトは先にHostが判断する）
+var dialogues *dialogue.Machine
+
+// apiIdleMargin APIの無音タイムアウトをHostより長くする分（Hostが落ちて終了が届かない時だけAPIが打ち切る）
+const apiIdleMargin = 2 * time.Minute
+
+// hostLostTimeout Hostへのストリームが切れたまま、進行中の通話を終了するまでの時間
+const hostLostTimeout = 30 * time.Second
+
+// hostLostSince Hostへのストリームが切れた時刻（つながっている間はゼロ。expireDialogues だけが触る）
+var hostLostSince time.Time
+
+// screenings 待合室の通話者と放送前にやりとりするスクリーナー
+var screenings *screener.Screener
+
+// callerStream Hostへの常時接続のストリーム（通話者の音声・コミット・状態遷移を順番に送る）
+var callerStream *callerlink.Client
 
-var dialogueState *DialogueState
-var dialogueMutex sync.RWMutex
+// hostDialogues Hostへ送る通話の状態遷移（起きた順に forwardDialoguesToHost が1件ずつ送る。上限なし）
+var (
+	hostDialoguesMutex sync.Mutex
+	hostDialogues      []dialogue.Transition
+	hostDialoguesReady = make(chan struct{}, 1)
+)
+
+// callerSendTimeout Hostの処理が詰まっている時に送信枠を待つ上限（超えたら音声を捨てる）
+const callerSendTimeout = 500 * time.Millisecond
+
+// 放送中のテーマ（毎正時に時間割から切り替わる）
+var currentTheme = Theme{Title: "Radio-24", Color: "#1a1a2e"}
+var themeMutex sync.RWMutex
+
+// blockThemes 時間割の枠ごとのテーマ
+var blockThemes = map[string]Theme{
+	"OP":      {Title: "オープニング", Color: "#2d4059"},
+	"NEWS":    {Title: "ニュース", Color: "#16213e"},
+	"QANDA":   {Title: "リスナーQ&A", Color: "#0f3460"},
+	"MUSIC":   {Title: "ミュージック", Color: "#1a1a2e"},
+	"TOPIC_A": {Title: "トーク", Color: "#533483"},
+	"JINGLE":  {Title: "Radio-24", Color: "#1a1a2e"},
+}
 
 func main() {
 	// 環境変数読み込み（リポジトリルートの.envファイル）
@@ -97,6 +159,13 @@ func main() {
 
 	// テーブル作成
 	createTables()
+	scheduleStore = schedule.NewStore(db)
+	memoryStore = memory.NewStore(db)
+	scriptStore = scripts.NewStore(db)
+	submissionStore = submissions.NewStore(db)
+	personaStore = persona.NewStore(db)
+	llmProvider = llm.Resilient(llm.FromEnv(), llm.DefaultRetryPolicy, nil)
+	screenings = screener.New(llmProvider.Chat)
 
 	// LiveKit Token Generator初期化
 	livekitAPIKey := getEnv("LIVEKIT_API_KEY", "devkey")
@@ -114,6 +183,25 @@ func main() {
 	// 対話接続管理初期化
 	dialogueConnections = make(map[string]*websocket.Conn)
 	clientConnections = make(map[string]*websocket.Conn)
+	// 無音タイムアウトは音声を受け取るHostが判断する。APIはそれより長く待っても終わらない通話を打ち切る
+	idleTimeout, err := time.ParseDuration(getEnv("DIALOGUE_IDLE_TIMEOUT", dialogue.DefaultIdleTimeout.String()))
+	if err != nil || idleTimeout <= 0 {
+		idleTimeout = dialogue.DefaultIdleTimeout
+	}
+	dialogues = dialogue.NewMachine(idleTimeout + apiIdleMargin)
+	dialogues.OwnsRequests = true
+	dialogues.OnTransition = func(t dialogue.Transition) {
+		announceDialogue(t)
+		notifyHostDialogue(t)
+	}
+	go expireDialogues(context.Background())
+
+	// Hostへのストリーム（切れたらつなぎ直す）
+	window, _ := strconv.Atoi(getEnv("CALLER_STREAM_WINDOW", strconv.Itoa(callerlink.DefaultWindow)))
+	callerStream = callerlink.NewClient(callerlink.URL(getEnv("HOST_BASE", "http://host:8080")), window)
+	callerStream.Control = handleHostControl
+	go callerStream.Run(context.Background())
+	go forwardDialoguesToHost(context.Background())
 
 	// ルーター設定
 	r := chi.NewRouter()
@@ -121,8 +209,9 @@ func main() {
 	r.Use(middleware.Recoverer)
 	r.Use(corsMiddleware())
 
-	// 対話モードのタイムアウトチェックを開始
-	go checkDialogueTimeout()
+	// 起動時のテーマを時間割から設定し、以降は毎正時に切り替え
+	updateThemeFromSchedule(time.Now())
+	go clock.RunTopOfHour(context.Background(), handleTopOfHour)
 
 	// ルート
 	r.Get("/health", handleHealth)
@@ -131,11 +220,34 @@ func main() {
 	r.Post("/v1/realtime/ephemeral", handleEphemeral)
 	r.Post("/v1/room/join", handleRoomJoin)
 	r.Post("/v1/submission", handleSubmission)
+	r.Get("/v1/submissions/relevant", handleRelevantSubmissions)
+	r.Post("/v1/submissions/featured", handleSubmissionsFeatured)
 	r.Post("/v1/theme/rotate", handleThemeRotate)
+	r.Get("/v1/theme", handleThemeCurrent)
+	r.Get("/v1/schedule/now", handleScheduleNow)
+	r.Get("/v1/schedule/week", handleScheduleWeek)
+	r.Get("/v1/schedule", handleScheduleList)
+	r.Post("/v1/schedule", handleScheduleCreate)
+	r.Get("/v1/schedule/{id}", handleScheduleGet)
+	r.Put("/v1/schedule/{id}", handleScheduleUpdate)
+	r.Delete("/v1/schedule/{id}", handleScheduleDelete)
+	r.Get("/v1/personas", handlePersonaList)
+	r.Get("/v1/personas/{id}", handlePersonaGet)
+	r.Put("/v1/personas/{id}", handlePersonaPut)
+	r.Delete("/v1/personas/{id}", handlePersonaDelete)
+	r.Post("/v1/memory", handleMemoryAdd)
+	r.Get("/v1/memory/context", handleMemoryContext)
+	r.Post("/v1/memory/compact", handleMemoryCompact)
+	r.Post("/v1/scripts", handleScriptAired)
+	r.Post("/v1/scripts/check", handleScriptCheck)
 	r.Get("/v1/queue/peek", handleQueuePeek)
 	r.Post("/v1/queue/dequeue", handleQueueDequeue)
 	r.Post("/v1/broadcast", handleBroadcastMessage)
 	r.Get("/v1/dialogue/status", handleDialogueStatus)
+	r.Post("/v1/dialogue/events", handleDialogueEvent)
+	r.Get("/v1/dialogue/waiting", handleDialogueWaiting)
+	r.Post("/v1/dialogue/waiting/{id}/pick", handleDialoguePick)
+	r.Get("/v1/dialogue/next", handleDialogueNext)
 	r.Post("/v1/subtitle", handleSubtitle)
 	r.Get("/v1/queue/item/{id}", handleQueueItem)
 
@@ -199,36 +311,39 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// pttConn PTTのWebSocket接続（読み込みループとスクリーナーのゴルーチンの両方から書くので、書き込みは1つずつ）
+type pttConn struct {
+	*websocket.Conn
+	mu sync.Mutex
+}
+
+func (c *pttConn) WriteJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Conn.WriteJSON(v)
+}
+
 func handlePTTWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	ws, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade failed: %v", err)
 		return
 	}
+	conn := &pttConn{Conn: ws}
 
 	// クライアントIDを生成
 	clientID := fmt.Sprintf("client_%d", time.Now().UnixNano())
 
 	// クライアント接続を記録
-	clientConnections[clientID] = conn
+	clientConnections[clientID] = ws
 
 	defer func() {
 		// クライアント接続を削除
 		delete(clientConnections, clientID)
 
-		// 切断時に対話モードを終了（リクエストしたクライアントの場合のみ）
-		dialogueMutex.Lock()
-		if dialogueState != nil && dialogueState.Active && dialogueState.RequestedBy == clientID {
-			log.Println("Dialogue requester disconnected - ending dialogue mode")
-			dialogueState = nil
-			dialogueMutex.Unlock()
-
-			// 他のクライアントに対話終了を通知
-			broadcastHub.Broadcast("dialogue_ended", map[string]interface{}{
-				"reason": "requester_disconnected",
-			})
-		} else {
-			dialogueMutex.Unlock()
+		// 切断したクライアントのリクエストは取り下げ、通話中なら終了処理に進める
+		if abandoned := dialogues.Abandon(clientID, dialogue.ReasonRequesterDisconnected); len(abandoned) > 0 {
+			log.Printf("Dialogue requester disconnected - abandoned %d dialogue(s)", len(abandoned))
 		}
 		conn.Close()
 	}()
@@ -274,41 +389,64 @@ func handlePTTWebSocket(w http.ResponseWriter, r *http.Request) {
 			conn.WriteJSON(response)
 
 		case "dialogue_request":
-			// 対話リクエストをキューに追加
-			kind, _ := msg["kind"].(string)
-
-			item := queue.PTTItem{
-				ID:       fmt.Sprintf("dialogue_%d", time.Now().UnixNano()),
-				UserID:   clientID, // クライアントIDを使用
-				Kind:     queue.PTTKind(kind),
-				Text:     "対話リクエスト",
-				Priority: 10, // 対話リクエストは高優先度
+			// 対話リクエストを待合室に入れる（topic は事前質問「何について話したい？」への答え）
+			topic, _ := msg["topic"].(string)
+
+			// 待っている・通話中のリクエストがあれば受け付けない（1人1件まで）
+			if hasDialogue(clientID) {
+				conn.WriteJSON(map[string]interface{}{
+					"type":   "dialogue_request_denied",
+					"reason": "already_requested",
+				})
+				continue
 			}
 
-			pttQueue.Enqueue(item)
-			log.Printf("Dialogue request enqueued: %s by client: %s", item.ID, clientID)
+			id := fmt.Sprintf("dialogue_%d", time.Now().UnixNano())
+			dialogues.Request(id, clientID, trimTopic(topic))
+			position, waiting := waitingPosition(id)
+			log.Printf("Dialogue request %s by client %s is #%d of %d in the waiting room", id, clientID, position, waiting)
 
-			// クライアントに確認応答（クライアントIDも含める）
+			// クライアントに確認応答（クライアントIDと待合室での順番も含める）
 			response := map[string]interface{}{
 				"type":      "dialogue_queued",
-				"id":        item.ID,
+				"id":        id,
 				"client_id": clientID,
+				"position":  position,
+				"waiting":   waiting,
 			}
 			conn.WriteJSON(response)
 
+			// 放送前のスクリーニングを始める（LLMを待つ間も読み込みは止めない）
+			startScreening(conn, id, trimTopic(topic))
+
+		case "screening_message":
+			// 待合室でのスクリーナーとのやりとり
+			text, _ := msg["text"].(string)
+			text = strings.TrimSpace(text)
+			session, ok := waitingRequest(clientID)
+			if !ok || text == "" {
+				conn.WriteJSON(map[string]interface{}{
+					"type":   "screening_message_denied",
+					"reason": "not_waiting",
+				})
+				continue
+			}
+
+			if !queueScreeningMessage(session.ID, text) {
+				conn.WriteJSON(map[string]interface{}{
+					"type":   "screening_message_denied",
+					"reason": "busy",
+				})
+			}
+
 		case "dialogue_end":
 			// 対話終了リクエスト（リクエストしたクライアントのみ許可）
 			log.Printf("Dialogue end request received from client: %s", clientID)
 
-			// 対話状態をチェックして、リクエストしたクライアントかどうか確認
-			dialogueMutex.Lock()
-			if dialogueState != nil && dialogueState.Active && dialogueState.RequestedBy == clientID {
+			// 通話中で、リクエストしたクライアントかどうか確認（終了処理はHostが行う）
+			if session, ok := dialogues.Live(); ok && session.ClientID == clientID && session.State != dialogue.WrappingUp {
 				log.Printf("Dialogue end authorized for client: %s", clientID)
-				dialogueState = nil
-				dialogueMutex.Unlock()
-
-				// hostエージェントに対話終了を通知
-				endDialogueMode()
+				dialogues.Fire(session.ID, dialogue.EventWrapUp, dialogue.ReasonRequesterEnded)
 
 				// クライアントに確認応答
 				response := map[string]interface{}{
@@ -316,7 +454,6 @@ func handlePTTWebSocket(w http.ResponseWriter, r *http.Request) {
 				}
 				conn.WriteJSON(response)
 			} else {
-				dialogueMutex.Unlock()
 				log.Printf("Dialogue end request denied for client: %s (not the requester)", clientID)
 
 				// 拒否応答
@@ -330,18 +467,21 @@ func handlePTTWebSocket(w http.ResponseWriter, r *http.Request) {
 		case "input_audio_buffer.append":
 			// 対話モード中の音声入力（リクエストしたクライアントのみ許可）
 			audio, _ := msg["audio"].(string)
+			// 音声フォーマット（省略時はhost側で24kHz monoとして扱う）
+			sampleRate, _ := msg["sample_rate"].(float64)
+			channels, _ := msg["channels"].(float64)
 			log.Printf("Received audio input for dialogue from client: %s, %d bytes", clientID, len(audio))
 
-			// 対話状態をチェックして、リクエストしたクライアントかどうか確認
-			dialogueMutex.Lock()
-			if dialogueState != nil && dialogueState.Active && dialogueState.RequestedBy == clientID {
-				dialogueState.LastActivity = time.Now()
-				dialogueMutex.Unlock()
-
-				// OpenAI Realtimeに音声データを転送
-				forwardAudioToOpenAI(audio)
+			// 放送中の通話で、リクエストしたクライアントかどうか確認
+			if onAirBy(clientID) {
+				// OpenAI Realtimeに音声データを転送（Hostの処理が追いつかない時はクライアントに知らせる）
+				if err := forwardAudioToOpenAI(audio, int(sampleRate), int(channels)); errors.Is(err, callerlink.ErrBackpressure) {
+					conn.WriteJSON(map[string]interface{}{
+						"type":   "audio_input_throttled",
+						"reason": "host_busy",
+					})
+				}
 			} else {
-				dialogueMutex.Unlock()
 				log.Printf("Audio input denied for client: %s (not the requester or dialogue not active)", clientID)
 
 				// 拒否応答
@@ -356,15 +496,11 @@ func handlePTTWebSocket(w http.ResponseWriter, r *http.Request) {
 			// 音声入力のコミット（リクエストしたクライアントのみ許可）
 			log.Printf("Audio input commit request from client: %s", clientID)
 
-			// 対話状態をチェックして、リクエストしたクライアントかどうか確認
-			dialogueMutex.Lock()
-			if dialogueState != nil && dialogueState.Active && dialogueState.RequestedBy == clientID {
-				dialogueMutex.Unlock()
-
+			// 放送中の通話で、リクエストしたクライアントかどうか確認
+			if onAirBy(clientID) {
 				// OpenAI Realtimeにコミット信号を送信
 				commitAudioToOpenAI()
 			} else {
-				dialogueMutex.Unlock()
 				log.Printf("Audio commit denied for client: %s (not the requester or dialogue not active)", clientID)
 
 				// 拒否応答
@@ -413,25 +549,23 @@ func corsMiddleware() func(http.Handler) http.Handler {
 }
 
 func handleEphemeral(w http.ResponseWriter, r *http.Request) {
-	// OpenAIの client_secrets を叩いて短命キーを発行
+	// Realtime の client_secrets を叩いて短命キーを発行（OPENAI_REALTIME_BASE_URL などの接続先を使う）
+	client, ok := llmProvider.Realtime.(*llm.OpenAI)
+	if !ok {
+		http.Error(w, "Realtime is not available in test mode", http.StatusServiceUnavailable)
+		return
+	}
 	payload := map[string]any{
 		"session": map[string]any{
 			"type": "realtime",
 		},
 	}
-	b, _ := json.Marshal(payload)
 
-	req, _ := http.NewRequest("POST", "https://api.openai.com/v1/realtime/client_secrets", bytes.NewReader(b))
-	req.Header.Set("Authorization", "Bearer "+os.Getenv("OPENAI_API_KEY"))
-	req.Header.Set("Content-Type", "application/json")
-
-	res, err := http.DefaultClient.Do(req)
+	body, err := client.ClientSecret(r.Context(), payload)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
-	defer res.Body.Close()
-	body, _ := io.ReadAll(res.Body)
 
 	// 受け取った value のみをフロントへ返す（最小化）
 	var parsed EphemeralResp
@@ -497,23 +631,413 @@ func handleSubmission(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func handleThemeRotate(w http.ResponseWriter, r *http.Request) {
-	themes := []Theme{
-		{Title: "深夜の音楽", Color: "#1a1a2e"},
-		{Title: "朝のニュース", Color: "#16213e"},
-		{Title: "午後のトーク", Color: "#0f3460"},
-		{Title: "夜の物語", Color: "#533483"},
+// handleRelevantSubmissions トピックに近い最近の未紹介の投稿（台本で紹介する候補）
+func handleRelevantSubmissions(w http.ResponseWriter, r *http.Request) {
+	topic := r.URL.Query().Get("topic")
+	if topic == "" {
+		http.Error(w, "Topic is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := 3
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 10 {
+		limit = v
+	}
+	window := 24 * time.Hour
+	if v, err := time.ParseDuration(getEnv("SUBMISSION_WINDOW", "")); err == nil {
+		window = v
+	}
+	minSimilarity := 0.3
+	if v, err := strconv.ParseFloat(getEnv("SUBMISSION_MIN_SIMILARITY", ""), 64); err == nil {
+		minSimilarity = v
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	embedding, err := getEmbedding(topic)
+	if err != nil {
+		log.Printf("Failed to embed topic, no submissions to feature: %v", err)
+		json.NewEncoder(w).Encode(map[string]interface{}{"submissions": []interface{}{}})
+		return
+	}
+
+	subs, err := submissionStore.Relevant(embedding, time.Now().Add(-window), minSimilarity, limit)
+	if err != nil {
+		log.Printf("Failed to get relevant submissions: %v", err)
+		http.Error(w, "Failed to get submissions", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"submissions": subs})
+}
+
+// handleSubmissionsFeatured 放送で紹介した投稿を記録（以降は候補から外れる）
+func handleSubmissionsFeatured(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs   []string `json:"ids"`
+		Topic string   `json:"topic"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.IDs) == 0 {
+		http.Error(w, "ids is required", http.StatusBadRequest)
+		return
 	}
 
-	// 現在時刻に基づいてテーマを選択
-	hour := time.Now().Hour()
-	themeIndex := hour % len(themes)
-	theme := themes[themeIndex]
+	featured, err := submissionStore.MarkFeatured(req.IDs, req.Topic)
+	if err != nil {
+		log.Printf("Failed to mark submissions as featured: %v", err)
+		http.Error(w, "Failed to mark submissions", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Featured %d submissions on topic %q", featured, req.Topic)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "featured",
+		"featured": featured,
+	})
+}
+
+// handleThemeRotate 現在の時間割に合わせてテーマを切り替える
+func handleThemeRotate(w http.ResponseWriter, r *http.Request) {
+	theme := updateThemeFromSchedule(time.Now())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(theme)
+}
+
+func handleThemeCurrent(w http.ResponseWriter, r *http.Request) {
+	themeMutex.RLock()
+	theme := currentTheme
+	themeMutex.RUnlock()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(theme)
 }
 
+// updateThemeFromSchedule 時間割から at のテーマを決め、変わった場合は theme_changed を配信
+func updateThemeFromSchedule(at time.Time) Theme {
+	slot, err := lookupScheduleSlot("Radio-24", at)
+	if err != nil {
+		log.Printf("Failed to look up schedule for theme: %v", err)
+		themeMutex.RLock()
+		defer themeMutex.RUnlock()
+		return currentTheme
+	}
+	return applySlotTheme(slot)
+}
+
+// applySlotTheme 枠のテーマに切り替え、変わった場合は theme_changed を配信
+func applySlotTheme(slot ScheduleSlot) Theme {
+	themeMutex.Lock()
+	defer themeMutex.Unlock()
+
+	theme, ok := blockThemes[slot.Block]
+	if !ok || theme == currentTheme {
+		return currentTheme
+	}
+
+	currentTheme = theme
+	log.Printf("Theme changed: %s (%s)", theme.Title, slot.Block)
+	broadcastHub.Broadcast("theme_changed", map[string]interface{}{
+		"title": theme.Title,
+		"color": theme.Color,
+		"block": slot.Block,
+		"hour":  slot.Hour,
+	})
+	return theme
+}
+
+// handleTopOfHour 毎正時の処理（EVENT.TOP_OF_HOUR）：テーマ切り替えとhostへの時報アナウンスの指示
+func handleTopOfHour(t time.Time) {
+	log.Printf("EVENT.TOP_OF_HOUR: %s", t.Format(time.RFC3339))
+
+	// 前の時間帯の台本を要約にまとめる（直近の分はそのまま残す）
+	go compactMemory("Radio-24", t.Add(-memoryKeepRaw))
+
+	// 時間割は1回だけ引き、テーマの切り替えとHostへの通知の両方に使う
+	themeMutex.RLock()
+	theme := currentTheme
+	themeMutex.RUnlock()
+	slot, err := lookupScheduleSlot("Radio-24", t)
+	if err != nil {
+		log.Printf("Failed to look up schedule at top of hour: %v", err)
+	} else {
+		theme = applySlotTheme(slot)
+	}
+
+	apiBase := getEnv("HOST_BASE", "http://host:8080")
+
+	payload := map[string]interface{}{
+		"type":  "TOP_OF_HOUR",
+		"hour":  t.Hour(),
+		"block": slot.Block,
+		"theme": theme.Title,
+	}
+
+	jsonData, _ := json.Marshal(payload)
+
+	// HTTPクライアントにタイムアウトを設定
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+	}
+
+	resp, err := client.Post(apiBase+"/events", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		log.Printf("Failed to send top of hour event to host: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+}
+
+// handleScheduleNow 指定チャンネルの現在（または at で指定した時刻）の枠を返す
+func handleScheduleNow(w http.ResponseWriter, r *http.Request) {
+	channel := r.URL.Query().Get("channel")
+	if channel == "" {
+		channel = "Radio-24"
+	}
+
+	at := time.Now()
+	if v := r.URL.Query().Get("at"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid at (RFC3339 expected)", http.StatusBadRequest)
+			return
+		}
+		at = t.In(time.Local)
+	}
+
+	slot, err := lookupScheduleSlot(channel, at)
+	if errors.Is(err, schedule.ErrNotFound) {
+		http.Error(w, "No schedule for this hour", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to query schedule: %v", err)
+		http.Error(w, "Failed to query schedule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slot)
+}
+
+// lookupScheduleSlot 指定チャンネル・時刻の時間割の枠を取得（日付指定 > 曜日 > 毎日）
+func lookupScheduleSlot(channel string, at time.Time) (ScheduleSlot, error) {
+	entry, err := scheduleStore.At(channel, at)
+	if err != nil {
+		return ScheduleSlot{}, err
+	}
+	return ScheduleSlot{
+		Channel: channel,
+		Hour:    at.Hour(),
+		Block:   entry.Block,
+		Prompt:  entry.Prompt,
+		Title:   entry.Title,
+		EntryID: entry.ID,
+		Persona: entry.Persona,
+		CoHost:  entry.CoHost,
+	}, nil
+}
+
+// scheduleChannel リクエストの対象チャンネル（既定は Radio-24）
+func scheduleChannel(r *http.Request) string {
+	if channel := r.URL.Query().Get("channel"); channel != "" {
+		return channel
+	}
+	return "Radio-24"
+}
+
+// handleScheduleWeek start（YYYY-MM-DD、既定は今日）から7日分の番組表
+func handleScheduleWeek(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	if v := r.URL.Query().Get("start"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			http.Error(w, "Invalid start (YYYY-MM-DD expected)", http.StatusBadRequest)
+			return
+		}
+		start = t
+	}
+
+	entries, err := scheduleStore.List(scheduleChannel(r))
+	if err != nil {
+		log.Printf("Failed to list schedule: %v", err)
+		http.Error(w, "Failed to list schedule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"channel": scheduleChannel(r),
+		"days":    schedule.Week(entries, start),
+	})
+}
+
+func handleScheduleList(w http.ResponseWriter, r *http.Request) {
+	entries, err := scheduleStore.List(scheduleChannel(r))
+	if err != nil {
+		log.Printf("Failed to list schedule: %v", err)
+		http.Error(w, "Failed to list schedule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+	})
+}
+
+func handleScheduleGet(w http.ResponseWriter, r *http.Request) {
+	entry, err := scheduleStore.Get(scheduleChannel(r), chi.URLParam(r, "id"))
+	if err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+func handleScheduleCreate(w http.ResponseWriter, r *http.Request) {
+	var entry schedule.Entry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := scheduleStore.Create(scheduleChannel(r), entry)
+	if err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+	log.Printf("Schedule entry created: %s %s %02d:00 (+%dh)", entry.ID, entry.Block, entry.Hour, entry.Hours)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+func handleScheduleUpdate(w http.ResponseWriter, r *http.Request) {
+	var entry schedule.Entry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	entry.ID = chi.URLParam(r, "id")
+
+	entry, err := scheduleStore.Update(scheduleChannel(r), entry)
+	if err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+	log.Printf("Schedule entry updated: %s", entry.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+func handleScheduleDelete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := scheduleStore.Delete(scheduleChannel(r), id); err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+	log.Printf("Schedule entry deleted: %s", id)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deleted": true,
+		"id":      id,
+	})
+}
+
+// writeScheduleError 時間割操作のエラーをHTTPステータスに変換
+func writeScheduleError(w http.ResponseWriter, err error) {
+	var overlap *schedule.OverlapError
+	switch {
+	case errors.As(err, &overlap):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":    err.Error(),
+			"conflict": overlap.Existing,
+		})
+	case errors.Is(err, schedule.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, schedule.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Schedule operation failed: %v", err)
+		http.Error(w, "Schedule operation failed", http.StatusInternalServerError)
+	}
+}
+
+// handlePersonaList DJのペルソナ一覧（default は PERSONA_DEFAULT）
+func handlePersonaList(w http.ResponseWriter, r *http.Request) {
+	personas, err := personaStore.List()
+	if err != nil {
+		writePersonaError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"default":  getEnv("PERSONA_DEFAULT", ""),
+		"personas": personas,
+	})
+}
+
+func handlePersonaGet(w http.ResponseWriter, r *http.Request) {
+	p, err := personaStore.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		writePersonaError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// handlePersonaPut ペルソナを追加・更新（次に枠が切り替わった時からhostに反映）
+func handlePersonaPut(w http.ResponseWriter, r *http.Request) {
+	var p persona.Persona
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	p.ID = chi.URLParam(r, "id")
+
+	p, err := personaStore.Put(p)
+	if err != nil {
+		writePersonaError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+func handlePersonaDelete(w http.ResponseWriter, r *http.Request) {
+	if err := personaStore.Delete(chi.URLParam(r, "id")); err != nil {
+		writePersonaError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writePersonaError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, persona.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, persona.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Persona operation failed: %v", err)
+		http.Error(w, "Persona operation failed", http.StatusInternalServerError)
+	}
+}
+
 func createTables() {
 	// マイグレーション実行
 	migrationSQL := `
@@ -531,6 +1055,10 @@ func createTables() {
 	CREATE INDEX IF NOT EXISTS submission_embed_hnsw
 	ON submission USING hnsw (embed vector_cosine_ops);
 
+	-- 放送で紹介した投稿
+	ALTER TABLE submission ADD COLUMN IF NOT EXISTS featured_at TIMESTAMPTZ;
+	ALTER TABLE submission ADD COLUMN IF NOT EXISTS featured_topic TEXT;
+
 	-- Program Director用テーブル
 	CREATE TABLE IF NOT EXISTS channel (
 		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -548,6 +1076,24 @@ func createTables() {
 		created_at TIMESTAMPTZ DEFAULT now()
 	);
 
+	-- 曜日ルール・日付指定の上書き・複数時間の枠
+	ALTER TABLE schedule ADD COLUMN IF NOT EXISTS hours INTEGER DEFAULT 1 NOT NULL;
+	ALTER TABLE schedule ADD COLUMN IF NOT EXISTS day_of_week INTEGER;
+	ALTER TABLE schedule ADD COLUMN IF NOT EXISTS date DATE;
+	ALTER TABLE schedule ADD COLUMN IF NOT EXISTS title TEXT;
+	-- 起動のたびに重複して投入されていた毎日の基本枠を整理（同じ開始時の最古の行を残す）
+	DELETE FROM schedule a USING schedule b
+	WHERE a.channel_id = b.channel_id AND a.hour = b.hour
+		AND a.day_of_week IS NULL AND b.day_of_week IS NULL
+		AND a.date IS NULL AND b.date IS NULL
+		AND (a.created_at, a.id::text) > (b.created_at, b.id::text);
+	ALTER TABLE schedule DROP CONSTRAINT IF EXISTS schedule_rule_check;
+	ALTER TABLE schedule ADD CONSTRAINT schedule_rule_check CHECK (
+		hours >= 1 AND hour + hours <= 24
+		AND (day_of_week IS NULL OR day_of_week BETWEEN 0 AND 6)
+		AND NOT (day_of_week IS NOT NULL AND date IS NOT NULL)
+	);
+
 	CREATE TABLE IF NOT EXISTS queue (
 		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 		user_id TEXT,
@@ -589,10 +1135,53 @@ func createTables() {
 		SELECT generate_series(0, 23) as hour
 	) h
 	WHERE c.name = 'Radio-24'
-	ON CONFLICT DO NOTHING;
+	AND NOT EXISTS (SELECT 1 FROM schedule s WHERE s.channel_id = c.id);
+
+	-- DJのペルソナ（時間割の枠ごとに切り替え）
+	CREATE TABLE IF NOT EXISTS persona (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		style TEXT,
+		catchphrases JSONB NOT NULL DEFAULT '[]',
+		tts_voice TEXT,
+		realtime_voice TEXT,
+		forbidden_topics JSONB NOT NULL DEFAULT '[]',
+		updated_at TIMESTAMPTZ DEFAULT now()
+	);
+
+	ALTER TABLE schedule ADD COLUMN IF NOT EXISTS persona TEXT;
+	ALTER TABLE schedule ADD COLUMN IF NOT EXISTS cohost TEXT;
+
+	-- 放送の記憶（台本・リスナー・要約）
+	CREATE TABLE IF NOT EXISTS onair_memory (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		channel_id UUID REFERENCES channel(id) ON DELETE CASCADE,
+		kind TEXT CHECK (kind IN ('script', 'caller', 'summary')) NOT NULL,
+		topic TEXT,
+		text TEXT NOT NULL,
+		period_start TIMESTAMPTZ,
+		period_end TIMESTAMPTZ,
+		created_at TIMESTAMPTZ DEFAULT now()
+	);
+
+	-- 放送した台本（繰り返し検出用）
+	CREATE TABLE IF NOT EXISTS aired_script (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		channel_id UUID REFERENCES channel(id) ON DELETE CASCADE,
+		topic TEXT,
+		text TEXT NOT NULL,
+		embed VECTOR(1536),
+		created_at TIMESTAMPTZ DEFAULT now()
+	);
+
+	CREATE INDEX IF NOT EXISTS aired_script_embed_hnsw
+	ON aired_script USING hnsw (embed vector_cosine_ops);
 
 	-- インデックス作成
+	CREATE INDEX IF NOT EXISTS idx_aired_script_channel_created ON aired_script(channel_id, created_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_onair_memory_channel_kind ON onair_memory(channel_id, kind, created_at DESC);
 	CREATE INDEX IF NOT EXISTS idx_schedule_channel_hour ON schedule(channel_id, hour);
+	CREATE INDEX IF NOT EXISTS idx_schedule_channel_date ON schedule(channel_id, date);
 	CREATE INDEX IF NOT EXISTS idx_queue_status_enqueued ON queue(status, enqueued_at);
 	CREATE INDEX IF NOT EXISTS idx_queue_meta_priority ON queue USING GIN (meta);
 	`
@@ -604,48 +1193,12 @@ func createTables() {
 }
 
 func getEmbedding(text string) (string, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return "", fmt.Errorf("OPENAI_API_KEY not set")
-	}
-
-	payload := map[string]interface{}{
-		"model": "text-embedding-3-small",
-		"input": text,
-	}
-
-	jsonData, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", "https://api.openai.com/v1/embeddings", bytes.NewBuffer(jsonData))
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("OpenAI API error: %s", string(body))
-	}
-
-	var result struct {
-		Data []struct {
-			Embedding []float64 `json:"embedding"`
-		} `json:"data"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
-	}
-
-	if len(result.Data) == 0 {
-		return "", fmt.Errorf("no embedding returned")
+	embedding, err := llmProvider.Embeddings.Embed(context.Background(), text)
+	if err != nil {
+		return "", err
 	}
 
 	// ベクトルを文字列に変換（pgvector形式）
-	embedding := result.Data[0].Embedding
 	embeddingStr := "["
 	for i, val := range embedding {
 		if i > 0 {
@@ -737,21 +1290,6 @@ func handleBroadcastMessage(w http.ResponseWriter, r *http.Request) {
 	msgType := msg["type"].(string)
 	log.Printf("Broadcasting message: %s", msgType)
 
-	// 対話開始メッセージの場合は状態を更新
-	if msgType == "dialogue_ready" {
-		// リクエストIDからクライアントIDを取得
-		requestID, _ := msg["id"].(string)
-		clientID, _ := msg["client_id"].(string)
-
-		if clientID == "" {
-			clientID = "anonymous" // デフォルト値
-			log.Printf("No client_id in dialogue_ready message, using default")
-		}
-
-		log.Printf("Starting dialogue mode for client: %s (request: %s)", clientID, requestID)
-		startDialogueMode(clientID)
-	}
-
 	// Broadcast Hubにメッセージを送信
 	broadcastHub.Broadcast(msgType, msg)
 
@@ -762,15 +1300,61 @@ func handleBroadcastMessage(w http.ResponseWriter, r *http.Request) {
 }
 
 // forwardAudioToOpenAI OpenAI Realtimeに音声データを転送
-func forwardAudioToOpenAI(audioData string) {
-	// 実際の実装では、hostエージェントのOpenAI Realtime接続に音声データを転送
-	// ここでは簡略化のため、hostエージェントにHTTPリクエストで通知
-	apiBase := getEnv("HOST_BASE", "http://host:8080")
+//
+// Hostへのストリームで送り、Hostの処理が詰まっていれば callerlink.ErrBackpressure を返す（その音声は捨てる）。
+// ストリームがつながっていない間はHTTPで送る。
+func forwardAudioToOpenAI(audioData string, sampleRate, channels int) error {
+	pcm, err := base64.StdEncoding.DecodeString(audioData)
+	if err != nil {
+		log.Printf("Failed to decode caller audio: %v", err)
+		return err
+	}
+	if live, ok := dialogues.Live(); ok {
+		dialogues.Touch(live.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callerSendTimeout)
+	defer cancel()
+	err = callerStream.Send(ctx, callerlink.Frame{Kind: callerlink.KindAudio, SampleRate: sampleRate, Channels: channels, Audio: pcm})
+	if !errors.Is(err, callerlink.ErrNotConnected) {
+		if err != nil {
+			log.Printf("Failed to stream audio to host agent: %v", err)
+		}
+		return err
+	}
 
 	payload := map[string]interface{}{
 		"type":  "audio_input",
 		"audio": audioData,
 	}
+	if sampleRate > 0 {
+		payload["sample_rate"] = sampleRate
+	}
+	if channels > 0 {
+		payload["channels"] = channels
+	}
+	return postToHost("/audio/input", payload)
+}
+
+// commitAudioToOpenAI OpenAI Realtimeに音声コミット信号を送信
+func commitAudioToOpenAI() {
+	ctx, cancel := context.WithTimeout(context.Background(), callerSendTimeout)
+	defer cancel()
+	err := callerStream.Send(ctx, callerlink.Frame{Kind: callerlink.KindCommit})
+	if errors.Is(err, callerlink.ErrNotConnected) {
+		err = postToHost("/audio/commit", map[string]interface{}{"type": "audio_commit"})
+	}
+	if err != nil {
+		log.Printf("Failed to commit audio to OpenAI: %v", err)
+		return
+	}
+
+	log.Printf("Audio commit signal sent to OpenAI Realtime")
+}
+
+// postToHost ストリームが使えない時にhostエージェントへHTTPで送る
+func postToHost(path string, payload interface{}) error {
+	apiBase := getEnv("HOST_BASE", "http://host:8080")
 
 	jsonData, _ := json.Marshal(payload)
 
@@ -779,74 +1363,356 @@ func forwardAudioToOpenAI(audioData string) {
 		Timeout: 5 * time.Second,
 	}
 
-	resp, err := client.Post(apiBase+"/audio/input", "application/json", bytes.NewBuffer(jsonData))
+	resp, err := client.Post(apiBase+path, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
-		log.Printf("Failed to forward audio to OpenAI: %v", err)
-		return
+		return err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("host agent returned status %d for %s", resp.StatusCode, path)
+	}
+	return nil
+}
 
-	log.Printf("Audio forwarded to OpenAI Realtime")
+// notifyHostDialogue APIで起きた通話の状態遷移（リクエスト・終了の要求）をhostエージェントに送る
+//
+// 列に積むだけで待たない（Hostが落ちていても Fire を呼んだ側、PTTの読み込みループなどは止まらない）。
+// 送るのは forwardDialoguesToHost に任せる。
+func notifyHostDialogue(t dialogue.Transition) {
+	hostDialoguesMutex.Lock()
+	hostDialogues = append(hostDialogues, t)
+	hostDialoguesMutex.Unlock()
+	select {
+	case hostDialoguesReady <- struct{}{}:
+	default:
+	}
 }
 
-// commitAudioToOpenAI OpenAI Realtimeに音声コミット信号を送信
-func commitAudioToOpenAI() {
-	// 実際の実装では、hostエージェントのOpenAI Realtime接続にコミット信号を転送
-	apiBase := getEnv("HOST_BASE", "http://host:8080")
+// takeHostDialogues 送る列に積まれた状態遷移をまとめて取り出す
+func takeHostDialogues() []dialogue.Transition {
+	hostDialoguesMutex.Lock()
+	defer hostDialoguesMutex.Unlock()
+	queued := hostDialogues
+	hostDialogues = nil
+	return queued
+}
 
-	payload := map[string]interface{}{
-		"type": "audio_commit",
+// forwardDialoguesToHost 通話の状態遷移を起きた順にhostエージェントへ送る
+func forwardDialoguesToHost(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hostDialoguesReady:
+		}
+		for _, t := range takeHostDialogues() {
+			sendDialogueToHost(t)
+		}
 	}
+}
 
-	jsonData, _ := json.Marshal(payload)
+// hostDialogueRetries ストリームで送れなかった状態遷移をHTTPで送る回数
+const hostDialogueRetries = 3
+
+// sendDialogueToHost 状態遷移をhostエージェントに送る
+//
+// 音声と同じストリームで送るので、終了の要求はそれまでの音声の後に届く。
+// ストリームが切れている・詰まっている時は捨てずにHTTPで送る（この場合は音声より先に届くことがある）。
+func sendDialogueToHost(t dialogue.Transition) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	err := callerStream.Send(ctx, callerlink.Frame{Kind: callerlink.KindDialogue, Dialogue: &t})
+	cancel()
+	if err == nil {
+		log.Printf("Dialogue %s sent to host agent: %s -> %s", t.Event, t.From, t.To)
+		return
+	}
+	log.Printf("Caller stream unavailable for dialogue %s, sending over HTTP: %v", t.Event, err)
 
-	// HTTPクライアントにタイムアウトを設定
-	client := &http.Client{
-		Timeout: 5 * time.Second,
+	for attempt := 1; ; attempt++ {
+		err := postToHost("/dialogue/events", t)
+		if err == nil {
+			log.Printf("Dialogue %s sent to host agent over HTTP: %s -> %s", t.Event, t.From, t.To)
+			return
+		}
+		if attempt == hostDialogueRetries {
+			log.Printf("Failed to send dialogue %s to host agent: %v", t.Event, err)
+			return
+		}
+		time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
 	}
+}
 
-	resp, err := client.Post(apiBase+"/audio/commit", "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Printf("Failed to commit audio to OpenAI: %v", err)
-		return
+// announceDialogue 通話の状態遷移をクライアントに配信
+//
+// 遷移そのものは dialogue_state、放送の開始・終了は従来どおり dialogue_ready・dialogue_ended でも送る。
+// 待合室の顔ぶれが変わったら dialogue_waiting で順番を知らせる。
+func announceDialogue(t dialogue.Transition) {
+	// 事前質問への答えは番組側（GET /v1/dialogue/waiting）だけに見せる
+	public := t
+	public.Topic = ""
+	broadcastHub.Broadcast("dialogue_state", public)
+
+	switch {
+	case t.To == dialogue.OnAir:
+		broadcastHub.Broadcast("dialogue_ready", map[string]interface{}{
+			"id":        t.ID,
+			"client_id": t.ClientID,
+		})
+	case t.To == dialogue.Ended && t.From == dialogue.Requested:
+		// 待合室から取り下げた・スクリーニングで外しただけ（放送はしていない）
+	case t.To == dialogue.Ended:
+		session, _ := dialogues.Get(t.ID)
+		broadcastHub.Broadcast("dialogue_ended", map[string]interface{}{
+			"id":     t.ID,
+			"reason": session.Reason,
+		})
 	}
-	defer resp.Body.Close()
 
-	log.Printf("Audio commit signal sent to OpenAI Realtime")
+	if t.From == dialogue.Requested {
+		// 放送に出た・取り下げたらスクリーナーとのやりとりは要らない（要約は通話に付いている）
+		stopScreening(t.ID)
+		screenings.Forget(t.ID)
+	}
+	if t.From == dialogue.Requested || t.To == dialogue.Requested {
+		announceWaitingRoom()
+	}
 }
 
-// endDialogueMode 対話モードを終了し、hostエージェントに通知
-func endDialogueMode() {
-	// 対話状態をクリア
-	dialogueMutex.Lock()
-	if dialogueState != nil {
-		log.Printf("Dialogue mode ended for client: %s", dialogueState.ClientID)
-		dialogueState = nil
+// maxTopicLength 事前質問への答えの上限（文字数）
+const maxTopicLength = 200
+
+// trimTopic 事前質問への答えを整える
+func trimTopic(topic string) string {
+	topic = strings.TrimSpace(topic)
+	if runes := []rune(topic); len(runes) > maxTopicLength {
+		topic = string(runes[:maxTopicLength])
 	}
-	dialogueMutex.Unlock()
+	return topic
+}
 
-	// hostエージェントに対話終了を通知
-	apiBase := getEnv("HOST_BASE", "http://host:8080")
+// waitingCaller 待合室の1人（position は1始まり）
+type waitingCaller struct {
+	dialogue.Session
+	Position int `json:"position"`
+}
 
-	payload := map[string]interface{}{
-		"type": "end_dialogue",
+// waitingRoom 待合室の並び（次に放送に出る人が先頭）
+func waitingRoom() []waitingCaller {
+	waiting := dialogues.Waiting()
+	callers := make([]waitingCaller, len(waiting))
+	for i, s := range waiting {
+		callers[i] = waitingCaller{Session: s, Position: i + 1}
 	}
+	return callers
+}
 
-	jsonData, _ := json.Marshal(payload)
+// waitingPosition id の待合室での順番と待っている人数（いなければ順番は0）
+func waitingPosition(id string) (position, waiting int) {
+	callers := waitingRoom()
+	for _, c := range callers {
+		if c.ID == id {
+			position = c.Position
+		}
+	}
+	return position, len(callers)
+}
 
-	// HTTPクライアントにタイムアウトを設定
-	client := &http.Client{
-		Timeout: 5 * time.Second,
+// hasDialogue clientID のリクエストが待合室にいるか、通話中か
+func hasDialogue(clientID string) bool {
+	if session, ok := dialogues.Live(); ok && session.ClientID == clientID {
+		return true
+	}
+	_, ok := waitingRequest(clientID)
+	return ok
+}
+
+// waitingRequest clientID の待合室にいるリクエスト
+func waitingRequest(clientID string) (dialogue.Session, bool) {
+	for _, s := range dialogues.Waiting() {
+		if s.ClientID == clientID {
+			return s, true
+		}
 	}
+	return dialogue.Session{}, false
+}
+
+// screeningTimeout スクリーナーの1回の返事・判定の上限
+const screeningTimeout = 20 * time.Second
+
+// screeningWait 事前質問に答えず、スクリーナーにも返事をしない通話者を判定するまでの待ち時間
+const screeningWait = 2 * time.Minute
+
+// screeningRetry 判定に失敗した（LLMの障害など）通話者を判定し直すまでの間隔
+const screeningRetry = 30 * time.Second
 
-	resp, err := client.Post(apiBase+"/dialogue/end", "application/json", bytes.NewBuffer(jsonData))
+// screeningInboxes 待合室の通話者ごとに、スクリーナーへの発言を届いた順に渡す先
+var (
+	screeningMutex   sync.Mutex
+	screeningInboxes = map[string]chan string{}
+)
+
+// screeningInboxSize 返事を待っている間に受け付ける発言の数（やりとりは最大4回）
+const screeningInboxSize = 4
+
+// startScreening 通話者ごとのゴルーチンでスクリーナーとのやりとりを始める
+//
+// LLMの呼び出しはPTTの読み込みループの外で行い、同じ通話者の発言は届いた順に1つずつ返事をする。
+func startScreening(conn *pttConn, id, topic string) {
+	inbox := make(chan string, screeningInboxSize)
+	screeningMutex.Lock()
+	screeningInboxes[id] = inbox
+	screeningMutex.Unlock()
+
+	go func() {
+		openScreening(conn, id, topic)
+		for text := range inbox {
+			replyToCaller(conn, id, text)
+		}
+	}()
+}
+
+// queueScreeningMessage 通話者の発言をスクリーナーに渡す（やりとりが終わっている・詰まっていれば false）
+func queueScreeningMessage(id, text string) bool {
+	screeningMutex.Lock()
+	defer screeningMutex.Unlock()
+	inbox, ok := screeningInboxes[id]
+	if !ok {
+		return false
+	}
+	select {
+	case inbox <- text:
+		return true
+	default:
+		return false
+	}
+}
+
+// stopScreening 待合室を出た通話者のやりとりを終える
+func stopScreening(id string) {
+	screeningMutex.Lock()
+	defer screeningMutex.Unlock()
+	if inbox, ok := screeningInboxes[id]; ok {
+		close(inbox)
+		delete(screeningInboxes, id)
+	}
+}
+
+// replyToCaller 通話者の発言にスクリーナーが返事をし、これまでのやりとりを判定する
+func replyToCaller(conn *pttConn, id, text string) {
+	ctx, cancel := context.WithTimeout(context.Background(), screeningTimeout)
+	defer cancel()
+
+	reply, done, err := screenings.Say(ctx, id, text)
 	if err != nil {
-		log.Printf("Failed to notify host agent to end dialogue: %v", err)
-		return
+		log.Printf("Screener reply failed for %s: %v", id, err)
 	}
-	defer resp.Body.Close()
+	conn.WriteJSON(map[string]interface{}{
+		"type": "screening_message",
+		"id":   id,
+		"role": screener.RoleScreener,
+		"text": reply,
+		"done": done,
+	})
+	screenCaller(ctx, conn, id)
+}
 
-	log.Printf("Dialogue end notification sent to host agent")
+// openScreening 待合室に入った通話者にスクリーナーの最初の問いかけを送り、事前質問への答えがあれば判定する
+func openScreening(conn *pttConn, id, topic string) {
+	ctx, cancel := context.WithTimeout(context.Background(), screeningTimeout)
+	defer cancel()
+
+	opener, err := screenings.Start(ctx, id, topic)
+	if err != nil {
+		log.Printf("Screener opener failed for %s, using fallback: %v", id, err)
+	}
+	conn.WriteJSON(map[string]interface{}{
+		"type": "screening_message",
+		"id":   id,
+		"role": screener.RoleScreener,
+		"text": opener,
+		"done": false,
+	})
+	if topic != "" {
+		screenCaller(ctx, conn, id)
+	}
+
+	// 返事がないままでも判定はする（判定を通るまでは放送に出さない）
+	time.AfterFunc(screeningWait, func() {
+		if s, ok := dialogues.Get(id); !ok || s.State != dialogue.Requested || s.Cleared {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), screeningTimeout)
+		defer cancel()
+		log.Printf("Dialogue request %s did not answer the screener, judging what we have", id)
+		judgeCaller(ctx, id)
+	})
+}
+
+// screenCaller これまでのやりとりを判定し、不適切なら通話者に断りを送る
+func screenCaller(ctx context.Context, conn *pttConn, id string) {
+	if judgeCaller(ctx, id) {
+		conn.WriteJSON(map[string]interface{}{
+			"type":   "dialogue_request_denied",
+			"id":     id,
+			"reason": dialogue.ReasonScreenedOut,
+		})
+	}
+}
+
+// judgeCaller これまでのやりとりを判定して要約を通話に付ける（不適切なら待合室から外して true）
+//
+// 判定を通った通話だけが Host に放送に出す人として渡る。判定できなかった時は通さずに待たせたまま、
+// screeningRetry 後に判定し直す。
+func judgeCaller(ctx context.Context, id string) bool {
+	result, err := screenings.Screen(ctx, id)
+	if err != nil {
+		log.Printf("Screening of %s failed, keeping the caller waiting (retrying in %v): %v", id, screeningRetry, err)
+		time.AfterFunc(screeningRetry, func() {
+			if s, ok := dialogues.Get(id); !ok || s.State != dialogue.Requested || s.Cleared {
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), screeningTimeout)
+			defer cancel()
+			judgeCaller(ctx, id)
+		})
+		return false
+	}
+
+	if result.Flagged {
+		log.Printf("Dialogue request %s screened out: %s", id, result.Reason)
+		_, err := dialogues.Fire(id, dialogue.EventEnd, dialogue.ReasonScreenedOut)
+		return err == nil
+	}
+
+	// 判定の間に取り下げた場合は付けない
+	if err := dialogues.Screen(id, result.Summary); err == nil {
+		log.Printf("Dialogue request %s screened: %s", id, result.Summary)
+	}
+	return false
+}
+
+// announceWaitingRoom 待合室の順番を配信（各クライアントは client_id で自分の順番を探す）
+func announceWaitingRoom() {
+	callers := waitingRoom()
+	standing := make([]map[string]interface{}, len(callers))
+	for i, c := range callers {
+		standing[i] = map[string]interface{}{
+			"id":           c.ID,
+			"client_id":    c.ClientID,
+			"position":     c.Position,
+			"picked":       c.Picked,
+			"requested_at": c.RequestedAt,
+		}
+	}
+	broadcastHub.Broadcast("dialogue_waiting", map[string]interface{}{
+		"waiting": standing,
+	})
+}
+
+// onAirBy clientID がリクエストした通話を放送中か（音声入力を受け付ける）
+func onAirBy(clientID string) bool {
+	session, ok := dialogues.Live()
+	return ok && session.State == dialogue.OnAir && session.ClientID == clientID
 }
 
 func getEnv(key, defaultValue string) string {
@@ -858,69 +1724,355 @@ func getEnv(key, defaultValue string) string {
 
 // handleDialogueStatus 対話状態確認エンドポイント
 func handleDialogueStatus(w http.ResponseWriter, r *http.Request) {
-	dialogueMutex.RLock()
-	active := dialogueState != nil && dialogueState.Active
+	current, waiting := dialogues.Status()
 	requestedBy := ""
-	if dialogueState != nil {
-		requestedBy = dialogueState.RequestedBy
+	active := false
+	if current != nil && current.Live() {
+		active = true
+		requestedBy = current.ClientID
 	}
-	dialogueMutex.RUnlock()
 
 	status := map[string]interface{}{
 		"active":       active,
 		"requested_by": requestedBy,
+		"session":      current,
+		"waiting":      waiting,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
 }
 
-// checkDialogueTimeout 対話モードのタイムアウト処理
-func checkDialogueTimeout() {
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
+// handleDialogueWaiting 待合室の一覧（事前質問への答えを含む。番組側で次に出す人を選ぶ用）
+func handleDialogueWaiting(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"waiting": waitingRoom(),
+	})
+}
 
-	for range ticker.C {
-		dialogueMutex.Lock()
-		if dialogueState != nil && dialogueState.Active {
-			if time.Since(dialogueState.LastActivity) > 5*time.Minute {
-				log.Println("Dialogue mode timeout - ending dialogue")
-				dialogueState = nil
-				dialogueMutex.Unlock()
-
-				// 他のクライアントに対話終了を通知
-				broadcastHub.Broadcast("dialogue_ended", map[string]interface{}{
-					"reason": "timeout",
-				})
-			} else {
-				dialogueMutex.Unlock()
+// handleDialoguePick 待合室の1人を次に放送に出す人に選ぶ
+func handleDialoguePick(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := dialogues.Pick(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	log.Printf("Dialogue request %s picked to go on air next", id)
+	announceWaitingRoom()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"waiting": waitingRoom(),
+	})
+}
+
+// handleDialogueNext 次に放送に出す通話（hostエージェントが通話の空いた時に取りに来る）
+func handleDialogueNext(w http.ResponseWriter, r *http.Request) {
+	var next *dialogue.Session
+	if s, ok := dialogues.Next(); ok {
+		next = &s
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"session": next,
+	})
+}
+
+// expireDialogues 進まなくなった通話を定期的に打ち切る（Hostが落ちた時など）
+func expireDialogues(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, t := range dialogues.Expire() {
+				log.Printf("Dialogue %s expired while %s", t.ID, t.From)
 			}
-		} else {
-			dialogueMutex.Unlock()
+			watchHost(callerStream.Connected(), now)
 		}
 	}
 }
 
-// startDialogueMode 対話モードを開始
-func startDialogueMode(clientID string) {
-	dialogueMutex.Lock()
-	defer dialogueMutex.Unlock()
+// watchHost Hostへのストリームが hostLostTimeout 以上切れたままなら進行中の通話を終了する
+func watchHost(connected bool, now time.Time) {
+	if connected {
+		hostLostSince = time.Time{}
+		return
+	}
+	if hostLostSince.IsZero() {
+		hostLostSince = now
+	}
+	if now.Sub(hostLostSince) < hostLostTimeout {
+		return
+	}
+	if live, ok := dialogues.Live(); ok {
+		log.Printf("Host unreachable for %v, ending dialogue %s", now.Sub(hostLostSince).Round(time.Second), live.ID)
+		dialogues.Fire(live.ID, dialogue.EventEnd, dialogue.ReasonHostLost)
+	}
+}
 
-	dialogueState = &DialogueState{
-		Active:       true,
-		ClientID:     clientID,
-		StartedAt:    time.Now(),
-		LastActivity: time.Now(),
-		RequestedBy:  clientID, // リクエストしたクライアントを記録
+// handleDialogueEvent hostエージェントで起きた通話の状態遷移を反映して配信
+func handleDialogueEvent(w http.ResponseWriter, r *http.Request) {
+	var t dialogue.Transition
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil || t.ID == "" || t.Event == "" {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	session, err := applyHostDialogue(t)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// handleHostControl Hostからのストリームで届いた control フレーム（/v1/dialogue/events と同じ処理）
+func handleHostControl(f callerlink.Frame) (int, error) {
+	if f.Dialogue == nil || f.Dialogue.ID == "" || f.Dialogue.Event == "" {
+		return http.StatusBadRequest, errors.New("control frame without transition")
 	}
-	log.Printf("Dialogue mode started for client: %s", clientID)
+	if _, err := applyHostDialogue(*f.Dialogue); err != nil {
+		return http.StatusConflict, err
+	}
+	return http.StatusOK, nil
+}
+
+// applyHostDialogue hostエージェントで起きた遷移を反映して配信する
+//
+// ストリームとHTTPの両方で届いた同じ遷移（ack が届く前に送り直したもの）は受け付けたものとして扱う。
+func applyHostDialogue(t dialogue.Transition) (dialogue.Session, error) {
+	applied, err := dialogues.Apply(t)
+	if err != nil {
+		if s, ok := dialogues.Get(t.ID); ok && t.To != "" && s.State == t.To {
+			return s, nil
+		}
+		// 重複・順序の入れ替わりなど（状態は変えない）
+		log.Printf("Dialogue event ignored: %v", err)
+		return dialogue.Session{}, err
+	}
+	log.Printf("Dialogue %s (%s): %s -> %s", applied.ID, applied.Event, applied.From, applied.To)
+	announceDialogue(applied)
+
+	session, _ := dialogues.Get(applied.ID)
+	return session, nil
+}
+
+// memoryKeepRaw 要約せずにそのまま残す直近の台本の期間
+const memoryKeepRaw = 15 * time.Minute
+
+// defaultMemoryBudget 記憶に使うトークン数の既定値
+const defaultMemoryBudget = 1200
+
+// handleMemoryAdd 放送した台本・リスナーの投稿や通話を記憶に追加
+func handleMemoryAdd(w http.ResponseWriter, r *http.Request) {
+	var e memory.Entry
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	e, err := memoryStore.Add(scheduleChannel(r), e)
+	switch {
+	case errors.Is(err, memory.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, memory.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("Failed to add memory: %v", err)
+		http.Error(w, "Failed to add memory", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(e)
+}
+
+// handleMemoryContext トークン予算（budget）に収めた記憶とプロンプト用の文面
+func handleMemoryContext(w http.ResponseWriter, r *http.Request) {
+	budget := defaultMemoryBudget
+	if v := r.URL.Query().Get("budget"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid budget", http.StatusBadRequest)
+			return
+		}
+		budget = n
+	}
+
+	c, err := memoryStore.Context(scheduleChannel(r), budget)
+	if err != nil {
+		log.Printf("Failed to load memory: %v", err)
+		http.Error(w, "Failed to load memory", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"summaries": c.Summaries,
+		"callers":   c.Callers,
+		"scripts":   c.Scripts,
+		"tokens":    c.Tokens,
+		"budget":    budget,
+		"prompt":    c.Prompt(),
+	})
+}
+
+// handleMemoryCompact 直近以外の台本を今すぐ要約にまとめる
+func handleMemoryCompact(w http.ResponseWriter, r *http.Request) {
+	summary, ok := compactMemory(scheduleChannel(r), time.Now().Add(-memoryKeepRaw))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"compacted": ok,
+		"summary":   summary,
+	})
+}
+
+// compactMemory before より前の台本を要約に置き換える
+func compactMemory(channel string, before time.Time) (memory.Entry, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	summary, ok, err := memoryStore.Compact(ctx, channel, before, summarizeMemory)
+	if err != nil {
+		log.Printf("Failed to compact memory: %v", err)
+		return memory.Entry{}, false
+	}
+	if ok {
+		log.Printf("Memory compacted: %s", summary.Text)
+	}
+	return summary, ok
+}
+
+// summarizeMemory 台本をLLMで要約（テストモード・失敗した場合は簡易要約）
+func summarizeMemory(ctx context.Context, entries []memory.Entry) (string, error) {
+	if llmProvider.IsFake() {
+		return memory.Digest(entries, 300), nil
+	}
+
+	var transcript string
+	for _, e := range entries {
+		transcript += fmt.Sprintf("[%s %s] %s\n", e.CreatedAt.In(time.Local).Format("15:04"), e.Topic, e.Text)
+	}
+
+	summary, err := llmProvider.Chat.Complete(ctx, llm.ChatRequest{
+		System:      "あなたはラジオ番組の構成作家です。DJが放送した内容を、後で話の続きをするための記録として200文字程度の日本語で要約してください。話した話題と印象的な内容を残してください。",
+		Prompt:      transcript,
+		MaxTokens:   300,
+		Temperature: 0.3,
+	})
+	if err != nil {
+		log.Printf("Failed to summarize memory, using digest: %v", err)
+		return memory.Digest(entries, 300), nil
+	}
+	return summary, nil
+}
+
+// handleScriptAired 放送した台本を埋め込みベクトル付きで保存
+func handleScriptAired(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Topic     string `json:"topic"`
+		Text      string `json:"text"`
+		Embedding string `json:"embedding"` // チェックの時に得た埋め込み（あれば作り直さない）
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Text == "" {
+		http.Error(w, "Text is required", http.StatusBadRequest)
+		return
+	}
+
+	embedding := req.Embedding
+	if _, err := scripts.ParseVector(embedding); err != nil {
+		// 埋め込みが取れなくても台本自体は残す
+		embedding, err = getEmbedding(req.Text)
+		if err != nil {
+			log.Printf("Failed to embed aired script: %v", err)
+		}
+	}
+
+	sc, err := scriptStore.Add(scheduleChannel(r), req.Topic, req.Text, embedding)
+	if err != nil {
+		log.Printf("Failed to store aired script: %v", err)
+		http.Error(w, "Failed to store script", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sc)
+}
+
+// handleScriptCheck 新しい台本が最近放送した台本・放送待ちの台本と似すぎていないかをチェック
+//
+// 放送した時に作り直さなくて済むよう、埋め込みも返す。
+func handleScriptCheck(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Text    string            `json:"text"`
+		Pending []scripts.Pending `json:"pending"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Text == "" {
+		http.Error(w, "Text is required", http.StatusBadRequest)
+		return
+	}
+
+	threshold := scripts.DefaultThreshold
+	if v, err := strconv.ParseFloat(getEnv("SCRIPT_SIMILARITY_THRESHOLD", ""), 64); err == nil {
+		threshold = v
+	}
+	window := scripts.DefaultWindow
+	if v, err := time.ParseDuration(getEnv("SCRIPT_REPEAT_WINDOW", "")); err == nil {
+		window = v
+	}
+
+	result := map[string]interface{}{
+		"duplicate": false,
+		"threshold": threshold,
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	// 埋め込みが取れない場合はチェックせずに通す
+	embedding, err := getEmbedding(req.Text)
+	if err != nil {
+		log.Printf("Skipping repetition check: %v", err)
+		result["skipped"] = true
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	matches, err := scriptStore.Similar(scheduleChannel(r), embedding, time.Now().Add(-window), 3)
+	if err != nil {
+		log.Printf("Failed to search similar scripts: %v", err)
+		http.Error(w, "Failed to search scripts", http.StatusInternalServerError)
+		return
+	}
+	matches = scripts.ComparePending(matches, embedding, req.Pending)
+	result["embedding"] = embedding
+
+	if m, ok := scripts.Duplicate(matches, threshold); ok {
+		log.Printf("Script repeats a recent script (similarity %.3f): %s", m.Similarity, m.Text)
+		result["duplicate"] = true
+		result["match"] = m
+	}
+	if len(matches) > 0 {
+		result["similarity"] = matches[0].Similarity
+	}
+	json.NewEncoder(w).Encode(result)
 }
 
 // handleSubtitle 字幕データを配信
 func handleSubtitle(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Text string `json:"text"`
-		Type string `json:"type,omitempty"`
+		Text        string `json:"text"`
+		Type        string `json:"type,omitempty"`
+		Speaker     string `json:"speaker,omitempty"`      // persona.id（掛け合いでの話者）
+		SpeakerName string `json:"speaker_name,omitempty"` // 字幕に表示する話者名
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -937,9 +2089,11 @@ func handleSubtitle(w http.ResponseWriter, r *http.Request) {
 
 	// Broadcast Hubに字幕を配信
 	broadcastHub.Broadcast("subtitle", map[string]interface{}{
-		"text":      req.Text,
-		"type":      req.Type,
-		"timestamp": time.Now().Format(time.RFC3339),
+		"text":         req.Text,
+		"type":         req.Type,
+		"speaker":      req.Speaker,
+		"speaker_name": req.SpeakerName,
+		"timestamp":    time.Now().Format(time.RFC3339),
 	})
 
 	w.Header().Set("Content-Type", "application/json")
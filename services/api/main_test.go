@@ -1,7 +1,23 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/radio24/api/pkg/broadcast"
+	"github.com/radio24/api/pkg/screener"
+	"github.com/radio24/api/pkg/submissions"
+	"github.com/radio24/pkg/callerlink"
+	"github.com/radio24/pkg/dialogue"
+	"github.com/radio24/pkg/llm"
 )
 
 func TestMain(t *testing.T) {
@@ -9,3 +25,194 @@ func TestMain(t *testing.T) {
 	// This is a placeholder test that will pass
 	t.Log("Main package test passed")
 }
+
+func TestHandleSubmissionsFeatured(t *testing.T) {
+	// UUID でない id だけならDBに問い合わせない
+	submissionStore = submissions.NewStore(nil)
+
+	rec := httptest.NewRecorder()
+	handleSubmissionsFeatured(rec, httptest.NewRequest(http.MethodPost, "/v1/submissions/featured", strings.NewReader(`{"ids":[]}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status without ids = %d, want 400", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handleSubmissionsFeatured(rec, httptest.NewRequest(http.MethodPost, "/v1/submissions/featured", strings.NewReader(`{"ids":["1"],"topic":"天気"}`)))
+	var result struct {
+		Featured int `json:"featured"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil || rec.Code != http.StatusOK || result.Featured != 0 {
+		t.Errorf("status = %d, featured = %d, want 200 with none featured", rec.Code, result.Featured)
+	}
+}
+
+func TestDialogueFallsBackToHTTPWhenStreamStalls(t *testing.T) {
+	// 音声の処理が詰まったHost
+	release := make(chan struct{})
+	posted := make(chan dialogue.Transition, 1)
+	mux := http.NewServeMux()
+	mux.Handle(callerlink.Path, callerlink.NewServer(func(callerlink.Frame) error {
+		<-release
+		return nil
+	}))
+	mux.HandleFunc("/dialogue/events", func(w http.ResponseWriter, r *http.Request) {
+		var tr dialogue.Transition
+		json.NewDecoder(r.Body).Decode(&tr)
+		posted <- tr
+	})
+	host := httptest.NewServer(mux)
+	defer host.Close()
+	defer close(release)
+	t.Setenv("HOST_BASE", host.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	callerStream = callerlink.NewClient(callerlink.URL(host.URL), 1)
+	go callerStream.Run(ctx)
+	for !callerStream.Connected() {
+		time.Sleep(5 * time.Millisecond)
+	}
+	callerStream.Send(ctx, callerlink.Frame{Kind: callerlink.KindAudio, Audio: []byte{0, 0}})
+
+	sendDialogueToHost(dialogue.Transition{ID: "req-1", Event: dialogue.EventWrapUp, To: dialogue.WrappingUp})
+	select {
+	case tr := <-posted:
+		if tr.ID != "req-1" || tr.Event != dialogue.EventWrapUp {
+			t.Errorf("posted = %+v", tr)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("wrap up was dropped while the stream was stalled")
+	}
+}
+
+func TestHostControlAppliesHostTransitions(t *testing.T) {
+	broadcastHub = broadcast.NewHub()
+	screenings = screener.New(llm.NewFakeProvider(llm.NewFake()).Chat)
+	dialogues = dialogue.NewMachine(0)
+	dialogues.OwnsRequests = true
+	dialogues.Request("req-1", "client_1", "")
+
+	accept := dialogue.Transition{ID: "req-1", ClientID: "client_1", Event: dialogue.EventAccept, From: "", To: dialogue.Connecting}
+	if status, err := handleHostControl(callerlink.Frame{Kind: callerlink.KindControl, Dialogue: &accept}); status != http.StatusOK {
+		t.Fatalf("accept = %d, %v", status, err)
+	}
+	// ack が届く前にHTTPでも送られた同じ遷移
+	if status, err := handleHostControl(callerlink.Frame{Kind: callerlink.KindControl, Dialogue: &accept}); status != http.StatusOK {
+		t.Errorf("duplicate accept = %d, %v, want accepted", status, err)
+	}
+	if s, _ := dialogues.Get("req-1"); s.State != dialogue.Connecting {
+		t.Errorf("state = %s, want connecting", s.State)
+	}
+
+	// 待合室にいない通話は受け付けない
+	unknown := dialogue.Transition{ID: "req-2", Event: dialogue.EventAccept, To: dialogue.Connecting}
+	if status, _ := handleHostControl(callerlink.Frame{Kind: callerlink.KindControl, Dialogue: &unknown}); status != http.StatusConflict {
+		t.Errorf("accept for unknown request = %d, want 409", status)
+	}
+}
+
+func TestNotifyHostDialogueNeverBlocks(t *testing.T) {
+	// Hostに送れない間（forwardDialoguesToHost が止まっている間）も Fire の呼び出し側を待たせない
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 500; i++ {
+			notifyHostDialogue(dialogue.Transition{ID: fmt.Sprintf("req-%d", i), Event: dialogue.EventRequest})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("notifyHostDialogue blocked")
+	}
+
+	queued := takeHostDialogues()
+	if len(queued) != 500 || queued[0].ID != "req-0" || queued[499].ID != "req-499" {
+		t.Errorf("queued %d transitions, want all 500 in order", len(queued))
+	}
+}
+
+func TestHostLostEndsLiveDialogue(t *testing.T) {
+	broadcastHub = broadcast.NewHub()
+	screenings = screener.New(llm.NewFakeProvider(llm.NewFake()).Chat)
+	dialogues = dialogue.NewMachine(0)
+	dialogues.Accept("req-1", "client_1")
+	hostLostSince = time.Time{}
+
+	// 一時的に切れただけなら終えない
+	start := time.Now()
+	watchHost(false, start)
+	watchHost(true, start.Add(20*time.Second))
+	watchHost(false, start.Add(40*time.Second))
+	if _, ok := dialogues.Live(); !ok {
+		t.Fatal("dialogue ended after a brief disconnect")
+	}
+
+	watchHost(false, start.Add(40*time.Second+hostLostTimeout))
+	if _, ok := dialogues.Live(); ok {
+		t.Fatal("dialogue still live after the host was lost")
+	}
+	if s, _ := dialogues.Get("req-1"); s.Reason != dialogue.ReasonHostLost {
+		t.Errorf("reason = %q, want %q", s.Reason, dialogue.ReasonHostLost)
+	}
+}
+
+func TestJudgeCallerKeepsCallerWaitingWhenScreeningFails(t *testing.T) {
+	fake := llm.NewFake()
+	fake.Reply = func(llm.ChatRequest) (string, error) {
+		return "", errors.New("upstream unavailable")
+	}
+	broadcastHub = broadcast.NewHub()
+	screenings = screener.New(llm.NewFakeProvider(fake).Chat)
+	dialogues = dialogue.NewMachine(0)
+	dialogues.Request("req-1", "client_1", "宣伝したい")
+
+	ctx := context.Background()
+	screenings.Start(ctx, "req-1", "宣伝したい")
+	if judgeCaller(ctx, "req-1") {
+		t.Fatal("judgeCaller() screened out the caller without a verdict")
+	}
+	s, _ := dialogues.Get("req-1")
+	if s.State != dialogue.Requested || s.Cleared {
+		t.Errorf("session = %+v, want still waiting and not cleared", s)
+	}
+	if _, ok := dialogues.Next(); ok {
+		t.Error("caller handed to the host without being screened")
+	}
+}
+
+func TestScreenerDoesNotBlockPTTReads(t *testing.T) {
+	release := make(chan struct{})
+	fake := llm.NewFake()
+	fake.Reply = func(llm.ChatRequest) (string, error) {
+		<-release
+		return `{}`, nil
+	}
+	broadcastHub = broadcast.NewHub()
+	screenings = screener.New(llm.NewFakeProvider(fake).Chat)
+	dialogues = dialogue.NewMachine(0)
+	clientConnections = make(map[string]*websocket.Conn)
+	srv := httptest.NewServer(http.HandlerFunc(handlePTTWebSocket))
+	defer srv.Close()
+	defer close(release)
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() = %v", err)
+	}
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	// スクリーナーがLLMを待っている間も、同じ接続のメッセージは読まれる
+	ws.WriteJSON(map[string]string{"type": "dialogue_request", "topic": "週末のライブ"})
+	ws.WriteJSON(map[string]string{"type": "dialogue_end"})
+	for _, want := range []string{"dialogue_queued", "dialogue_end_denied"} {
+		var msg map[string]interface{}
+		if err := ws.ReadJSON(&msg); err != nil {
+			t.Fatalf("waiting for %s: %v", want, err)
+		}
+		if msg["type"] != want {
+			t.Fatalf("got %v, want %s", msg["type"], want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package clock
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// NextTopOfHour t より後の最初の正時
+func NextTopOfHour(t time.Time) time.Time {
+	hour := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	return hour.Add(time.Hour)
+}
+
+// RunTopOfHour 毎正時に fn を呼び出す（ctx がキャンセルされるまでブロック）
+func RunTopOfHour(ctx context.Context, fn func(t time.Time)) {
+	for {
+		next := NextTopOfHour(time.Now())
+		log.Printf("Next top of hour: %s", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			fn(next)
+		}
+	}
+}
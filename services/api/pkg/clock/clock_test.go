@@ -0,0 +1,21 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextTopOfHour(t *testing.T) {
+	cases := []struct {
+		in, want time.Time
+	}{
+		{time.Date(2026, 10, 18, 14, 59, 59, 0, time.UTC), time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC), time.Date(2026, 10, 18, 16, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 10, 18, 23, 30, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		if got := NextTopOfHour(c.in); !got.Equal(c.want) {
+			t.Errorf("NextTopOfHour(%s) = %s, want %s", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Kind 記憶の種類
+type Kind string
+
+const (
+	KindScript  Kind = "script"  // 放送した台本
+	KindCaller  Kind = "caller"  // リスナーの投稿・通話
+	KindSummary Kind = "summary" // それ以前の時間帯の要約
+)
+
+// Valid 既知の種類か
+func (k Kind) Valid() bool {
+	switch k {
+	case KindScript, KindCaller, KindSummary:
+		return true
+	}
+	return false
+}
+
+// Entry 放送の記憶の1件
+type Entry struct {
+	ID        string    `json:"id"`
+	Kind      Kind      `json:"kind"`
+	Topic     string    `json:"topic,omitempty"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+	// 要約の場合の対象期間
+	PeriodStart *time.Time `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time `json:"period_end,omitempty"`
+}
+
+// Context プロンプトに含める記憶（それぞれ古い順）
+type Context struct {
+	Summaries []Entry `json:"summaries"`
+	Callers   []Entry `json:"callers"`
+	Scripts   []Entry `json:"scripts"`
+	Tokens    int     `json:"tokens"`
+}
+
+// 予算の配分（直近の台本を最優先し、残りを要約に回す）
+const (
+	scriptShare = 0.5
+	callerShare = 0.2
+)
+
+// EstimateTokens テキストのおおよそのトークン数
+//
+// 日本語は1文字1トークン程度、ASCIIは4文字で1トークン程度として見積もる。
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return other + (ascii+3)/4
+}
+
+// Build 新しい順に並んだ記憶から、予算内に収まる分を選ぶ
+//
+// 台本・通話はそれぞれの配分まで新しいものから詰め、残った予算を要約に使う。
+func Build(summaries, callers, scripts []Entry, budget int) Context {
+	c := Context{}
+	c.Scripts, c.Tokens = fill(scripts, int(float64(budget)*scriptShare))
+	var used int
+	c.Callers, used = fill(callers, int(float64(budget)*callerShare))
+	c.Tokens += used
+	c.Summaries, used = fill(summaries, budget-c.Tokens)
+	c.Tokens += used
+	return c
+}
+
+// fill 新しい順の entries から limit に収まるだけ取り、古い順にして返す
+func fill(entries []Entry, limit int) ([]Entry, int) {
+	out := []Entry{}
+	used := 0
+	for _, e := range entries {
+		cost := EstimateTokens(e.Text)
+		if used+cost > limit {
+			break
+		}
+		used += cost
+		out = append(out, e)
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, used
+}
+
+// Prompt 台本生成のシステムプロンプトに加える文面（記憶がなければ空）
+func (c Context) Prompt() string {
+	if len(c.Summaries) == 0 && len(c.Callers) == 0 && len(c.Scripts) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("これまでの放送の記憶（同じ話や局名の紹介を繰り返さず、必要なら前の話題に触れて自然に続けてください）:\n")
+	for _, e := range c.Summaries {
+		fmt.Fprintf(&b, "- [要約 %s] %s\n", period(e), e.Text)
+	}
+	for _, e := range c.Callers {
+		fmt.Fprintf(&b, "- [リスナー %s] %s\n", e.CreatedAt.Format("15:04"), e.Text)
+	}
+	for _, e := range c.Scripts {
+		fmt.Fprintf(&b, "- [%s %s] %s\n", e.CreatedAt.Format("15:04"), e.Topic, e.Text)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func period(e Entry) string {
+	if e.PeriodStart == nil || e.PeriodEnd == nil {
+		return e.CreatedAt.Format("15:04")
+	}
+	return e.PeriodStart.Format("15:04") + "-" + e.PeriodEnd.Format("15:04")
+}
+
+// Digest 要約APIが使えない時の簡易要約（各台本の冒頭を maxRunes まで連結）
+func Digest(entries []Entry, maxRunes int) string {
+	parts := make([]string, 0, len(entries))
+	for _, e := range entries {
+		text := e.Text
+		if i := strings.IndexAny(text, "。！？\n"); i >= 0 {
+			text = text[:i]
+		}
+		if e.Topic != "" {
+			text = e.Topic + ": " + text
+		}
+		parts = append(parts, text)
+	}
+	digest := strings.Join(parts, " / ")
+	if utf8.RuneCountInString(digest) > maxRunes {
+		digest = string([]rune(digest)[:maxRunes]) + "…"
+	}
+	return digest
+}
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func entries(kind Kind, texts ...string) []Entry {
+	base := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	out := make([]Entry, len(texts))
+	for i, text := range texts {
+		// 新しい順に並べる
+		out[i] = Entry{Kind: kind, Text: text, CreatedAt: base.Add(-time.Duration(i) * time.Minute)}
+	}
+	return out
+}
+
+func TestEstimateTokens(t *testing.T) {
+	cases := map[string]int{
+		"":               0,
+		"こんにちは":          5,
+		"hello world!":   3,
+		"ラジオ24 Radio-24": 6,
+	}
+	for text, want := range cases {
+		if got := EstimateTokens(text); got != want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestBuildRespectsBudget(t *testing.T) {
+	scripts := entries(KindScript, "三つ目の台本です", "二つ目の台本です", "一つ目の台本です")
+	callers := entries(KindCaller, "リスナーの投稿")
+	summaries := entries(KindSummary, "八時台はニュースを中心に話した", "七時台は天気の話をした")
+
+	c := Build(summaries, callers, scripts, 40)
+
+	// 台本は配分（20トークン）まで新しいものから、古い順で返す
+	if len(c.Scripts) != 2 || c.Scripts[0].Text != "二つ目の台本です" || c.Scripts[1].Text != "三つ目の台本です" {
+		t.Errorf("scripts = %+v", c.Scripts)
+	}
+	if len(c.Callers) != 1 {
+		t.Errorf("callers = %+v", c.Callers)
+	}
+	// 残りの予算で入るのは新しい要約1件だけ
+	if len(c.Summaries) != 1 || c.Summaries[0].Text != "八時台はニュースを中心に話した" {
+		t.Errorf("summaries = %+v, want only the newest", c.Summaries)
+	}
+	if c.Tokens > 40 {
+		t.Errorf("tokens = %d, want <= 40", c.Tokens)
+	}
+
+	c = Build(summaries, nil, nil, 40)
+	if len(c.Summaries) != 2 || c.Summaries[0].Text != "七時台は天気の話をした" {
+		t.Errorf("summaries = %+v, want both, oldest first", c.Summaries)
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	if (Context{}).Prompt() != "" {
+		t.Error("empty context should produce no prompt")
+	}
+
+	start := time.Date(2026, 10, 18, 7, 0, 0, 0, time.UTC)
+	end := start.Add(55 * time.Minute)
+	c := Context{
+		Summaries: []Entry{{Kind: KindSummary, Text: "天気の話をした", PeriodStart: &start, PeriodEnd: &end}},
+		Scripts:   []Entry{{Kind: KindScript, Topic: "音楽", Text: "今日の一曲", CreatedAt: start.Add(2 * time.Hour)}},
+	}
+	prompt := c.Prompt()
+	for _, want := range []string{"[要約 07:00-07:55] 天気の話をした", "[09:00 音楽] 今日の一曲"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt %q does not contain %q", prompt, want)
+		}
+	}
+}
+
+func TestDigest(t *testing.T) {
+	got := Digest([]Entry{
+		{Topic: "天気", Text: "今日は晴れです。午後から雲が出ます。"},
+		{Topic: "音楽", Text: "秋の夜長にぴったりの曲！"},
+	}, 100)
+	if want := "天気: 今日は晴れです / 音楽: 秋の夜長にぴったりの曲"; got != want {
+		t.Errorf("Digest = %q, want %q", got, want)
+	}
+
+	if got := Digest([]Entry{{Text: "あいうえおかきくけこ"}}, 5); got != "あいうえお…" {
+		t.Errorf("Digest = %q", got)
+	}
+}
@@ -0,0 +1,175 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNotFound チャンネルが存在しない
+	ErrNotFound = errors.New("channel not found")
+	// ErrInvalid 種類または本文が不正
+	ErrInvalid = errors.New("invalid memory entry")
+)
+
+// recentLimit 予算に詰める前に種類ごとに取得する件数
+const recentLimit = 50
+
+// compactLimit 1回の要約にまとめる台本の最大件数（残りは次の要約で古い順にまとめる）
+const compactLimit = recentLimit * 4
+
+// Summarizer 台本をまとめて要約する
+type Summarizer func(ctx context.Context, entries []Entry) (string, error)
+
+// Store onair_memory テーブルへのアクセス
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Add 記憶を1件追加
+func (s *Store) Add(channel string, e Entry) (Entry, error) {
+	if !e.Kind.Valid() || e.Text == "" {
+		return Entry{}, fmt.Errorf("%w: kind=%q", ErrInvalid, e.Kind)
+	}
+
+	err := s.db.QueryRow(`
+		INSERT INTO onair_memory (channel_id, kind, topic, text, period_start, period_end)
+		SELECT c.id, $2, NULLIF($3, ''), $4, $5, $6
+		FROM channel c WHERE c.name = $1
+		RETURNING id, created_at`,
+		channel, e.Kind, e.Topic, e.Text, e.PeriodStart, e.PeriodEnd).Scan(&e.ID, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return Entry{}, ErrNotFound
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to add memory: %w", err)
+	}
+	return e, nil
+}
+
+// Recent 指定した種類の記憶を新しい順に取得
+func (s *Store) Recent(channel string, kind Kind, limit int) ([]Entry, error) {
+	return s.query(`
+		SELECT m.id, m.kind, COALESCE(m.topic, ''), m.text, m.created_at, m.period_start, m.period_end
+		FROM onair_memory m
+		JOIN channel c ON c.id = m.channel_id
+		WHERE c.name = $1 AND m.kind = $2
+		ORDER BY m.created_at DESC
+		LIMIT $3`, channel, kind, limit)
+}
+
+// scriptsBefore before より前の台本を古い順に limit 件まで取得
+func (s *Store) scriptsBefore(channel string, before time.Time, limit int) ([]Entry, error) {
+	return s.query(`
+		SELECT m.id, m.kind, COALESCE(m.topic, ''), m.text, m.created_at, m.period_start, m.period_end
+		FROM onair_memory m
+		JOIN channel c ON c.id = m.channel_id
+		WHERE c.name = $1 AND m.kind = $2 AND m.created_at < $3
+		ORDER BY m.created_at
+		LIMIT $4`, channel, KindScript, before, limit)
+}
+
+func (s *Store) query(query string, args ...any) ([]Entry, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memory: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var e Entry
+		var start, end sql.NullTime
+		if err := rows.Scan(&e.ID, &e.Kind, &e.Topic, &e.Text, &e.CreatedAt, &start, &end); err != nil {
+			return nil, err
+		}
+		if start.Valid {
+			e.PeriodStart = &start.Time
+		}
+		if end.Valid {
+			e.PeriodEnd = &end.Time
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Context トークン予算内に収めたプロンプト用の記憶
+func (s *Store) Context(channel string, budget int) (Context, error) {
+	summaries, err := s.Recent(channel, KindSummary, recentLimit)
+	if err != nil {
+		return Context{}, err
+	}
+	callers, err := s.Recent(channel, KindCaller, recentLimit)
+	if err != nil {
+		return Context{}, err
+	}
+	scripts, err := s.Recent(channel, KindScript, recentLimit)
+	if err != nil {
+		return Context{}, err
+	}
+	return Build(summaries, callers, scripts, budget), nil
+}
+
+// Compact before より前の台本を古い順に compactLimit 件まで1件の要約にまとめて置き換える
+//
+// 要約に使った台本だけを消すので、残った分は次の Compact でまとめられる。
+// 対象がなければ false を返す。要約に失敗した場合は台本を残したままエラーを返す。
+func (s *Store) Compact(ctx context.Context, channel string, before time.Time, summarize Summarizer) (Entry, bool, error) {
+	scripts, err := s.scriptsBefore(channel, before, compactLimit)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	if len(scripts) == 0 {
+		return Entry{}, false, nil
+	}
+
+	text, err := summarize(ctx, scripts)
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("failed to summarize memory: %w", err)
+	}
+
+	start, end := scripts[0].CreatedAt, scripts[len(scripts)-1].CreatedAt
+	summary := Entry{Kind: KindSummary, Text: text, PeriodStart: &start, PeriodEnd: &end}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO onair_memory (channel_id, kind, text, period_start, period_end)
+		SELECT c.id, $2, $3, $4, $5
+		FROM channel c WHERE c.name = $1
+		RETURNING id, created_at`,
+		channel, summary.Kind, summary.Text, start, end).Scan(&summary.ID, &summary.CreatedAt)
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("failed to add summary: %w", err)
+	}
+
+	ids := make([]string, len(scripts))
+	for i, e := range scripts {
+		ids[i] = e.ID
+	}
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM onair_memory m
+		USING channel c
+		WHERE c.id = m.channel_id AND c.name = $1 AND m.kind = $2 AND m.id = ANY($3::uuid[])`,
+		channel, KindScript, ids)
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("failed to delete summarized scripts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Entry{}, false, fmt.Errorf("failed to commit summary: %w", err)
+	}
+	return summary, true, nil
+}
@@ -0,0 +1,144 @@
+package persona
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var (
+	// ErrNotFound ペルソナが存在しない
+	ErrNotFound = errors.New("persona not found")
+	// ErrInvalid 値が不正
+	ErrInvalid = errors.New("invalid persona")
+)
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Persona DJのキャラクター設定（Host Agentが台本・対話・音声に使う）
+type Persona struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	Style           string    `json:"style"`
+	Catchphrases    []string  `json:"catchphrases"`
+	TTSVoice        string    `json:"tts_voice"`
+	RealtimeVoice   string    `json:"realtime_voice"`
+	ForbiddenTopics []string  `json:"forbidden_topics"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Validate 値をチェック（nil のリストは空にそろえる）
+func (p *Persona) Validate() error {
+	if !idPattern.MatchString(p.ID) {
+		return fmt.Errorf("%w: id must be lowercase letters, digits, '-' or '_'", ErrInvalid)
+	}
+	if p.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if p.Catchphrases == nil {
+		p.Catchphrases = []string{}
+	}
+	if p.ForbiddenTopics == nil {
+		p.ForbiddenTopics = []string{}
+	}
+	return nil
+}
+
+// Store persona テーブルへのアクセス
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+const selectPersona = `
+	SELECT id, name, COALESCE(style, ''), catchphrases, COALESCE(tts_voice, ''), COALESCE(realtime_voice, ''), forbidden_topics, updated_at
+	FROM persona`
+
+func (s *Store) List() ([]Persona, error) {
+	rows, err := s.db.Query(selectPersona + ` ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list personas: %w", err)
+	}
+	defer rows.Close()
+
+	personas := []Persona{}
+	for rows.Next() {
+		p, err := scanPersona(rows)
+		if err != nil {
+			return nil, err
+		}
+		personas = append(personas, p)
+	}
+	return personas, rows.Err()
+}
+
+func (s *Store) Get(id string) (Persona, error) {
+	p, err := scanPersona(s.db.QueryRow(selectPersona+` WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return Persona{}, ErrNotFound
+	}
+	return p, err
+}
+
+// Put 追加または置き換え
+func (s *Store) Put(p Persona) (Persona, error) {
+	if err := p.Validate(); err != nil {
+		return Persona{}, err
+	}
+	catchphrases, _ := json.Marshal(p.Catchphrases)
+	forbidden, _ := json.Marshal(p.ForbiddenTopics)
+
+	err := s.db.QueryRow(`
+		INSERT INTO persona (id, name, style, catchphrases, tts_voice, realtime_voice, forbidden_topics, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4::jsonb, NULLIF($5, ''), NULLIF($6, ''), $7::jsonb, now())
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			style = EXCLUDED.style,
+			catchphrases = EXCLUDED.catchphrases,
+			tts_voice = EXCLUDED.tts_voice,
+			realtime_voice = EXCLUDED.realtime_voice,
+			forbidden_topics = EXCLUDED.forbidden_topics,
+			updated_at = now()
+		RETURNING updated_at`,
+		p.ID, p.Name, p.Style, string(catchphrases), p.TTSVoice, p.RealtimeVoice, string(forbidden)).Scan(&p.UpdatedAt)
+	if err != nil {
+		return Persona{}, fmt.Errorf("failed to save persona: %w", err)
+	}
+	return p, nil
+}
+
+func (s *Store) Delete(id string) error {
+	res, err := s.db.Exec(`DELETE FROM persona WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete persona: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPersona(row scanner) (Persona, error) {
+	var p Persona
+	var catchphrases, forbidden []byte
+	if err := row.Scan(&p.ID, &p.Name, &p.Style, &catchphrases, &p.TTSVoice, &p.RealtimeVoice, &forbidden, &p.UpdatedAt); err != nil {
+		return Persona{}, err
+	}
+	if err := json.Unmarshal(catchphrases, &p.Catchphrases); err != nil {
+		return Persona{}, fmt.Errorf("invalid catchphrases for persona %s: %w", p.ID, err)
+	}
+	if err := json.Unmarshal(forbidden, &p.ForbiddenTopics); err != nil {
+		return Persona{}, fmt.Errorf("invalid forbidden_topics for persona %s: %w", p.ID, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,27 @@
+package persona
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	p := Persona{ID: "aoi", Name: "あおい"}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if p.Catchphrases == nil || p.ForbiddenTopics == nil {
+		t.Error("nil lists should be normalized to empty")
+	}
+
+	for _, bad := range []Persona{
+		{ID: "", Name: "あおい"},
+		{ID: "Aoi", Name: "あおい"},
+		{ID: "aoi night", Name: "あおい"},
+		{ID: "aoi"},
+	} {
+		if err := bad.Validate(); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Validate(%+v) = %v, want ErrInvalid", bad, err)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// Blocks 時間割で使える枠の種類
+var Blocks = []string{"OP", "NEWS", "QANDA", "MUSIC", "TOPIC_A", "JINGLE"}
+
+const dateLayout = "2006-01-02"
+
+// Entry 時間割の1エントリ
+//
+// DayOfWeek と Date がどちらも nil なら毎日の基本枠、DayOfWeek は曜日ルール、
+// Date は特定日の上書き（祝日・特番）。hour から hours 時間を占める。
+type Entry struct {
+	ID        string  `json:"id"`
+	Hour      int     `json:"hour"`
+	Hours     int     `json:"hours"`
+	Block     string  `json:"block"`
+	Title     string  `json:"title,omitempty"`
+	Prompt    string  `json:"prompt"`
+	Persona   string  `json:"persona,omitempty"`     // 枠を担当するDJ（persona.id、空なら既定）
+	CoHost    string  `json:"cohost,omitempty"`      // 掛け合いの相手（persona.id、空なら1人で進行）
+	DayOfWeek *int    `json:"day_of_week,omitempty"` // 0=日曜 .. 6=土曜
+	Date      *string `json:"date,omitempty"`        // YYYY-MM-DD
+}
+
+// Validate 値の範囲をチェック（Hours が 0 の場合は 1 として扱う）
+func (e *Entry) Validate() error {
+	if e.Hours == 0 {
+		e.Hours = 1
+	}
+	if e.Hour < 0 || e.Hour > 23 {
+		return fmt.Errorf("hour must be between 0 and 23")
+	}
+	if e.Hours < 1 || e.Hour+e.Hours > 24 {
+		return fmt.Errorf("hours must be at least 1 and end by 24:00")
+	}
+	if !validBlock(e.Block) {
+		return fmt.Errorf("invalid block: %s", e.Block)
+	}
+	if e.CoHost != "" && e.CoHost == e.Persona {
+		return fmt.Errorf("cohost must differ from persona")
+	}
+	if e.DayOfWeek != nil && e.Date != nil {
+		return fmt.Errorf("day_of_week and date cannot both be set")
+	}
+	if e.DayOfWeek != nil && (*e.DayOfWeek < 0 || *e.DayOfWeek > 6) {
+		return fmt.Errorf("day_of_week must be between 0 (Sunday) and 6 (Saturday)")
+	}
+	if e.Date != nil {
+		if _, err := time.Parse(dateLayout, *e.Date); err != nil {
+			return fmt.Errorf("date must be YYYY-MM-DD")
+		}
+	}
+	return nil
+}
+
+func validBlock(block string) bool {
+	for _, b := range Blocks {
+		if b == block {
+			return true
+		}
+	}
+	return false
+}
+
+// rank 優先度（大きいほど優先）
+func (e Entry) rank() int {
+	switch {
+	case e.Date != nil:
+		return 2
+	case e.DayOfWeek != nil:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// sameScope 同じ日付・曜日・毎日の区分に属するか
+func (e Entry) sameScope(o Entry) bool {
+	switch {
+	case e.Date != nil || o.Date != nil:
+		return e.Date != nil && o.Date != nil && *e.Date == *o.Date
+	case e.DayOfWeek != nil || o.DayOfWeek != nil:
+		return e.DayOfWeek != nil && o.DayOfWeek != nil && *e.DayOfWeek == *o.DayOfWeek
+	default:
+		return true
+	}
+}
+
+// Covers t の日・時にこのエントリが該当するか
+func (e Entry) Covers(t time.Time) bool {
+	if t.Hour() < e.Hour || t.Hour() >= e.end() {
+		return false
+	}
+	switch {
+	case e.Date != nil:
+		return t.Format(dateLayout) == *e.Date
+	case e.DayOfWeek != nil:
+		return int(t.Weekday()) == *e.DayOfWeek
+	default:
+		return true
+	}
+}
+
+// Overlaps 同じ区分で時間帯が重なるか
+func Overlaps(a, b Entry) bool {
+	if !a.sameScope(b) {
+		return false
+	}
+	return a.Hour < b.end() && b.Hour < a.end()
+}
+
+// end 終了時（Hours 未指定は1時間）
+func (e Entry) end() int {
+	if e.Hours < 1 {
+		return e.Hour + 1
+	}
+	return e.Hour + e.Hours
+}
+
+// FindOverlap entries の中で e と重なるもの（ID が同じものは除く）
+func FindOverlap(entries []Entry, e Entry) (Entry, bool) {
+	for _, o := range entries {
+		if o.ID != "" && o.ID == e.ID {
+			continue
+		}
+		if Overlaps(o, e) {
+			return o, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Resolve t に放送する枠を決める（日付指定 > 曜日 > 毎日）
+func Resolve(entries []Entry, t time.Time) (Entry, bool) {
+	var best Entry
+	found := false
+	for _, e := range entries {
+		if !e.Covers(t) {
+			continue
+		}
+		if !found || e.rank() > best.rank() {
+			best = e
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Program 番組表の1枠（連続する同じエントリをまとめたもの）
+type Program struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Entry Entry     `json:"entry"`
+}
+
+// Day 番組表の1日分
+type Day struct {
+	Date     string    `json:"date"`
+	Weekday  int       `json:"weekday"`
+	Programs []Program `json:"programs"`
+}
+
+// Week start の日から7日分の番組表を作る
+func Week(entries []Entry, start time.Time) []Day {
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+
+	days := make([]Day, 0, 7)
+	for d := 0; d < 7; d++ {
+		date := day.AddDate(0, 0, d)
+		out := Day{Date: date.Format(dateLayout), Weekday: int(date.Weekday()), Programs: []Program{}}
+
+		for h := 0; h < 24; h++ {
+			at := time.Date(date.Year(), date.Month(), date.Day(), h, 0, 0, 0, date.Location())
+			e, ok := Resolve(entries, at)
+			if !ok {
+				continue
+			}
+			n := len(out.Programs)
+			if n > 0 && out.Programs[n-1].Entry.ID == e.ID && out.Programs[n-1].End.Equal(at) {
+				out.Programs[n-1].End = at.Add(time.Hour)
+				continue
+			}
+			out.Programs = append(out.Programs, Program{Start: at, End: at.Add(time.Hour), Entry: e})
+		}
+		days = append(days, out)
+	}
+	return days
+}
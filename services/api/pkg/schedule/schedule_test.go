@@ -0,0 +1,118 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func intPtr(v int) *int          { return &v }
+func strPtr(v string) *string    { return &v }
+func at(day, hour int) time.Time { return time.Date(2026, 10, day, hour, 30, 0, 0, time.UTC) }
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name  string
+		entry Entry
+		ok    bool
+	}{
+		{"daily", Entry{Hour: 6, Block: "NEWS"}, true},
+		{"runs past midnight", Entry{Hour: 22, Hours: 3, Block: "MUSIC"}, false},
+		{"unknown block", Entry{Hour: 6, Block: "SPORTS"}, false},
+		{"bad weekday", Entry{Hour: 6, Block: "NEWS", DayOfWeek: intPtr(7)}, false},
+		{"bad date", Entry{Hour: 6, Block: "NEWS", Date: strPtr("10/18")}, false},
+		{"weekday and date", Entry{Hour: 6, Block: "NEWS", DayOfWeek: intPtr(1), Date: strPtr("2026-10-18")}, false},
+		{"cohost", Entry{Hour: 6, Block: "NEWS", Persona: "aoi", CoHost: "ken"}, true},
+		{"cohost same as persona", Entry{Hour: 6, Block: "NEWS", Persona: "aoi", CoHost: "aoi"}, false},
+	}
+	for _, c := range cases {
+		err := c.entry.Validate()
+		if (err == nil) != c.ok {
+			t.Errorf("%s: err = %v, want ok=%v", c.name, err, c.ok)
+		}
+	}
+}
+
+func TestOverlapsOnlyWithinSameScope(t *testing.T) {
+	daily := Entry{ID: "a", Hour: 9, Hours: 3, Block: "TOPIC_A"}
+
+	if !Overlaps(daily, Entry{Hour: 11, Hours: 1, Block: "NEWS"}) {
+		t.Error("daily entries 9-12 and 11-12 should overlap")
+	}
+	if Overlaps(daily, Entry{Hour: 12, Hours: 1, Block: "NEWS"}) {
+		t.Error("adjacent entries should not overlap")
+	}
+	if Overlaps(daily, Entry{Hour: 10, Block: "NEWS", DayOfWeek: intPtr(0)}) {
+		t.Error("weekday rule should not conflict with daily entry")
+	}
+	if !Overlaps(Entry{Hour: 10, Block: "NEWS", Date: strPtr("2026-10-18")}, Entry{Hour: 10, Block: "MUSIC", Date: strPtr("2026-10-18")}) {
+		t.Error("same-date overrides should overlap")
+	}
+
+	if _, ok := FindOverlap([]Entry{daily}, Entry{ID: "a", Hour: 10, Block: "NEWS"}); ok {
+		t.Error("an entry should not overlap with itself when updated")
+	}
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	entries := []Entry{
+		{ID: "daily", Hour: 0, Hours: 24, Block: "MUSIC"},
+		{ID: "sunday", Hour: 9, Hours: 3, Block: "TOPIC_A", DayOfWeek: intPtr(0)},
+		{ID: "special", Hour: 10, Block: "QANDA", Date: strPtr("2026-10-18")},
+	}
+
+	cases := []struct {
+		at   time.Time
+		want string
+	}{
+		{at(17, 10), "daily"},   // 土曜
+		{at(18, 9), "sunday"},   // 日曜の曜日ルール
+		{at(18, 10), "special"}, // 日付指定が優先
+		{at(25, 10), "sunday"},  // 翌週の日曜
+	}
+	for _, c := range cases {
+		e, ok := Resolve(entries, c.at)
+		if !ok || e.ID != c.want {
+			t.Errorf("Resolve(%s) = %q, want %q", c.at.Format(time.RFC3339), e.ID, c.want)
+		}
+	}
+}
+
+func TestWeekMergesConsecutiveHours(t *testing.T) {
+	entries := []Entry{
+		{ID: "night", Hour: 0, Hours: 6, Block: "MUSIC"},
+		{ID: "morning", Hour: 6, Hours: 3, Block: "NEWS"},
+		{ID: "holiday", Hour: 6, Hours: 3, Block: "MUSIC", Date: strPtr("2026-10-19")},
+	}
+
+	days := Week(entries, at(18, 0))
+	if len(days) != 7 {
+		t.Fatalf("days = %d, want 7", len(days))
+	}
+
+	sunday := days[0]
+	if sunday.Date != "2026-10-18" || sunday.Weekday != 0 || len(sunday.Programs) != 2 {
+		t.Fatalf("sunday = %+v", sunday)
+	}
+	if p := sunday.Programs[0]; p.Entry.ID != "night" || p.End.Hour() != 6 {
+		t.Errorf("first program = %s until %s", p.Entry.ID, p.End.Format("15:04"))
+	}
+
+	if p := days[1].Programs[1]; p.Entry.ID != "holiday" {
+		t.Errorf("monday 6:00 = %s, want holiday override", p.Entry.ID)
+	}
+}
+
+func TestStoreRejectsMalformedID(t *testing.T) {
+	// UUID でない id はDBに問い合わせずに見つからない扱い
+	s := NewStore(nil)
+	if _, err := s.Get("Radio-24", "not-a-uuid"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Update("Radio-24", Entry{ID: "1", Hour: 7, Hours: 1, Block: "NEWS"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update() = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete("Radio-24", "1; DROP"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() = %v, want ErrNotFound", err)
+	}
+}
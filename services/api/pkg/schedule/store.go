@@ -0,0 +1,255 @@
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrNotFound エントリまたはチャンネルが存在しない
+	ErrNotFound = errors.New("schedule entry not found")
+	// ErrInvalid 値の範囲が不正
+	ErrInvalid = errors.New("invalid schedule entry")
+	// ErrOverlap 同じ区分の既存エントリと時間帯が重なる
+	ErrOverlap = errors.New("schedule entry overlaps an existing entry")
+)
+
+// OverlapError 重なった既存エントリ
+type OverlapError struct {
+	Existing Entry
+}
+
+func (e *OverlapError) Error() string {
+	return fmt.Sprintf("%v: %s %02d:00-%02d:00", ErrOverlap, e.Existing.Block, e.Existing.Hour, e.Existing.Hour+e.Existing.Hours)
+}
+
+func (e *OverlapError) Unwrap() error { return ErrOverlap }
+
+// maxTxAttempts 同時の書き込みと衝突した（直列化に失敗した）時にやり直す回数
+const maxTxAttempts = 3
+
+// idPattern エントリのID（UUID）
+var idPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Store schedule テーブルへのアクセス
+type Store struct {
+	db *sql.DB
+}
+
+// querier *sql.DB と *sql.Tx の共通部分
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+const selectEntry = `
+	SELECT s.id, s.hour, s.hours, s.block, COALESCE(s.title, ''), COALESCE(s.prompt, ''), COALESCE(s.persona, ''), COALESCE(s.cohost, ''), s.day_of_week, s.date
+	FROM schedule s
+	JOIN channel c ON c.id = s.channel_id`
+
+// List チャンネルの全エントリ
+func (s *Store) List(channel string) ([]Entry, error) {
+	return list(s.db, channel)
+}
+
+func list(q querier, channel string) ([]Entry, error) {
+	rows, err := q.Query(selectEntry+`
+		WHERE c.name = $1
+		ORDER BY s.date NULLS FIRST, s.day_of_week NULLS FIRST, s.hour`, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedule: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *Store) Get(channel, id string) (Entry, error) {
+	if !idPattern.MatchString(id) {
+		return Entry{}, ErrNotFound
+	}
+	row := s.db.QueryRow(selectEntry+` WHERE c.name = $1 AND s.id = $2`, channel, id)
+	e, err := scanEntry(row)
+	if err == sql.ErrNoRows {
+		return Entry{}, ErrNotFound
+	}
+	return e, err
+}
+
+// Create 検証と重複チェックの上で追加
+func (s *Store) Create(channel string, e Entry) (Entry, error) {
+	err := s.inTx(func(tx *sql.Tx) error {
+		if err := check(tx, channel, &e); err != nil {
+			return err
+		}
+		return create(tx, channel, &e)
+	})
+	if err != nil {
+		return Entry{}, err
+	}
+	return e, nil
+}
+
+func create(tx *sql.Tx, channel string, e *Entry) error {
+	err := tx.QueryRow(`
+		INSERT INTO schedule (channel_id, hour, hours, block, title, prompt, persona, cohost, day_of_week, date)
+		SELECT c.id, $2, $3, $4, NULLIF($5, ''), $6, NULLIF($7, ''), NULLIF($8, ''), $9, $10::date
+		FROM channel c WHERE c.name = $1
+		RETURNING id`,
+		channel, e.Hour, e.Hours, e.Block, e.Title, e.Prompt, e.Persona, e.CoHost, e.DayOfWeek, e.Date).Scan(&e.ID)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create schedule entry: %w", err)
+	}
+	return nil
+}
+
+// Update 検証と重複チェックの上で置き換え
+func (s *Store) Update(channel string, e Entry) (Entry, error) {
+	if !idPattern.MatchString(e.ID) {
+		return Entry{}, ErrNotFound
+	}
+	err := s.inTx(func(tx *sql.Tx) error {
+		if err := check(tx, channel, &e); err != nil {
+			return err
+		}
+		return update(tx, channel, e)
+	})
+	if err != nil {
+		return Entry{}, err
+	}
+	return e, nil
+}
+
+func update(tx *sql.Tx, channel string, e Entry) error {
+	res, err := tx.Exec(`
+		UPDATE schedule s
+		SET hour = $3, hours = $4, block = $5, title = NULLIF($6, ''), prompt = $7, persona = NULLIF($8, ''), cohost = NULLIF($9, ''), day_of_week = $10, date = $11::date
+		FROM channel c
+		WHERE c.id = s.channel_id AND c.name = $1 AND s.id = $2`,
+		channel, e.ID, e.Hour, e.Hours, e.Block, e.Title, e.Prompt, e.Persona, e.CoHost, e.DayOfWeek, e.Date)
+	if err != nil {
+		return fmt.Errorf("failed to update schedule entry: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) Delete(channel, id string) error {
+	if !idPattern.MatchString(id) {
+		return ErrNotFound
+	}
+	res, err := s.db.Exec(`
+		DELETE FROM schedule s
+		USING channel c
+		WHERE c.id = s.channel_id AND c.name = $1 AND s.id = $2`, channel, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule entry: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// At 指定時刻に放送する枠
+func (s *Store) At(channel string, t time.Time) (Entry, error) {
+	entries, err := s.List(channel)
+	if err != nil {
+		return Entry{}, err
+	}
+	e, ok := Resolve(entries, t)
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	return e, nil
+}
+
+// inTx 重複チェックと書き込みを SERIALIZABLE のトランザクションで行う
+//
+// 同時に書き込んだ2件が両方とも重複チェックを通らないよう、衝突した側はやり直す。
+func (s *Store) inTx(fn func(tx *sql.Tx) error) error {
+	for attempt := 1; ; attempt++ {
+		err := s.tryTx(fn)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "40001" && attempt < maxTxAttempts {
+			continue
+		}
+		return err
+	}
+}
+
+func (s *Store) tryTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return fmt.Errorf("failed to begin schedule transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit schedule entry: %w", err)
+	}
+	return nil
+}
+
+func check(q querier, channel string, e *Entry) error {
+	if err := e.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	entries, err := list(q, channel)
+	if err != nil {
+		return err
+	}
+	if existing, ok := FindOverlap(entries, *e); ok {
+		return &OverlapError{Existing: existing}
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEntry(row scanner) (Entry, error) {
+	var e Entry
+	var dow sql.NullInt64
+	var date sql.NullTime
+	if err := row.Scan(&e.ID, &e.Hour, &e.Hours, &e.Block, &e.Title, &e.Prompt, &e.Persona, &e.CoHost, &dow, &date); err != nil {
+		return Entry{}, err
+	}
+	if dow.Valid {
+		v := int(dow.Int64)
+		e.DayOfWeek = &v
+	}
+	if date.Valid {
+		v := date.Time.Format(dateLayout)
+		e.Date = &v
+	}
+	return e, nil
+}
@@ -0,0 +1,227 @@
+// Package screener 放送前に通話者と文字でやりとりし、話したい内容の要約と不適切なリクエストの判定をする
+package screener
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/radio24/pkg/llm"
+)
+
+// MaxTurns 通話者の発言に返事をする回数（超えたら締めの一言だけ返す）
+const MaxTurns = 4
+
+// maxMessageLength 通話者の1回の発言の上限（文字数）
+const maxMessageLength = 500
+
+// ClosingMessage やりとりを終えた後の返事
+const ClosingMessage = "ありがとうございます！DJに伝えておきますので、順番が来るまでこのままお待ちください。"
+
+// fallbackOpener 最初の問いかけを作れなかった時の文
+const fallbackOpener = "ラジオ24のスタッフです。オンエアの前に少しだけお話を聞かせてください。今日はどんなことを話したいですか？"
+
+const screenerPrompt = "あなたはラジオ番組「ラジオ24」の電話受付スタッフです。放送に出る前のリスナーと文字でやりとりし、" +
+	"DJと何について話したいかを短く聞き出してください。返事は1〜2文で、親しみやすく丁寧に。" +
+	"放送の内容には触れず、質問は1回に1つまでにしてください。"
+
+const judgePrompt = "あなたはラジオ番組の構成作家です。放送前にスタッフがリスナーと交わしたやりとりを読み、次のJSONだけを返してください。\n" +
+	`{"summary": "DJが通話の最初に触れるための、リスナーが話したい内容の要約（100文字程度・日本語）", ` +
+	`"flagged": 嫌がらせ・差別・脅迫・性的な内容・個人情報の暴露・宣伝目的など放送に出すべきでなければ true, ` +
+	`"reason": "flagged の場合の短い理由"}`
+
+// ErrUnknownCall Start していない、または Forget した通話
+var ErrUnknownCall = errors.New("screening not started")
+
+// Role 発言者
+type Role string
+
+const (
+	RoleCaller   Role = "caller"
+	RoleScreener Role = "screener"
+)
+
+// Turn やりとりの1発言
+type Turn struct {
+	Role Role   `json:"role"`
+	Text string `json:"text"`
+}
+
+// Result スクリーニングの結果（Summary を放送中のDJに渡す）
+type Result struct {
+	Summary string `json:"summary"`
+	Flagged bool   `json:"flagged"` // 放送に出さない
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Screener 待合室の通話者ごとのやりとり
+type Screener struct {
+	chat llm.Chat
+
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	topic string // リクエスト時の事前質問への答え
+	turns []Turn
+}
+
+func New(chat llm.Chat) *Screener {
+	return &Screener{chat: chat, calls: map[string]*call{}}
+}
+
+// Start 待合室に入った通話者への最初の問いかけ（LLMが失敗しても定型文を返す）
+func (s *Screener) Start(ctx context.Context, id, topic string) (string, error) {
+	c := &call{topic: topic}
+	s.mu.Lock()
+	s.calls[id] = c
+	s.mu.Unlock()
+
+	prompt := "リスナーが待合室に入りました。最初の一言をかけてください。"
+	if topic != "" {
+		prompt += "リクエストの時に「" + topic + "」について話したいと書いています。内容を一歩だけ掘り下げる質問をしてください。"
+	}
+	opener, err := s.chat.Complete(ctx, llm.ChatRequest{
+		System:      screenerPrompt,
+		Prompt:      prompt,
+		MaxTokens:   150,
+		Temperature: 0.7,
+	})
+	if err != nil || strings.TrimSpace(opener) == "" {
+		opener = fallbackOpener
+	}
+	opener = strings.TrimSpace(opener)
+
+	s.mu.Lock()
+	c.turns = append(c.turns, Turn{Role: RoleScreener, Text: opener})
+	s.mu.Unlock()
+	return opener, err
+}
+
+// Say 通話者の発言に返事をする（MaxTurns 回目の返事で done。以降は ClosingMessage だけ返す）
+func (s *Screener) Say(ctx context.Context, id, text string) (reply string, done bool, err error) {
+	s.mu.Lock()
+	c, ok := s.calls[id]
+	if !ok {
+		s.mu.Unlock()
+		return "", false, ErrUnknownCall
+	}
+	if c.callerTurns() >= MaxTurns {
+		s.mu.Unlock()
+		return ClosingMessage, true, nil
+	}
+	if runes := []rune(text); len(runes) > maxMessageLength {
+		text = string(runes[:maxMessageLength])
+	}
+	c.turns = append(c.turns, Turn{Role: RoleCaller, Text: text})
+	done = c.callerTurns() >= MaxTurns
+	transcript := c.transcript()
+	s.mu.Unlock()
+
+	if done {
+		reply = ClosingMessage
+	} else {
+		reply, err = s.chat.Complete(ctx, llm.ChatRequest{
+			System:      screenerPrompt,
+			Prompt:      transcript + "\nスタッフとして次の返事をしてください。",
+			MaxTokens:   150,
+			Temperature: 0.7,
+		})
+		if err != nil || strings.TrimSpace(reply) == "" {
+			reply = "なるほど、ありがとうございます。ほかに話したいことはありますか？"
+		}
+		reply = strings.TrimSpace(reply)
+	}
+
+	s.mu.Lock()
+	c.turns = append(c.turns, Turn{Role: RoleScreener, Text: reply})
+	s.mu.Unlock()
+	return reply, done, err
+}
+
+// Screen これまでのやりとりを要約し、放送に出すべきでないかを判定する
+//
+// LLMが要約を返さなかった場合は、事前質問への答えと通話者の発言をつないだものを要約にする。
+// LLMが失敗した・判定を読めなかった時はエラーを返す（Flagged が false でも判定を通ったことにはならない）。
+func (s *Screener) Screen(ctx context.Context, id string) (Result, error) {
+	s.mu.Lock()
+	c, ok := s.calls[id]
+	if !ok {
+		s.mu.Unlock()
+		return Result{}, ErrUnknownCall
+	}
+	transcript, digest := c.transcript(), c.digest()
+	s.mu.Unlock()
+
+	var result Result
+	text, err := s.chat.Complete(ctx, llm.ChatRequest{
+		System:      judgePrompt,
+		Prompt:      transcript,
+		MaxTokens:   300,
+		Temperature: 0.2,
+		JSON:        true,
+	})
+	if err == nil {
+		err = json.Unmarshal([]byte(text), &result)
+	}
+	if strings.TrimSpace(result.Summary) == "" {
+		result.Summary = digest
+	}
+	result.Summary = strings.TrimSpace(result.Summary)
+	return result, err
+}
+
+// Forget やりとりを破棄する（放送に出た・取り下げた）
+func (s *Screener) Forget(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.calls, id)
+}
+
+func (c *call) callerTurns() int {
+	n := 0
+	for _, t := range c.turns {
+		if t.Role == RoleCaller {
+			n++
+		}
+	}
+	return n
+}
+
+// transcript LLMに渡すやりとり
+func (c *call) transcript() string {
+	var b strings.Builder
+	if c.topic != "" {
+		b.WriteString("リクエスト時の「何について話したい？」への答え: " + c.topic + "\n")
+	}
+	for _, t := range c.turns {
+		if t.Role == RoleCaller {
+			b.WriteString("リスナー: ")
+		} else {
+			b.WriteString("スタッフ: ")
+		}
+		b.WriteString(t.Text + "\n")
+	}
+	return b.String()
+}
+
+// digest 要約が作れない時の代わり（事前質問への答えと通話者の発言、200文字まで）
+func (c *call) digest() string {
+	parts := []string{}
+	if c.topic != "" {
+		parts = append(parts, c.topic)
+	}
+	for _, t := range c.turns {
+		if t.Role == RoleCaller {
+			parts = append(parts, t.Text)
+		}
+	}
+	digest := strings.Join(parts, " / ")
+	if runes := []rune(digest); len(runes) > 200 {
+		digest = string(runes[:200])
+	}
+	return digest
+}
@@ -0,0 +1,76 @@
+package screener
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/radio24/pkg/llm"
+)
+
+func TestConversationEndsAfterMaxTurns(t *testing.T) {
+	s := New(llm.NewFake())
+	ctx := context.Background()
+
+	if opener, _ := s.Start(ctx, "req-1", "猫の話"); opener == "" {
+		t.Fatal("empty opener")
+	}
+	for i := 1; i <= MaxTurns; i++ {
+		reply, done, err := s.Say(ctx, "req-1", "うちの猫が")
+		if err != nil || reply == "" {
+			t.Fatalf("turn %d: %q, %v", i, reply, err)
+		}
+		if done != (i == MaxTurns) {
+			t.Errorf("turn %d: done = %v", i, done)
+		}
+	}
+	if reply, done, _ := s.Say(ctx, "req-1", "まだ話したい"); reply != ClosingMessage || !done {
+		t.Errorf("after max turns = %q, %v", reply, done)
+	}
+
+	s.Forget("req-1")
+	if _, _, err := s.Say(ctx, "req-1", "もしもし"); !errors.Is(err, ErrUnknownCall) {
+		t.Errorf("Say after Forget = %v", err)
+	}
+}
+
+func TestScreenSummarizesAndFlags(t *testing.T) {
+	fake := llm.NewFake()
+	fake.Reply = func(req llm.ChatRequest) (string, error) {
+		if !req.JSON {
+			return "どんなお話ですか？", nil
+		}
+		if strings.Contains(req.Prompt, "宣伝") {
+			return `{"summary": "商品の宣伝", "flagged": true, "reason": "宣伝目的"}`, nil
+		}
+		return `{"summary": "週末のライブの感想を話したい", "flagged": false}`, nil
+	}
+	s := New(fake)
+	ctx := context.Background()
+
+	s.Start(ctx, "req-1", "ライブ")
+	s.Say(ctx, "req-1", "週末のライブが最高でした")
+	if r, err := s.Screen(ctx, "req-1"); err != nil || r.Flagged || r.Summary != "週末のライブの感想を話したい" {
+		t.Errorf("Screen(req-1) = %+v, %v", r, err)
+	}
+
+	s.Start(ctx, "req-2", "")
+	s.Say(ctx, "req-2", "うちの店の宣伝をさせてください")
+	if r, _ := s.Screen(ctx, "req-2"); !r.Flagged || r.Reason != "宣伝目的" {
+		t.Errorf("Screen(req-2) = %+v", r)
+	}
+}
+
+func TestScreenFallsBackToDigest(t *testing.T) {
+	// Fake は JSON指定時に "{}" を返す（要約なし）
+	s := New(llm.NewFake())
+	ctx := context.Background()
+	s.Start(ctx, "req-1", "猫の話")
+	s.Say(ctx, "req-1", "最近2匹目を飼い始めました")
+
+	r, err := s.Screen(ctx, "req-1")
+	if err != nil || r.Flagged || r.Summary != "猫の話 / 最近2匹目を飼い始めました" {
+		t.Errorf("Screen() = %+v, %v", r, err)
+	}
+}
@@ -0,0 +1,150 @@
+package scripts
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultThreshold これ以上のコサイン類似度なら言い回しの繰り返しとみなす
+const DefaultThreshold = 0.9
+
+// DefaultWindow 繰り返しをチェックする過去の範囲
+const DefaultWindow = 6 * time.Hour
+
+// Script 放送した台本
+type Script struct {
+	ID        string    `json:"id"`
+	Topic     string    `json:"topic,omitempty"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Match 類似する過去の台本
+type Match struct {
+	Script
+	Similarity float64 `json:"similarity"`
+}
+
+// Duplicate 類似度の高い順に並んだ matches から threshold 以上の最初のもの
+func Duplicate(matches []Match, threshold float64) (Match, bool) {
+	for _, m := range matches {
+		if m.Similarity >= threshold {
+			return m, true
+		}
+	}
+	return Match{}, false
+}
+
+// Pending まだ放送していない台本（先読みで放送待ちのもの）
+type Pending struct {
+	Topic     string `json:"topic,omitempty"`
+	Text      string `json:"text"`
+	Embedding string `json:"embedding"` // pgvector 形式
+}
+
+// ComparePending embedding と放送待ちの台本のコサイン類似度を matches に加え、類似度の高い順に並べ直す
+//
+// 埋め込みが読めない台本は比べずに飛ばす。
+func ComparePending(matches []Match, embedding string, pending []Pending) []Match {
+	v, err := ParseVector(embedding)
+	if err != nil {
+		return matches
+	}
+	for _, p := range pending {
+		u, err := ParseVector(p.Embedding)
+		if err != nil || len(u) != len(v) {
+			continue
+		}
+		matches = append(matches, Match{Script: Script{Topic: p.Topic, Text: p.Text}, Similarity: cosine(v, u)})
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Similarity > matches[j].Similarity })
+	return matches
+}
+
+// ParseVector pgvector 形式（"[0.1,0.2]"）の文字列を読む
+func ParseVector(s string) ([]float64, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") || len(s) == 2 {
+		return nil, fmt.Errorf("invalid vector: %q", s)
+	}
+	fields := strings.Split(s[1:len(s)-1], ",")
+	v := make([]float64, len(fields))
+	for i, f := range fields {
+		x, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vector: %w", err)
+		}
+		v[i] = x
+	}
+	return v, nil
+}
+
+func cosine(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// Store aired_script テーブルへのアクセス
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Add 放送した台本を保存（embedding は pgvector 形式の文字列、空なら埋め込みなし）
+func (s *Store) Add(channel, topic, text, embedding string) (Script, error) {
+	sc := Script{Topic: topic, Text: text}
+	err := s.db.QueryRow(`
+		INSERT INTO aired_script (channel_id, topic, text, embed)
+		SELECT c.id, NULLIF($2, ''), $3, NULLIF($4, '')::vector
+		FROM channel c WHERE c.name = $1
+		RETURNING id, created_at`,
+		channel, topic, text, embedding).Scan(&sc.ID, &sc.CreatedAt)
+	if err == sql.ErrNoRows {
+		return Script{}, fmt.Errorf("channel not found: %s", channel)
+	}
+	if err != nil {
+		return Script{}, fmt.Errorf("failed to store script: %w", err)
+	}
+	return sc, nil
+}
+
+// Similar since 以降に放送した台本を embedding とのコサイン類似度の高い順に取得
+func (s *Store) Similar(channel, embedding string, since time.Time, limit int) ([]Match, error) {
+	rows, err := s.db.Query(`
+		SELECT a.id, COALESCE(a.topic, ''), a.text, a.created_at, 1 - (a.embed <=> $2::vector) AS similarity
+		FROM aired_script a
+		JOIN channel c ON c.id = a.channel_id
+		WHERE c.name = $1 AND a.embed IS NOT NULL AND a.created_at >= $3
+		ORDER BY a.embed <=> $2::vector
+		LIMIT $4`, channel, embedding, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search scripts: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []Match{}
+	for rows.Next() {
+		var m Match
+		if err := rows.Scan(&m.ID, &m.Topic, &m.Text, &m.CreatedAt, &m.Similarity); err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
@@ -0,0 +1,45 @@
+package scripts
+
+import "testing"
+
+func TestDuplicate(t *testing.T) {
+	matches := []Match{
+		{Script: Script{ID: "a"}, Similarity: 0.93},
+		{Script: Script{ID: "b"}, Similarity: 0.71},
+	}
+
+	if m, ok := Duplicate(matches, DefaultThreshold); !ok || m.ID != "a" {
+		t.Errorf("Duplicate = %+v, %v, want a", m, ok)
+	}
+	if _, ok := Duplicate(matches, 0.95); ok {
+		t.Error("no match should be above 0.95")
+	}
+	if _, ok := Duplicate(nil, DefaultThreshold); ok {
+		t.Error("no matches should not be a duplicate")
+	}
+}
+
+func TestComparePending(t *testing.T) {
+	aired := []Match{{Script: Script{ID: "a", Text: "昨日の話"}, Similarity: 0.5}}
+	pending := []Pending{
+		{Text: "同じ話", Embedding: "[1,0.1]"},
+		{Text: "別の話", Embedding: "[0,1]"},
+		{Text: "壊れた埋め込み", Embedding: "[1,"},
+	}
+
+	matches := ComparePending(aired, "[1,0]", pending)
+	if len(matches) != 3 {
+		t.Fatalf("matches = %+v, want aired and two pending", matches)
+	}
+	m, ok := Duplicate(matches, DefaultThreshold)
+	if !ok || m.Text != "同じ話" {
+		t.Errorf("Duplicate = %+v, %v, want the pending script", m, ok)
+	}
+	if matches[1].ID != "a" || matches[2].Text != "別の話" {
+		t.Errorf("matches not sorted by similarity: %+v", matches)
+	}
+
+	if got := ComparePending(aired, "not a vector", pending); len(got) != 1 {
+		t.Errorf("unreadable embedding should leave matches unchanged, got %+v", got)
+	}
+}
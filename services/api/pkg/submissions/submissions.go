@@ -0,0 +1,83 @@
+package submissions
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// idPattern 投稿のID（UUID）
+var idPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Submission 台本で紹介する候補のリスナー投稿
+type Submission struct {
+	ID         string    `json:"id"`
+	Text       string    `json:"text"`
+	CreatedAt  time.Time `json:"created_at"`
+	Similarity float64   `json:"similarity"`
+}
+
+// Store submission テーブルのうち、放送で紹介する投稿まわりのアクセス
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Relevant since 以降のまだ紹介していない投稿を、embedding との類似度が高い順に取得
+func (s *Store) Relevant(embedding string, since time.Time, minSimilarity float64, limit int) ([]Submission, error) {
+	rows, err := s.db.Query(`
+		SELECT id, text, created_at, 1 - (embed <=> $1) AS similarity
+		FROM submission
+		WHERE embed IS NOT NULL
+			AND featured_at IS NULL
+			AND created_at >= $2
+			AND 1 - (embed <=> $1) >= $3
+		ORDER BY embed <=> $1
+		LIMIT $4`, embedding, since, minSimilarity, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search submissions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []Submission{}
+	for rows.Next() {
+		var sub Submission
+		if err := rows.Scan(&sub.ID, &sub.Text, &sub.CreatedAt, &sub.Similarity); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// MarkFeatured ids の投稿を topic で紹介済みにし、新たに紹介済みにした件数を返す
+//
+// UUID でない id は無視する（すべて無視したらDBに問い合わせない）。
+func (s *Store) MarkFeatured(ids []string, topic string) (int, error) {
+	valid := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if idPattern.MatchString(id) {
+			valid = append(valid, id)
+		}
+	}
+	if len(valid) == 0 {
+		return 0, nil
+	}
+
+	res, err := s.db.Exec(`
+		UPDATE submission
+		SET featured_at = now(), featured_topic = NULLIF($2, '')
+		WHERE id = ANY($1::uuid[]) AND featured_at IS NULL`, valid, topic)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark submissions as featured: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
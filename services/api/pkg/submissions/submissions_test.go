@@ -0,0 +1,150 @@
+package submissions
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder 実行されたSQLを記録し、決まった行を返す database/sql のドライバ
+type recorder struct {
+	mu      sync.Mutex
+	queries []string
+	args    [][]driver.Value
+	rows    [][]driver.Value
+	columns []string
+}
+
+func (r *recorder) Open(string) (driver.Conn, error) { return &conn{r}, nil }
+
+type conn struct{ r *recorder }
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) { return &stmt{c.r, query}, nil }
+func (c *conn) Close() error                              { return nil }
+func (c *conn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+// CheckNamedValue pgx と同じく配列などもそのまま渡す
+func (c *conn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+type stmt struct {
+	r     *recorder
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) record(args []driver.Value) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.r.queries = append(s.r.queries, s.query)
+	s.r.args = append(s.r.args, args)
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.record(args)
+	return driver.RowsAffected(2), nil
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.record(args)
+	return &rows{columns: s.r.columns, values: s.r.rows}, nil
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+var registerOnce sync.Once
+var current *recorder
+
+func openRecorder(t *testing.T) (*sql.DB, *recorder) {
+	t.Helper()
+	registerOnce.Do(func() { sql.Register("submissions-recorder", &proxy{}) })
+	current = &recorder{}
+	db, err := sql.Open("submissions-recorder", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, current
+}
+
+// proxy テストごとの recorder に振り分ける
+type proxy struct{}
+
+func (proxy) Open(name string) (driver.Conn, error) { return current.Open(name) }
+
+const (
+	id1 = "6f1c1a52-3c4e-4b8e-9b1a-0d3f2a9e1c11"
+	id2 = "0b6e2a43-9d0f-4c3a-8e7b-5a4c3d2e1f00"
+)
+
+func TestMarkFeaturedUpdatesOnce(t *testing.T) {
+	db, rec := openRecorder(t)
+	s := NewStore(db)
+
+	n, err := s.MarkFeatured([]string{id1, "not-a-uuid", id2}, "天気")
+	if err != nil || n != 2 {
+		t.Fatalf("MarkFeatured() = %d, %v, want 2", n, err)
+	}
+	if len(rec.queries) != 1 || !strings.Contains(rec.queries[0], "id = ANY($1::uuid[])") {
+		t.Fatalf("queries = %q, want one UPDATE over all ids", rec.queries)
+	}
+	if got := rec.args[0][0]; !reflect.DeepEqual(got, []string{id1, id2}) {
+		t.Errorf("ids = %v, want only the valid ones", got)
+	}
+}
+
+func TestMarkFeaturedSkipsMalformedIDs(t *testing.T) {
+	db, rec := openRecorder(t)
+	s := NewStore(db)
+
+	if n, err := s.MarkFeatured([]string{"1; DROP TABLE submission"}, ""); err != nil || n != 0 {
+		t.Fatalf("MarkFeatured() = %d, %v, want 0", n, err)
+	}
+	if len(rec.queries) != 0 {
+		t.Errorf("queries = %q, want none for malformed ids", rec.queries)
+	}
+}
+
+func TestRelevant(t *testing.T) {
+	db, rec := openRecorder(t)
+	created := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	rec.columns = []string{"id", "text", "created_at", "similarity"}
+	rec.rows = [][]driver.Value{{id1, "雨が好きです", created, 0.8}}
+	s := NewStore(db)
+
+	since := created.Add(-time.Hour)
+	subs, err := s.Relevant("[1,0]", since, 0.3, 3)
+	if err != nil {
+		t.Fatalf("Relevant() = %v", err)
+	}
+	want := []Submission{{ID: id1, Text: "雨が好きです", CreatedAt: created, Similarity: 0.8}}
+	if !reflect.DeepEqual(subs, want) {
+		t.Errorf("submissions = %+v, want %+v", subs, want)
+	}
+	if !strings.Contains(rec.queries[0], "featured_at IS NULL") {
+		t.Errorf("query = %q, want already featured submissions excluded", rec.queries[0])
+	}
+	if got := rec.args[0]; !reflect.DeepEqual(got, []driver.Value{"[1,0]", since, 0.3, 3}) {
+		t.Errorf("args = %v", got)
+	}
+}
@@ -0,0 +1,126 @@
+package banter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/radio24/host/internal/persona"
+)
+
+// Line 掛け合いの1行
+type Line struct {
+	Speaker string `json:"speaker"` // persona.id
+	Text    string `json:"text"`
+}
+
+// SystemPrompt 2人のDJの掛け合い台本を生成するためのシステムプロンプト
+//
+// 台本は {"lines":[{"speaker":"<id>","text":"..."}]} のJSONで返すよう指示する。
+func SystemPrompt(lead, partner persona.Persona) string {
+	var b strings.Builder
+	b.WriteString("あなたは24時間AIラジオの構成作家です。2人のDJの掛け合いの台本を書いてください。\n")
+	for _, p := range []persona.Persona{lead, partner} {
+		fmt.Fprintf(&b, "\n[%s] %s\n%s\n", p.ID, p.DJ(), p.MonologuePrompt())
+	}
+	fmt.Fprintf(&b, "\nメインは %s で、%s が相づちやツッコミ、質問で会話を広げます。1行は1〜2文の短いセリフにして、交互に話してください。", lead.ID, partner.ID)
+	fmt.Fprintf(&b, "\n出力は必ず次のJSONだけにしてください: {\"lines\":[{\"speaker\":\"%s\",\"text\":\"セリフ\"},{\"speaker\":\"%s\",\"text\":\"セリフ\"}]}", lead.ID, partner.ID)
+	return b.String()
+}
+
+// Parse 生成されたJSONを掛け合いの行に変換
+//
+// speaker は id・名前のどちらでもよく、どちらでもない行や空の行は捨てる。
+func Parse(raw string, lead, partner persona.Persona) ([]Line, error) {
+	// ```json ... ``` で囲まれて返ってくることがある
+	raw = strings.TrimSpace(raw)
+	if i := strings.Index(raw, "{"); i > 0 {
+		raw = raw[i:]
+	}
+	if i := strings.LastIndex(raw, "}"); i >= 0 && i < len(raw)-1 {
+		raw = raw[:i+1]
+	}
+
+	var doc struct {
+		Lines []Line `json:"lines"`
+	}
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse banter script: %w", err)
+	}
+
+	lines := make([]Line, 0, len(doc.Lines))
+	for _, l := range doc.Lines {
+		text := strings.TrimSpace(l.Text)
+		if text == "" {
+			continue
+		}
+		switch l.Speaker {
+		case lead.ID, lead.Name:
+			l.Speaker = lead.ID
+		case partner.ID, partner.Name:
+			l.Speaker = partner.ID
+		default:
+			continue
+		}
+		lines = append(lines, Line{Speaker: l.Speaker, Text: text})
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("banter script has no lines")
+	}
+	return lines, nil
+}
+
+// Text 行をつなげた全体のテキスト（記憶・繰り返しチェック用）
+//
+// 話者が複数いる場合は names の名前を付けて「あおい「…」」の形にする。
+func Text(lines []Line, names map[string]string) string {
+	multi := false
+	for _, l := range lines {
+		if l.Speaker != lines[0].Speaker {
+			multi = true
+			break
+		}
+	}
+
+	parts := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if name := names[l.Speaker]; multi && name != "" {
+			parts = append(parts, name+"「"+l.Text+"」")
+			continue
+		}
+		parts = append(parts, l.Text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Fit 読み上げが maxRunes 文字に収まるように行を切り詰める
+//
+// 収まらない行は収まるところまでの文で切り、1文も入らなければそこで打ち切る。
+func Fit(lines []Line, maxRunes int) []Line {
+	fitted := make([]Line, 0, len(lines))
+	left := maxRunes
+	for _, l := range lines {
+		runes := []rune(l.Text)
+		if len(runes) <= left {
+			fitted = append(fitted, l)
+			left -= len(runes)
+			continue
+		}
+		if cut := lastSentenceEnd(runes[:left]); cut > 0 {
+			fitted = append(fitted, Line{Speaker: l.Speaker, Text: string(runes[:cut])})
+		}
+		break
+	}
+	return fitted
+}
+
+// lastSentenceEnd 最後の文末（。！？）の直後の位置（なければ0）
+func lastSentenceEnd(runes []rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		switch runes[i] {
+		case '。', '！', '？', '!', '?':
+			return i + 1
+		}
+	}
+	return 0
+}
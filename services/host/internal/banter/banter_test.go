@@ -0,0 +1,79 @@
+package banter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/radio24/host/internal/persona"
+)
+
+var (
+	aoi = persona.Persona{ID: "aoi", Name: "あおい"}
+	ken = persona.Persona{ID: "ken", Name: "けん"}
+)
+
+func TestParse(t *testing.T) {
+	raw := "```json\n" + `{"lines":[
+		{"speaker":"aoi","text":"こんばんは、あおいです。"},
+		{"speaker":"けん","text":" けんです。 "},
+		{"speaker":"guest","text":"誰？"},
+		{"speaker":"aoi","text":""}
+	]}` + "\n```"
+
+	lines, err := Parse(raw, aoi, ken)
+	if err != nil {
+		t.Fatalf("Parse() = %v", err)
+	}
+	want := []Line{{"aoi", "こんばんは、あおいです。"}, {"ken", "けんです。"}}
+	if len(lines) != len(want) {
+		t.Fatalf("lines = %+v, want %+v", lines, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestParseRejectsEmptyOrInvalid(t *testing.T) {
+	for _, raw := range []string{"ただのテキスト", `{"lines":[]}`, `{"lines":[{"speaker":"x","text":"a"}]}`} {
+		if _, err := Parse(raw, aoi, ken); err == nil {
+			t.Errorf("Parse(%q) should fail", raw)
+		}
+	}
+}
+
+func TestSystemPromptNamesBothHosts(t *testing.T) {
+	prompt := SystemPrompt(aoi, ken)
+	for _, want := range []string{"[aoi] DJのあおい", "[ken] DJのけん", `"speaker":"aoi"`} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt does not contain %q", want)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	names := map[string]string{"aoi": "あおい", "ken": "けん"}
+
+	if got := Text([]Line{{"aoi", "こんにちは。"}}, names); got != "こんにちは。" {
+		t.Errorf("single speaker text = %q", got)
+	}
+	got := Text([]Line{{"aoi", "こんにちは。"}, {"ken", "どうも。"}}, names)
+	if want := "あおい「こんにちは。」 けん「どうも。」"; got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
+
+func TestFitCutsAtSentenceEnd(t *testing.T) {
+	lines := []Line{{"aoi", "こんにちは。"}, {"ken", "今日は晴れ。明日は雨です。"}, {"aoi", "では。"}}
+
+	got := Fit(lines, 12)
+	want := []Line{{"aoi", "こんにちは。"}, {"ken", "今日は晴れ。"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Fit() = %v, want %v", got, want)
+	}
+	if got := Fit(lines, 3); len(got) != 0 {
+		t.Errorf("Fit() without room for a sentence = %v, want none", got)
+	}
+}
@@ -0,0 +1,260 @@
+package director
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Host Directorが番組進行のために操作するホスト側の機能
+type Host interface {
+	// SetPersona 枠を担当するDJのペルソナと掛け合いの相手に切り替え（空なら既定・1人）
+	SetPersona(id, coHost string)
+	// SetPrompt 枠の進行用ガイダンスを台本生成のシステムプロンプトに設定
+	SetPrompt(prompt string)
+	// Speak トピックについて台本を生成して読み上げ
+	Speak(topic string)
+	// Close セグメントのクロージングを within（セグメント終了まで）に収まるように読み上げ
+	Close(topic string, within time.Duration)
+	// AnswerQuestion キューからリスナーの質問を1件取り出して答える（なければ false）
+	AnswerQuestion() bool
+	// StartBed / StopBed 音楽ベッドの再生・停止
+	StartBed()
+	StopBed()
+	// InsertSweeper セグメント間のスイーパーを挿入
+	InsertSweeper()
+}
+
+// defaultTopics 枠ごとの台本トピック
+var defaultTopics = map[Block][]string{
+	BlockOP:     {"番組のオープニング", "今日の放送予定"},
+	BlockNews:   {"最新のニュース", "今日の天気予報", "今日の出来事"},
+	BlockQandA:  {"リスナーからのメッセージ", "リスナーへの問いかけ"},
+	BlockMusic:  {"音楽の話題", "今流れている曲の雰囲気"},
+	BlockTopicA: {"テクノロジーの話題", "エンターテイメント", "季節の話題"},
+	BlockJingle: {"ラジオ24のステーションID"},
+}
+
+// Topics 枠ごとの台本トピックをすべて（定型文を先に合成しておくのに使う）
+func Topics() []string {
+	var topics []string
+	for _, block := range []Block{BlockOP, BlockNews, BlockQandA, BlockMusic, BlockTopicA, BlockJingle} {
+		topics = append(topics, defaultTopics[block]...)
+	}
+	return topics
+}
+
+// fallbackSlot 時間割が取得できない時の枠
+var fallbackSlot = Slot{Block: BlockTopicA}
+
+// musicTalkInterval MUSIC枠で何Tickごとに喋るか（それ以外はベッドを流す）
+const musicTalkInterval = 4
+
+// TickInterval ホストが定期的に Tick を呼ぶ間隔（先読みで次に喋るトピックを見積もるのにも使う）
+const TickInterval = 30 * time.Second
+
+// talksAt 枠に入ってから ticks 回目の Tick でトピックについて喋るか（MUSIC枠は musicTalkInterval ごと）
+func talksAt(block Block, ticks int) bool {
+	return block != BlockMusic || ticks%musicTalkInterval == 0
+}
+
+// Director 時間割に従って番組を進行する
+//
+// Tick ごとに現在の枠を確認し、枠が変わったらスイーパーを挟んでプロンプトと
+// ベッドを切り替える。枠の中では種類に応じて喋る・質問に答える・音楽を流す。
+type Director struct {
+	mu       sync.Mutex
+	schedule Schedule
+	host     Host
+	topics   map[Block][]string
+	current  *Slot
+	segment  Segment
+	closed   bool // 現在のセグメントでクロージング済みか
+	ticks    int  // 現在の枠に入ってからのTick数
+	topicIdx int
+}
+
+func New(schedule Schedule, host Host) *Director {
+	return &Director{
+		schedule: schedule,
+		host:     host,
+		topics:   defaultTopics,
+	}
+}
+
+// Current 現在の枠
+func (d *Director) Current() (Slot, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.current == nil {
+		return Slot{}, false
+	}
+	return *d.current, true
+}
+
+// Segment 現在のセグメント
+func (d *Director) Segment() (Segment, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.segment, d.current != nil
+}
+
+// PromptLine 台本生成に埋め込む現在のセグメント情報（セグメント開始前は空）
+func (d *Director) PromptLine(now time.Time) string {
+	seg, ok := d.Segment()
+	if !ok {
+		return ""
+	}
+	return seg.PromptLine(now)
+}
+
+// ClosingAt 現在のセグメントでクロージングを始める時刻（クロージング済み・セグメント開始前は false）
+//
+// ホストはこの時刻にタイマーで Tick を呼ぶ（定期の Tick を待つとクロージングの時間を逃すため）。
+func (d *Director) ClosingAt() (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.current == nil || d.closed {
+		return time.Time{}, false
+	}
+	return d.segment.End().Add(-closingLead), true
+}
+
+// Tick 番組進行を1ステップ進める
+//
+// セグメントの最初の Tick でイントロ、終了30秒前（ClosingAt）にクロージングを喋り、
+// それ以外は枠の種類に応じて進行する。
+func (d *Director) Tick(ctx context.Context, now time.Time) {
+	slot, err := d.schedule.SlotAt(ctx, now)
+	if err != nil {
+		log.Printf("Director: failed to get schedule, keeping current block: %v", err)
+		if cur, ok := d.Current(); ok {
+			slot = cur
+		} else {
+			slot = fallbackSlot
+		}
+	}
+
+	d.mu.Lock()
+	changed := d.current == nil || *d.current != slot || !now.Before(d.segment.End())
+	var prev *Slot
+	if changed {
+		prev = d.current
+		d.current = &slot
+		d.segment = newSegment(slot, now)
+		d.closed = false
+		d.ticks = 0
+		d.topicIdx = 0
+	}
+	ticks := d.ticks
+	d.ticks++
+	closing := !d.closed && d.segment.Phase(now) == PhaseClosing
+	if closing {
+		d.closed = true
+	}
+	segment := d.segment
+	d.mu.Unlock()
+
+	if changed {
+		d.enter(prev, segment)
+	}
+
+	switch {
+	case closing:
+		d.host.Close(segment.Name()+"のクロージング", segment.Remaining(now))
+		return
+	case changed && slot.Block != BlockJingle:
+		d.host.Speak(segment.Name() + "のオープニング")
+		return
+	}
+
+	switch slot.Block {
+	case BlockQandA:
+		if d.host.AnswerQuestion() {
+			return
+		}
+		d.host.Speak(d.nextTopic(slot.Block))
+	case BlockMusic:
+		if talksAt(slot.Block, ticks) {
+			d.host.Speak(d.nextTopic(slot.Block))
+		}
+	case BlockJingle:
+		if !changed {
+			d.host.InsertSweeper()
+		}
+		d.host.Speak(d.nextTopic(slot.Block))
+	default:
+		d.host.Speak(d.nextTopic(slot.Block))
+	}
+}
+
+// enter 枠の切り替え
+func (d *Director) enter(prev *Slot, segment Segment) {
+	slot := segment.Slot
+	log.Printf("Director: entering %s block (hour=%d, until %s)", slot.Block, slot.Hour, segment.End().Format("15:04"))
+
+	if prev != nil {
+		d.host.InsertSweeper()
+	}
+	if slot.Block == BlockMusic {
+		d.host.StartBed()
+	} else if prev != nil && prev.Block == BlockMusic {
+		d.host.StopBed()
+	}
+	d.host.SetPersona(slot.Persona, slot.CoHost)
+	d.host.SetPrompt(slot.Prompt)
+}
+
+// Upcoming 現在の枠で within の間に Tick が喋る予定のトピック（先読み用。順番は進めない）
+//
+// now から TickInterval ごとに Tick が来るとみなして Tick と同じ規則でトピックを数え、
+// クロージング（とその後の枠の切り替え）より先の分は含めない。
+func (d *Director) Upcoming(now time.Time, within time.Duration, n int) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.current == nil {
+		return nil
+	}
+	block := d.current.Block
+	topics := d.topicsLocked(block)
+	until := d.segment.End()
+	if !d.closed {
+		until = until.Add(-closingLead)
+	}
+	if horizon := now.Add(within); horizon.Before(until) {
+		until = horizon
+	}
+
+	upcoming := make([]string, 0, n)
+	idx := d.topicIdx
+	ticks := d.ticks
+	for at := now.Add(TickInterval); !at.After(until) && len(upcoming) < n && len(upcoming) < len(topics); at = at.Add(TickInterval) {
+		if talksAt(block, ticks) {
+			upcoming = append(upcoming, topics[idx%len(topics)])
+			idx++
+		}
+		ticks++
+	}
+	return upcoming
+}
+
+func (d *Director) topicsLocked(block Block) []string {
+	if topics := d.topics[block]; len(topics) > 0 {
+		return topics
+	}
+	return d.topics[BlockTopicA]
+}
+
+func (d *Director) nextTopic(block Block) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	topics := d.topicsLocked(block)
+	topic := topics[d.topicIdx%len(topics)]
+	d.topicIdx++
+	return topic
+}
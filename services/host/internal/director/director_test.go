@@ -0,0 +1,240 @@
+package director
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fakeSchedule struct {
+	slot Slot
+	err  error
+}
+
+func (s *fakeSchedule) SlotAt(ctx context.Context, at time.Time) (Slot, error) {
+	return s.slot, s.err
+}
+
+type fakeHost struct {
+	calls     []string
+	prompt    string
+	persona   string
+	coHost    string
+	questions int
+}
+
+func (h *fakeHost) SetPrompt(prompt string) {
+	h.prompt = prompt
+	h.calls = append(h.calls, "prompt")
+}
+func (h *fakeHost) SetPersona(id, coHost string) { h.persona, h.coHost = id, coHost }
+func (h *fakeHost) Speak(topic string)           { h.calls = append(h.calls, "speak:"+topic) }
+func (h *fakeHost) Close(topic string, within time.Duration) {
+	h.calls = append(h.calls, fmt.Sprintf("close:%s:%v", topic, within))
+}
+func (h *fakeHost) AnswerQuestion() bool {
+	if h.questions == 0 {
+		return false
+	}
+	h.questions--
+	h.calls = append(h.calls, "answer")
+	return true
+}
+func (h *fakeHost) StartBed()      { h.calls = append(h.calls, "bed:start") }
+func (h *fakeHost) StopBed()       { h.calls = append(h.calls, "bed:stop") }
+func (h *fakeHost) InsertSweeper() { h.calls = append(h.calls, "sweeper") }
+
+func TestDirectorSetsPromptAndSpeaks(t *testing.T) {
+	schedule := &fakeSchedule{slot: Slot{Hour: 7, Block: BlockNews, Prompt: "朝のニュース"}}
+	host := &fakeHost{}
+	d := New(schedule, host)
+
+	now := time.Date(2026, 10, 18, 7, 10, 0, 0, time.Local)
+	d.Tick(context.Background(), now)
+	d.Tick(context.Background(), now.Add(30*time.Second))
+
+	want := []string{"prompt", "speak:ニュースのオープニング", "speak:最新のニュース"}
+	if !reflect.DeepEqual(host.calls, want) {
+		t.Fatalf("calls = %v, want %v", host.calls, want)
+	}
+	if host.prompt != "朝のニュース" {
+		t.Errorf("prompt = %q", host.prompt)
+	}
+}
+
+func TestDirectorSwitchesPersonaPerBlock(t *testing.T) {
+	schedule := &fakeSchedule{slot: Slot{Hour: 7, Block: BlockNews, Persona: "ken", CoHost: "aoi"}}
+	host := &fakeHost{}
+	d := New(schedule, host)
+
+	now := time.Date(2026, 10, 18, 7, 10, 0, 0, time.Local)
+	d.Tick(context.Background(), now)
+	if host.persona != "ken" || host.coHost != "aoi" {
+		t.Fatalf("persona = %q, cohost = %q, want ken with aoi", host.persona, host.coHost)
+	}
+
+	schedule.slot = Slot{Hour: 8, Block: BlockTopicA}
+	d.Tick(context.Background(), now.Add(time.Hour))
+	if host.persona != "" || host.coHost != "" {
+		t.Errorf("persona = %q, cohost = %q, want default DJ alone for block without persona", host.persona, host.coHost)
+	}
+}
+
+func TestDirectorBlockChangeInsertsSweeperAndSwitchesBed(t *testing.T) {
+	schedule := &fakeSchedule{slot: Slot{Hour: 5, Block: BlockMusic}}
+	host := &fakeHost{}
+	d := New(schedule, host)
+
+	now := time.Date(2026, 10, 18, 5, 10, 0, 0, time.Local)
+	d.Tick(context.Background(), now)
+	d.Tick(context.Background(), now.Add(30*time.Second)) // ベッドのみ
+
+	schedule.slot = Slot{Hour: 6, Block: BlockNews}
+	d.Tick(context.Background(), now.Add(time.Hour))
+
+	want := []string{
+		"bed:start", "prompt", "speak:ミュージックのオープニング",
+		"sweeper", "bed:stop", "prompt", "speak:ニュースのオープニング",
+	}
+	if !reflect.DeepEqual(host.calls, want) {
+		t.Fatalf("calls = %v, want %v", host.calls, want)
+	}
+}
+
+func TestDirectorQandAPullsFromQueue(t *testing.T) {
+	schedule := &fakeSchedule{slot: Slot{Hour: 12, Block: BlockQandA}}
+	host := &fakeHost{questions: 1}
+	d := New(schedule, host)
+
+	now := time.Date(2026, 10, 18, 12, 10, 0, 0, time.Local)
+	for i := 0; i < 3; i++ {
+		d.Tick(context.Background(), now.Add(time.Duration(i)*30*time.Second))
+	}
+
+	want := []string{"prompt", "speak:Q&Aのオープニング", "answer", "speak:リスナーからのメッセージ"}
+	if !reflect.DeepEqual(host.calls, want) {
+		t.Fatalf("calls = %v, want %v", host.calls, want)
+	}
+}
+
+func TestDirectorKeepsBlockWhenScheduleUnavailable(t *testing.T) {
+	schedule := &fakeSchedule{err: errors.New("api down")}
+	host := &fakeHost{}
+	d := New(schedule, host)
+
+	d.Tick(context.Background(), time.Now())
+	if slot, _ := d.Current(); slot.Block != BlockTopicA {
+		t.Fatalf("block = %s, want fallback %s", slot.Block, BlockTopicA)
+	}
+
+	schedule.err = nil
+	schedule.slot = Slot{Hour: 9, Block: BlockTopicA, Prompt: "午前"}
+	d.Tick(context.Background(), time.Now())
+
+	schedule.err = errors.New("api down")
+	d.Tick(context.Background(), time.Now())
+	if slot, _ := d.Current(); slot.Prompt != "午前" {
+		t.Fatalf("prompt = %q, want last known block to be kept", slot.Prompt)
+	}
+}
+
+func TestDirectorClosesSegmentOnce(t *testing.T) {
+	schedule := &fakeSchedule{slot: Slot{Hour: 9, Block: BlockTopicA}}
+	host := &fakeHost{}
+	d := New(schedule, host)
+
+	start := time.Date(2026, 10, 18, 9, 58, 0, 0, time.Local)
+	for _, offset := range []time.Duration{0, 100 * time.Second, 110 * time.Second} {
+		d.Tick(context.Background(), start.Add(offset))
+	}
+
+	want := []string{"prompt", "speak:トークのオープニング", "close:トークのクロージング:20s", "speak:テクノロジーの話題"}
+	if !reflect.DeepEqual(host.calls, want) {
+		t.Fatalf("calls = %v, want %v", host.calls, want)
+	}
+}
+
+func TestDirectorClosingAt(t *testing.T) {
+	schedule := &fakeSchedule{slot: Slot{Hour: 9, Block: BlockTopicA}}
+	d := New(schedule, &fakeHost{})
+
+	if _, ok := d.ClosingAt(); ok {
+		t.Fatal("ClosingAt() before the first tick should be false")
+	}
+	d.Tick(context.Background(), time.Date(2026, 10, 18, 9, 10, 0, 0, time.Local))
+	at, ok := d.ClosingAt()
+	if want := time.Date(2026, 10, 18, 9, 59, 30, 0, time.Local); !ok || !at.Equal(want) {
+		t.Fatalf("ClosingAt() = %v, %v, want %v", at, ok, want)
+	}
+
+	d.Tick(context.Background(), at)
+	if _, ok := d.ClosingAt(); ok {
+		t.Error("ClosingAt() after closing should be false")
+	}
+}
+
+func TestDirectorUpcomingDoesNotAdvance(t *testing.T) {
+	schedule := &fakeSchedule{slot: Slot{Hour: 7, Block: BlockNews}}
+	d := New(schedule, &fakeHost{})
+
+	now := time.Date(2026, 10, 18, 7, 10, 0, 0, time.Local)
+	if got := d.Upcoming(now, 3*time.Minute, 2); len(got) != 0 {
+		t.Fatalf("upcoming before first tick = %v", got)
+	}
+
+	d.Tick(context.Background(), now)
+
+	want := []string{"最新のニュース", "今日の天気予報"}
+	if got := d.Upcoming(now, 3*time.Minute, 2); !reflect.DeepEqual(got, want) {
+		t.Fatalf("upcoming = %v, want %v", got, want)
+	}
+	if got := d.Upcoming(now, 3*time.Minute, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("upcoming changed on second call: %v", got)
+	}
+}
+
+func TestDirectorUpcomingFollowsTick(t *testing.T) {
+	schedule := &fakeSchedule{slot: Slot{Hour: 5, Block: BlockMusic}}
+	host := &fakeHost{}
+	d := New(schedule, host)
+
+	// MUSIC枠は4Tickに1回しか喋らないので、3分先までに1件だけ
+	now := time.Date(2026, 10, 18, 5, 10, 0, 0, time.Local)
+	d.Tick(context.Background(), now)
+	if got, want := d.Upcoming(now, 3*time.Minute, 2), []string{"音楽の話題"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("upcoming in music block = %v, want %v", got, want)
+	}
+
+	// クロージングより後に喋る分は先読みしない
+	schedule.slot = Slot{Hour: 6, Block: BlockNews}
+	late := time.Date(2026, 10, 18, 6, 58, 50, 0, time.Local)
+	d.Tick(context.Background(), late)
+	if got, want := d.Upcoming(late, 3*time.Minute, 3), []string{"最新のニュース"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("upcoming before closing = %v, want %v", got, want)
+	}
+}
+
+func TestSegmentPhaseAndPromptLine(t *testing.T) {
+	at := time.Date(2026, 10, 18, 15, 0, 20, 0, time.Local)
+	seg := newSegment(Slot{Hour: 15, Block: BlockNews}, at)
+
+	if seg.Phase(at) != PhaseIntro {
+		t.Errorf("phase at start = %s, want intro", seg.Phase(at))
+	}
+	if got := seg.Phase(at.Add(20 * time.Minute)); got != PhaseBody {
+		t.Errorf("phase = %s, want body", got)
+	}
+
+	closing := time.Date(2026, 10, 18, 15, 59, 45, 0, time.Local)
+	if seg.Phase(closing) != PhaseClosing {
+		t.Errorf("phase = %s, want closing", seg.Phase(closing))
+	}
+
+	line := seg.PromptLine(time.Date(2026, 10, 18, 15, 47, 30, 0, time.Local))
+	if want := "このセグメント：ニュース（残り12:30）。"; line != want {
+		t.Errorf("prompt line = %q, want %q", line, want)
+	}
+}
@@ -0,0 +1,78 @@
+package director
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Block 時間割の枠の種類（schedule.block）
+type Block string
+
+const (
+	BlockOP     Block = "OP"
+	BlockNews   Block = "NEWS"
+	BlockQandA  Block = "QANDA"
+	BlockMusic  Block = "MUSIC"
+	BlockTopicA Block = "TOPIC_A"
+	BlockJingle Block = "JINGLE"
+)
+
+// Slot 時間割の1枠
+type Slot struct {
+	Channel string `json:"channel"`
+	Hour    int    `json:"hour"`
+	Block   Block  `json:"block"`
+	Prompt  string `json:"prompt"`
+	Persona string `json:"persona,omitempty"` // 枠を担当するDJ（空なら既定）
+	CoHost  string `json:"cohost,omitempty"`  // 掛け合いの相手（空なら1人で進行）
+}
+
+// Schedule 指定時刻の枠を返すもの
+type Schedule interface {
+	SlotAt(ctx context.Context, at time.Time) (Slot, error)
+}
+
+// APISchedule APIの /v1/schedule/now から時間割を取得
+type APISchedule struct {
+	BaseURL string
+	Channel string
+	Client  *http.Client
+}
+
+func NewAPISchedule(baseURL, channel string) *APISchedule {
+	return &APISchedule{
+		BaseURL: baseURL,
+		Channel: channel,
+		Client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (s *APISchedule) SlotAt(ctx context.Context, at time.Time) (Slot, error) {
+	q := url.Values{}
+	q.Set("channel", s.Channel)
+	q.Set("at", at.Format(time.RFC3339))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", s.BaseURL+"/v1/schedule/now?"+q.Encode(), nil)
+	if err != nil {
+		return Slot{}, err
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return Slot{}, fmt.Errorf("failed to fetch schedule: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Slot{}, fmt.Errorf("schedule API returned status: %d", resp.StatusCode)
+	}
+
+	var slot Slot
+	if err := json.NewDecoder(resp.Body).Decode(&slot); err != nil {
+		return Slot{}, fmt.Errorf("failed to decode schedule: %w", err)
+	}
+	return slot, nil
+}
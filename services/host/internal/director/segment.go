@@ -0,0 +1,93 @@
+package director
+
+import (
+	"fmt"
+	"time"
+)
+
+// Phase セグメント内の進行段階
+type Phase string
+
+const (
+	PhaseIntro   Phase = "intro"
+	PhaseBody    Phase = "body"
+	PhaseClosing Phase = "closing"
+)
+
+const (
+	// introDuration セグメント開始からイントロとみなす時間
+	introDuration = time.Minute
+	// closingLead セグメント終了の何秒前からクロージングに入るか
+	closingLead = 30 * time.Second
+)
+
+// blockNames プロンプトに埋め込む枠の表示名
+var blockNames = map[Block]string{
+	BlockOP:     "オープニング",
+	BlockNews:   "ニュース",
+	BlockQandA:  "Q&A",
+	BlockMusic:  "ミュージック",
+	BlockTopicA: "トーク",
+	BlockJingle: "ジングル",
+}
+
+// Segment 時間割の1枠を実際に放送している区間
+type Segment struct {
+	Slot    Slot          `json:"slot"`
+	Start   time.Time     `json:"start"`
+	Planned time.Duration `json:"planned"`
+}
+
+// newSegment at を含む時間帯（毎正時から1時間）のセグメントを作成
+func newSegment(slot Slot, at time.Time) Segment {
+	start := time.Date(at.Year(), at.Month(), at.Day(), at.Hour(), 0, 0, 0, at.Location())
+	return Segment{Slot: slot, Start: start, Planned: time.Hour}
+}
+
+func (s Segment) End() time.Time {
+	return s.Start.Add(s.Planned)
+}
+
+// Remaining 終了までの残り時間
+func (s Segment) Remaining(now time.Time) time.Duration {
+	if r := s.End().Sub(now); r > 0 {
+		return r
+	}
+	return 0
+}
+
+func (s Segment) Phase(now time.Time) Phase {
+	switch {
+	case s.Remaining(now) <= closingLead:
+		return PhaseClosing
+	case now.Sub(s.Start) < introDuration:
+		return PhaseIntro
+	default:
+		return PhaseBody
+	}
+}
+
+// Name 枠の表示名
+func (s Segment) Name() string {
+	if name, ok := blockNames[s.Slot.Block]; ok {
+		return name
+	}
+	return string(s.Slot.Block)
+}
+
+// PromptLine 台本生成に埋め込むセグメント情報（仕様書の「{segment}（残り{mm:ss}）」）
+func (s Segment) PromptLine(now time.Time) string {
+	line := fmt.Sprintf("このセグメント：%s（残り%s）。", s.Name(), formatMMSS(s.Remaining(now)))
+	switch s.Phase(now) {
+	case PhaseIntro:
+		line += "セグメントが始まったところです。この枠で何を届けるか軽く紹介してください。"
+	case PhaseClosing:
+		line += "まもなくセグメント終了です。話をまとめてクロージングしてください。"
+	}
+	return line
+}
+
+func formatMMSS(d time.Duration) string {
+	total := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%02d:%02d", total/60, total%60)
+}
@@ -0,0 +1,277 @@
+package lookahead
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Script 生成・レンダリング済みの台本
+type Script struct {
+	Topic    string
+	Text     string // 全体のテキスト（記憶・繰り返しチェック用）
+	Lines    []Line // 話者ごとの行（1人の場合は1行）
+	Rendered time.Time
+	// 台本で紹介したリスナー投稿のID（放送した時点で紹介済みにする）
+	Submissions []string
+	// 繰り返しチェックで得た Text の埋め込み（pgvector 形式。放送した台本の保存に使う）
+	Embedding string
+}
+
+// Line 話者ごとの1行
+type Line struct {
+	Speaker string // persona.id
+	Text    string
+	Audio   []byte // PCM（テストモードなど音声がない場合は空）
+}
+
+// RenderFunc トピックから台本を生成してPCMにレンダリングする
+type RenderFunc func(ctx context.Context, topic string) (Script, error)
+
+// Status バッファの状態（/director/status 用）
+type Status struct {
+	Depth      int      `json:"depth"`
+	Ready      []string `json:"ready"`
+	Pending    []string `json:"pending"`
+	Rendering  string   `json:"rendering,omitempty"`
+	Generation uint64   `json:"generation"`
+}
+
+// Buffer 次に喋る台本を先読みで生成・レンダリングしておくバッファ
+//
+// Prefetch で予定トピックを渡すと、Run のゴルーチンが depth 件まで先に生成して
+// 溜めておく。Take は用意済みならすぐ返す。Invalidate で世代を進めると、
+// 溜めていた台本は捨てられ、生成中のものはキャンセルされる（枠の切り替え・対話モードなど）。
+type Buffer struct {
+	mu        sync.Mutex
+	render    RenderFunc
+	depth     int
+	maxAge    time.Duration
+	gen       uint64
+	ready     []Script
+	pending   []string
+	rendering string
+	cancel    context.CancelFunc // 生成中の台本のキャンセル
+	changed   chan struct{}      // 状態が変わるたびに close して作り直す
+}
+
+func New(render RenderFunc, depth int, maxAge time.Duration) *Buffer {
+	if depth < 1 {
+		depth = 1
+	}
+	return &Buffer{
+		render:  render,
+		depth:   depth,
+		maxAge:  maxAge,
+		changed: make(chan struct{}),
+	}
+}
+
+// Run 生成ループ（ctx が終わるまでブロック）
+func (b *Buffer) Run(ctx context.Context) {
+	for {
+		topic, gen, renderCtx, ok := b.next(ctx)
+		if !ok {
+			b.mu.Lock()
+			changed := b.changed
+			b.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+			}
+			continue
+		}
+
+		script, err := b.render(renderCtx, topic)
+		if ctx.Err() != nil {
+			return
+		}
+		b.finish(topic, gen, script, err)
+	}
+}
+
+func (b *Buffer) next(ctx context.Context) (string, uint64, context.Context, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.pending) == 0 {
+		return "", 0, nil, false
+	}
+	topic := b.pending[0]
+	b.pending = b.pending[1:]
+	b.rendering = topic
+	renderCtx, cancel := context.WithCancel(ctx)
+	b.cancel = cancel
+	b.notifyLocked()
+	return topic, b.gen, renderCtx, true
+}
+
+func (b *Buffer) finish(topic string, gen uint64, script Script, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.cancel != nil {
+		b.cancel()
+		b.cancel = nil
+	}
+	b.rendering = ""
+	switch {
+	case gen != b.gen:
+		log.Printf("Lookahead: discarding %q rendered before invalidation", topic)
+	case err != nil:
+		log.Printf("Lookahead: failed to render %q: %v", topic, err)
+	default:
+		script.Topic = topic
+		if script.Rendered.IsZero() {
+			script.Rendered = time.Now()
+		}
+		b.ready = append(b.ready, script)
+	}
+	b.notifyLocked()
+}
+
+// Prefetch 次に喋る予定のトピックを先読み対象にする
+//
+// 用意済み・生成中のものは除き、合計が depth を超えない分だけ生成待ちに積む。
+// 以前の生成待ちはこの予定で置き換える。
+func (b *Buffer) Prefetch(topics ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.dropExpiredLocked(time.Now())
+
+	queued := len(b.ready)
+	if b.rendering != "" {
+		queued++
+	}
+	b.pending = b.pending[:0]
+	for _, topic := range topics {
+		if queued >= b.depth {
+			break
+		}
+		if b.hasLocked(topic) {
+			continue
+		}
+		b.pending = append(b.pending, topic)
+		queued++
+	}
+	b.notifyLocked()
+}
+
+// Take topic の台本を取り出す
+//
+// 用意済みならすぐ返し、生成中なら完成を待つ。どちらでもなければ false を返すので、
+// 呼び出し側でその場で生成する。
+func (b *Buffer) Take(ctx context.Context, topic string) (Script, bool) {
+	for {
+		b.mu.Lock()
+		b.dropExpiredLocked(time.Now())
+		for i, s := range b.ready {
+			if s.Topic == topic {
+				b.ready = append(b.ready[:i], b.ready[i+1:]...)
+				b.notifyLocked()
+				b.mu.Unlock()
+				return s, true
+			}
+		}
+		if b.rendering != topic {
+			b.mu.Unlock()
+			return Script{}, false
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Script{}, false
+		case <-changed:
+		}
+	}
+}
+
+// Ready すぐに取り出せる台本があるか
+func (b *Buffer) Ready() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dropExpiredLocked(time.Now())
+	return len(b.ready) > 0
+}
+
+// Queued 用意済みでまだ取り出されていない台本
+func (b *Buffer) Queued() []Script {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dropExpiredLocked(time.Now())
+	return append([]Script(nil), b.ready...)
+}
+
+// Invalidate 溜めている台本と生成待ちを捨て、生成中のものはキャンセルする
+//
+// 生成中の台本を待っている Take はすぐに false を返す。
+func (b *Buffer) Invalidate(reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.ready) > 0 || len(b.pending) > 0 || b.rendering != "" {
+		log.Printf("Lookahead: invalidated (%s): %d ready, %d pending, rendering %q", reason, len(b.ready), len(b.pending), b.rendering)
+	}
+	if b.cancel != nil {
+		b.cancel()
+		b.cancel = nil
+	}
+	b.gen++
+	b.ready = nil
+	b.pending = nil
+	b.rendering = ""
+	b.notifyLocked()
+}
+
+func (b *Buffer) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st := Status{
+		Depth:      b.depth,
+		Ready:      []string{},
+		Pending:    append([]string{}, b.pending...),
+		Rendering:  b.rendering,
+		Generation: b.gen,
+	}
+	for _, s := range b.ready {
+		st.Ready = append(st.Ready, s.Topic)
+	}
+	return st
+}
+
+func (b *Buffer) hasLocked(topic string) bool {
+	if b.rendering == topic {
+		return true
+	}
+	for _, s := range b.ready {
+		if s.Topic == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// dropExpiredLocked 古くなった台本を捨てる（放送の記憶やリスナーの投稿が古くなるため）
+func (b *Buffer) dropExpiredLocked(now time.Time) {
+	if b.maxAge <= 0 {
+		return
+	}
+	kept := b.ready[:0]
+	for _, s := range b.ready {
+		if now.Sub(s.Rendered) < b.maxAge {
+			kept = append(kept, s)
+		}
+	}
+	b.ready = kept
+}
+
+func (b *Buffer) notifyLocked() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
@@ -0,0 +1,194 @@
+package lookahead
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRenderer トピックごとに release されるまで生成を止めておける
+type fakeRenderer struct {
+	mu      sync.Mutex
+	calls   []string
+	gate    chan struct{}
+	started chan string
+}
+
+func newFakeRenderer(blocking bool) *fakeRenderer {
+	r := &fakeRenderer{started: make(chan string, 10)}
+	if blocking {
+		r.gate = make(chan struct{})
+	}
+	return r
+}
+
+func (r *fakeRenderer) render(ctx context.Context, topic string) (Script, error) {
+	r.mu.Lock()
+	r.calls = append(r.calls, topic)
+	r.mu.Unlock()
+	r.started <- topic
+	if r.gate != nil {
+		select {
+		case <-r.gate:
+		case <-ctx.Done():
+		}
+	}
+	return Script{Text: topic + "の台本", Lines: []Line{{Speaker: "aoi", Text: topic + "の台本", Audio: []byte{1, 2}}}}, nil
+}
+
+func (r *fakeRenderer) callCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.calls)
+}
+
+func waitReady(t *testing.T, b *Buffer, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for len(b.Status().Ready) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("ready = %v, want %d scripts", b.Status().Ready, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPrefetchFillsUpToDepth(t *testing.T) {
+	r := newFakeRenderer(false)
+	b := New(r.render, 2, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	b.Prefetch("天気", "ニュース", "音楽")
+	waitReady(t, b, 2)
+
+	s, ok := b.Take(ctx, "ニュース")
+	if !ok || s.Text != "ニュースの台本" || s.Topic != "ニュース" {
+		t.Fatalf("Take = %+v, %v", s, ok)
+	}
+	if _, ok := b.Take(ctx, "音楽"); ok {
+		t.Error("topic beyond depth should not have been rendered")
+	}
+	if n := r.callCount(); n != 2 {
+		t.Errorf("render calls = %d, want 2", n)
+	}
+
+	// 既に用意済みのトピックは再生成しない
+	b.Prefetch("天気", "音楽")
+	waitReady(t, b, 2)
+	if n := r.callCount(); n != 3 {
+		t.Errorf("render calls = %d, want 3", n)
+	}
+}
+
+func TestTakeWaitsForRenderingTopic(t *testing.T) {
+	r := newFakeRenderer(true)
+	b := New(r.render, 1, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	b.Prefetch("天気")
+	<-r.started
+
+	done := make(chan Script)
+	go func() {
+		s, _ := b.Take(ctx, "天気")
+		done <- s
+	}()
+	close(r.gate)
+
+	select {
+	case s := <-done:
+		if s.Text != "天気の台本" {
+			t.Errorf("script = %q", s.Text)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Take did not return after rendering finished")
+	}
+}
+
+func TestInvalidateDiscardsReadyAndInFlight(t *testing.T) {
+	r := newFakeRenderer(true)
+	b := New(r.render, 2, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	b.Prefetch("天気", "ニュース")
+	<-r.started
+	b.Invalidate("block changed")
+	close(r.gate)
+
+	// 生成中だった結果は捨てられ、生成待ちも消える
+	deadline := time.Now().Add(time.Second)
+	for b.Status().Rendering != "" {
+		if time.Now().After(deadline) {
+			t.Fatal("rendering did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	st := b.Status()
+	if len(st.Ready) != 0 || len(st.Pending) != 0 {
+		t.Fatalf("status = %+v, want empty after invalidation", st)
+	}
+	if _, ok := b.Take(ctx, "天気"); ok {
+		t.Error("script rendered before invalidation should not be returned")
+	}
+}
+
+func TestInvalidateCancelsRenderAndReleasesTake(t *testing.T) {
+	r := newFakeRenderer(true)
+	b := New(r.render, 1, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	b.Prefetch("天気")
+	<-r.started
+
+	taken := make(chan bool)
+	go func() {
+		_, ok := b.Take(ctx, "天気")
+		taken <- ok
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// gate を開けなくてもキャンセルで生成が終わり、待っていた Take も戻る
+	b.Invalidate("dialogue started")
+	select {
+	case ok := <-taken:
+		if ok {
+			t.Error("Take returned a script that was invalidated")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Take kept waiting for an invalidated render")
+	}
+
+	b.Prefetch("ニュース")
+	select {
+	case topic := <-r.started:
+		if topic != "ニュース" {
+			t.Errorf("next render = %q, want ニュース", topic)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cancelled render blocked the buffer")
+	}
+}
+
+func TestExpiredScriptsAreDropped(t *testing.T) {
+	b := New(nil, 2, time.Minute)
+	b.ready = []Script{
+		{Topic: "古い", Rendered: time.Now().Add(-2 * time.Minute)},
+		{Topic: "新しい", Rendered: time.Now()},
+	}
+
+	if _, ok := b.Take(context.Background(), "古い"); ok {
+		t.Error("expired script should not be returned")
+	}
+	if _, ok := b.Take(context.Background(), "新しい"); !ok {
+		t.Error("fresh script should be returned")
+	}
+}
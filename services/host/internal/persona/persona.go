@@ -0,0 +1,245 @@
+package persona
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Persona DJのキャラクター設定
+type Persona struct {
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	Style           string   `json:"style"`
+	Catchphrases    []string `json:"catchphrases"`
+	TTSVoice        string   `json:"tts_voice"`
+	RealtimeVoice   string   `json:"realtime_voice"`
+	ForbiddenTopics []string `json:"forbidden_topics"`
+}
+
+// Default 設定がない時のDJ
+var Default = Persona{
+	ID:            "default",
+	Style:         "自然で親しみやすい口調で、リスナーとの距離感を大切にしてください。",
+	TTSVoice:      "nova",
+	RealtimeVoice: "marin",
+}
+
+// withDefaults 未設定の項目を Default で埋める
+func (p Persona) withDefaults() Persona {
+	if p.Style == "" {
+		p.Style = Default.Style
+	}
+	if p.TTSVoice == "" {
+		p.TTSVoice = Default.TTSVoice
+	}
+	if p.RealtimeVoice == "" {
+		p.RealtimeVoice = Default.RealtimeVoice
+	}
+	return p
+}
+
+// DJ 自己紹介用の呼び名（「DJ」または「DJのあおい」）
+func (p Persona) DJ() string {
+	if p.Name == "" {
+		return "DJ"
+	}
+	return "DJの" + p.Name
+}
+
+// MonologuePrompt 台本生成のシステムプロンプト
+func (p Persona) MonologuePrompt() string {
+	p = p.withDefaults()
+	return "あなたは24時間AIラジオの" + p.DJ() + "です。" + p.Style + p.rules()
+}
+
+// DialogueInstructions Realtime APIでリスナーと対話する時の指示
+//
+// briefing は放送前のスクリーニングでまとめたリスナーの話したい内容（空なら挨拶だけで始める）。
+func (p Persona) DialogueInstructions(briefing string) string {
+	p = p.withDefaults()
+	opening := "対話モードが開始されたら、まずは「こんにちは！ラジオ24の" + p.DJ() + "です。何かお話ししたいことはありますか？」のような挨拶をしてください。"
+	if briefing != "" {
+		opening = "放送の前にスタッフがリスナーから次の話を聞いています: " + briefing +
+			"\n対話モードが開始されたら、挨拶のあとこの内容に触れて「〜というお話だそうですね」のようにリスナーに話を振ってください。"
+	}
+	return "あなたは24時間AIラジオの" + p.DJ() + "です。" + p.Style +
+		"リスナーとの対話では、ラジオDJらしく短く、親しみやすく、エンターテイメント性のある会話を心がけてください。" +
+		opening + p.rules()
+}
+
+// rules 口癖と避ける話題（どちらのモードでも共通）
+func (p Persona) rules() string {
+	var b strings.Builder
+	if len(p.Catchphrases) > 0 {
+		b.WriteString("\n口癖: 「" + strings.Join(p.Catchphrases, "」「") + "」（使いすぎず、自然なところで）")
+	}
+	if len(p.ForbiddenTopics) > 0 {
+		b.WriteString("\n次の話題には触れないでください。話題に上がったら別の話に切り替えてください: " + strings.Join(p.ForbiddenTopics, "、"))
+	}
+	return b.String()
+}
+
+// Config ペルソナの定義（PERSONAS_PATH のJSONファイル、または API の /v1/personas）
+type Config struct {
+	Default  string    `json:"default"`
+	Personas []Persona `json:"personas"`
+}
+
+// Source ペルソナの定義を読み込むもの
+type Source interface {
+	Load(ctx context.Context) (Config, error)
+}
+
+// FileSource JSONファイルから読み込む
+type FileSource struct {
+	Path string
+}
+
+func (s FileSource) Load(ctx context.Context) (Config, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read personas: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse personas: %w", err)
+	}
+	return cfg, nil
+}
+
+// APISource APIの /v1/personas（DBの persona テーブル）から読み込む
+type APISource struct {
+	BaseURL string
+	client  *http.Client
+}
+
+func NewAPISource(baseURL string) *APISource {
+	return &APISource{BaseURL: baseURL, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (s *APISource) Load(ctx context.Context) (Config, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.BaseURL+"/v1/personas", nil)
+	if err != nil {
+		return Config{}, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to load personas: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Config{}, fmt.Errorf("personas API returned status: %d", resp.StatusCode)
+	}
+	var cfg Config
+	if err := json.NewDecoder(resp.Body).Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to decode personas: %w", err)
+	}
+	return cfg, nil
+}
+
+// Set 読み込んだペルソナと、いま担当しているペルソナ
+type Set struct {
+	mu        sync.RWMutex
+	personas  map[string]Persona
+	defaultID string
+	current   Persona
+	partner   *Persona // 掛け合いの相手（いなければ nil）
+}
+
+func NewSet() *Set {
+	return &Set{personas: map[string]Persona{}, current: Default}
+}
+
+// Update 定義を入れ替える（担当中のペルソナも新しい定義に更新）
+func (s *Set) Update(cfg Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.personas = make(map[string]Persona, len(cfg.Personas))
+	for _, p := range cfg.Personas {
+		s.personas[p.ID] = p.withDefaults()
+	}
+	s.defaultID = cfg.Default
+	if p, ok := s.personas[s.current.ID]; ok {
+		s.current = p
+	}
+	if s.partner != nil {
+		if p, ok := s.personas[s.partner.ID]; ok {
+			s.partner = &p
+		}
+	}
+}
+
+// Select 担当を切り替える（空なら既定、未定義なら既定にして false）
+func (s *Set) Select(id string) (Persona, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := true
+	if id == "" {
+		id = s.defaultID
+	}
+	p, ok := s.personas[id]
+	if !ok {
+		found = id == "" || id == s.defaultID
+		p, ok = s.personas[s.defaultID]
+		if !ok {
+			p = Default
+		}
+	}
+	s.current = p
+	return p, found
+}
+
+// SelectPartner 掛け合いの相手を設定（空なら1人で喋る。未定義・担当と同じなら false）
+func (s *Set) SelectPartner(id string) (Persona, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.partner = nil
+	if id == "" {
+		return Persona{}, true
+	}
+	p, ok := s.personas[id]
+	if !ok || p.ID == s.current.ID {
+		return Persona{}, false
+	}
+	s.partner = &p
+	return p, true
+}
+
+// Partner 掛け合いの相手
+func (s *Set) Partner() (Persona, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.partner == nil {
+		return Persona{}, false
+	}
+	return *s.partner, true
+}
+
+// Cast 担当と掛け合いの相手をまとめて取得（id から引けるマップ）
+func (s *Set) Cast() map[string]Persona {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cast := map[string]Persona{s.current.ID: s.current}
+	if s.partner != nil {
+		cast[s.partner.ID] = *s.partner
+	}
+	return cast
+}
+
+// Current 担当中のペルソナ
+func (s *Set) Current() Persona {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
@@ -0,0 +1,113 @@
+package persona
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultPromptMatchesBuiltInDJ(t *testing.T) {
+	want := "あなたは24時間AIラジオのDJです。自然で親しみやすい口調で、リスナーとの距離感を大切にしてください。"
+	if got := Default.MonologuePrompt(); got != want {
+		t.Errorf("MonologuePrompt() = %q, want %q", got, want)
+	}
+}
+
+func TestPromptsIncludePersonaRules(t *testing.T) {
+	p := Persona{
+		Name:            "あおい",
+		Style:           "落ち着いた語り口で話してください。",
+		Catchphrases:    []string{"それではまた", "いい夜を"},
+		ForbiddenTopics: []string{"政治", "宗教"},
+	}
+
+	for name, prompt := range map[string]string{
+		"monologue": p.MonologuePrompt(),
+		"dialogue":  p.DialogueInstructions(""),
+	} {
+		for _, want := range []string{"DJのあおい", "落ち着いた語り口", "「それではまた」「いい夜を」", "政治、宗教"} {
+			if !strings.Contains(prompt, want) {
+				t.Errorf("%s prompt %q does not contain %q", name, prompt, want)
+			}
+		}
+	}
+}
+
+func TestDialogueInstructionsOpenWithBriefing(t *testing.T) {
+	briefing := "週末のライブの感想を話したい"
+	if got := Default.DialogueInstructions(briefing); !strings.Contains(got, briefing) || strings.Contains(got, "何かお話ししたいことはありますか") {
+		t.Errorf("DialogueInstructions(briefing) = %q", got)
+	}
+	if got := Default.DialogueInstructions(""); !strings.Contains(got, "何かお話ししたいことはありますか") {
+		t.Errorf("DialogueInstructions(\"\") = %q", got)
+	}
+}
+
+func TestSetSelect(t *testing.T) {
+	s := NewSet()
+	if p := s.Current(); p.TTSVoice != "nova" {
+		t.Fatalf("initial persona = %+v, want built-in default", p)
+	}
+
+	s.Update(Config{
+		Default: "aoi",
+		Personas: []Persona{
+			{ID: "aoi", Name: "あおい", TTSVoice: "shimmer"},
+			{ID: "ken", Name: "けん", TTSVoice: "onyx", RealtimeVoice: "cedar"},
+		},
+	})
+
+	if p, ok := s.Select("ken"); !ok || p.TTSVoice != "onyx" || p.RealtimeVoice != "cedar" {
+		t.Errorf("Select(ken) = %+v, %v", p, ok)
+	}
+	if p, ok := s.Select(""); !ok || p.ID != "aoi" || p.RealtimeVoice != "marin" {
+		t.Errorf("Select(\"\") = %+v, %v, want default with filled voices", p, ok)
+	}
+	if p, ok := s.Select("unknown"); ok || p.ID != "aoi" {
+		t.Errorf("Select(unknown) = %+v, %v, want default and false", p, ok)
+	}
+	if s.Current().ID != "aoi" {
+		t.Errorf("current = %s, want aoi", s.Current().ID)
+	}
+}
+
+func TestSetSelectPartner(t *testing.T) {
+	s := NewSet()
+	s.Update(Config{Personas: []Persona{{ID: "aoi", Name: "あおい"}, {ID: "ken", Name: "けん"}}})
+	s.Select("aoi")
+
+	if p, ok := s.SelectPartner("ken"); !ok || p.ID != "ken" {
+		t.Fatalf("SelectPartner(ken) = %+v, %v", p, ok)
+	}
+	if cast := s.Cast(); len(cast) != 2 || cast["ken"].Name != "けん" {
+		t.Errorf("cast = %+v", cast)
+	}
+
+	if _, ok := s.SelectPartner("aoi"); ok {
+		t.Error("lead cannot be their own partner")
+	}
+	if _, ok := s.Partner(); ok {
+		t.Error("partner should be cleared")
+	}
+	if _, ok := s.SelectPartner(""); !ok {
+		t.Error("clearing the partner should succeed")
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "personas.json")
+	data := `{"default":"aoi","personas":[{"id":"aoi","name":"あおい","catchphrases":["やっほー"]}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := FileSource{Path: path}.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if cfg.Default != "aoi" || len(cfg.Personas) != 1 || cfg.Personas[0].Catchphrases[0] != "やっほー" {
+		t.Errorf("config = %+v", cfg)
+	}
+}
@@ -0,0 +1,137 @@
+package speech
+
+import (
+	"context"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/radio24/pkg/llm"
+)
+
+// 文の長さの目安（文字数）
+const (
+	minSentence = 8   // これより短い文は次の文とまとめる
+	maxSentence = 100 // これより長い文は読点で区切る
+)
+
+// Split 読み上げ用に文単位で分割
+//
+// 句点・感嘆符・疑問符・改行で区切り、閉じ括弧は直前の文に含める。
+// 短すぎる文は次の文とまとめ、長すぎる文は読点（なければ文字数）で区切る。
+// 最初の文が短いほど早く喋り始められる。
+func Split(text string) []string {
+	var sentences []string
+	var cur strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			sentences = append(sentences, splitLong(s)...)
+		}
+		cur.Reset()
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '\n' {
+			flush()
+			continue
+		}
+		cur.WriteRune(r)
+		if !isTerminator(r) {
+			continue
+		}
+		for i+1 < len(runes) && (isTerminator(runes[i+1]) || isClosing(runes[i+1])) {
+			i++
+			cur.WriteRune(runes[i])
+		}
+		flush()
+	}
+	flush()
+
+	return mergeShort(sentences)
+}
+
+func isTerminator(r rune) bool {
+	return strings.ContainsRune("。．！？!?…", r)
+}
+
+func isClosing(r rune) bool {
+	return strings.ContainsRune("」』）)】\"'", r)
+}
+
+// splitLong 長すぎる文を読点の後ろで区切る
+func splitLong(s string) []string {
+	var parts []string
+	for utf8.RuneCountInString(s) > maxSentence {
+		runes := []rune(s)
+		cut := maxSentence
+		for i := maxSentence - 1; i >= minSentence; i-- {
+			if runes[i] == '、' || runes[i] == ',' {
+				cut = i + 1
+				break
+			}
+		}
+		parts = append(parts, string(runes[:cut]))
+		s = strings.TrimSpace(string(runes[cut:]))
+	}
+	if s != "" {
+		parts = append(parts, s)
+	}
+	return parts
+}
+
+// mergeShort 短すぎる文を次の文とつなげる（最後の文は前の文へ）
+func mergeShort(sentences []string) []string {
+	var merged []string
+	var pending string
+	for _, s := range sentences {
+		s = pending + s
+		if utf8.RuneCountInString(s) < minSentence {
+			pending = s
+			continue
+		}
+		merged = append(merged, s)
+		pending = ""
+	}
+	if pending != "" {
+		if len(merged) == 0 {
+			return []string{pending}
+		}
+		merged[len(merged)-1] += pending
+	}
+	return merged
+}
+
+// Stream 文ごとに合成し、届いたPCMから順に w に書き込む
+//
+// w にはサンプル（2バイト）単位にそろえて書き込むので、Player などにそのまま渡せる。
+func Stream(ctx context.Context, tts llm.TTS, req llm.SpeechRequest, w io.Writer) error {
+	aligned := &sampleWriter{w: w}
+	for _, sentence := range Split(req.Text) {
+		req.Text = sentence
+		if err := tts.SynthesizeStream(ctx, req, aligned); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sampleWriter 奇数バイトのチャンクの端数を次の書き込みに回す
+type sampleWriter struct {
+	w     io.Writer
+	carry []byte
+}
+
+func (s *sampleWriter) Write(p []byte) (int, error) {
+	buf := append(s.carry, p...)
+	n := len(buf) &^ 1
+	s.carry = append([]byte(nil), buf[n:]...)
+	if n == 0 {
+		return len(p), nil
+	}
+	if _, err := s.w.Write(buf[:n]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,78 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/radio24/pkg/llm"
+)
+
+func TestSplit(t *testing.T) {
+	got := Split("こんばんは、ラジオ24です。今夜は「星空」の話をしましょう！\nはい。では早速いきましょうか？")
+	want := []string{
+		"こんばんは、ラジオ24です。",
+		"今夜は「星空」の話をしましょう！",
+		"はい。では早速いきましょうか？",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Split() = %q, want %q", got, want)
+	}
+}
+
+func TestSplitLongSentenceAtComma(t *testing.T) {
+	long := strings.Repeat("あ", 70) + "、" + strings.Repeat("い", 60) + "。"
+	got := Split(long)
+	if len(got) != 2 || got[0] != strings.Repeat("あ", 70)+"、" {
+		t.Errorf("Split() = %q", got)
+	}
+}
+
+// chunkyTTS 奇数バイトのチャンクで返すTTS
+type chunkyTTS struct {
+	llm.Fake
+	texts []string
+}
+
+func (c *chunkyTTS) SynthesizeStream(ctx context.Context, req llm.SpeechRequest, w io.Writer) error {
+	c.texts = append(c.texts, req.Text)
+	for _, chunk := range [][]byte{{1}, {2, 3}, {4}} {
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestStreamWritesWholeSamplesPerSentence(t *testing.T) {
+	tts := &chunkyTTS{}
+	var writes [][]byte
+	w := writerFunc(func(p []byte) (int, error) {
+		writes = append(writes, append([]byte(nil), p...))
+		return len(p), nil
+	})
+
+	if err := Stream(context.Background(), tts, llm.SpeechRequest{Text: "今日はいい天気ですね。明日も晴れるそうです。"}, w); err != nil {
+		t.Fatalf("Stream() = %v", err)
+	}
+	if len(tts.texts) != 2 {
+		t.Errorf("synthesized %q, want 2 sentences", tts.texts)
+	}
+	var all []byte
+	for _, p := range writes {
+		if len(p)%2 != 0 {
+			t.Errorf("write of %d bytes is not sample aligned", len(p))
+		}
+		all = append(all, p...)
+	}
+	if !bytes.Equal(all, []byte{1, 2, 3, 4, 1, 2, 3, 4}) {
+		t.Errorf("pcm = %v", all)
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
@@ -0,0 +1,279 @@
+package ttscache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/radio24/pkg/llm"
+)
+
+// ext キャッシュファイルの拡張子（24kHz mono s16 のPCM）
+const ext = ".pcm"
+
+// tmpPrefix 書き込み中の一時ファイルの接頭辞
+const tmpPrefix = "tmp-"
+
+// Key 合成結果を引くキー（テキスト・声・モデル・速度のハッシュ）
+func Key(model string, req llm.SpeechRequest) string {
+	speed := req.Speed
+	if speed <= 0 {
+		speed = 1.0
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%.2f\x00%s", model, req.Voice, speed, req.Text)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Stats キャッシュの状態（/tts/cache 用）
+type Stats struct {
+	Entries  int   `json:"entries"`
+	Pinned   int   `json:"pinned"` // 消さずに残す定型文のうちキャッシュにあるもの
+	Bytes    int64 `json:"bytes"`
+	MaxBytes int64 `json:"max_bytes"`
+	Hits     int64 `json:"hits"`
+	Misses   int64 `json:"misses"`
+}
+
+type entry struct {
+	size int64
+	used time.Time
+}
+
+// Cache レンダリング済みPCMのディスクキャッシュ
+//
+// 合計が maxBytes を超えたら、最後に使ってから時間の経ったものから消す（LRU）。
+// 使った時刻はファイルの mtime にも残すので、再起動後も順序を引き継ぐ。
+// Pin したキー（先に合成しておく定型文）は、めったに使わなくても消さない。
+type Cache struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	entries  map[string]*entry
+	pinned   map[string]bool
+	total    int64
+	hits     int64
+	misses   int64
+}
+
+// Open dir のキャッシュを開く（既存のファイルを読み込み、上限を超えていれば消す）
+//
+// 前回書きかけのまま残った一時ファイルは消す。
+func Open(dir string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create tts cache dir: %w", err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tts cache dir: %w", err)
+	}
+
+	c := &Cache{dir: dir, maxBytes: maxBytes, entries: map[string]*entry{}, pinned: map[string]bool{}}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		if strings.HasPrefix(f.Name(), tmpPrefix) {
+			os.Remove(filepath.Join(dir, f.Name()))
+			continue
+		}
+		if !strings.HasSuffix(f.Name(), ext) {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		c.entries[strings.TrimSuffix(f.Name(), ext)] = &entry{size: info.Size(), used: info.ModTime()}
+		c.total += info.Size()
+	}
+
+	c.mu.Lock()
+	c.evictLocked()
+	c.mu.Unlock()
+	return c, nil
+}
+
+// Get キャッシュ済みのPCM
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	pcm, err := os.ReadFile(c.path(key))
+	if err != nil {
+		// 外から消された場合など
+		c.removeLocked(key)
+		c.misses++
+		return nil, false
+	}
+
+	c.hits++
+	e.used = time.Now()
+	os.Chtimes(c.path(key), e.used, e.used)
+	return pcm, true
+}
+
+// Put PCMを保存し、上限を超えた分を古いものから消す
+func (c *Cache) Put(key string, pcm []byte) error {
+	if len(pcm) == 0 || int64(len(pcm)) > c.maxBytes {
+		return nil
+	}
+
+	// 書きかけのファイルを読まないよう、一時ファイルに書いてから置き換える
+	tmp, err := os.CreateTemp(c.dir, tmpPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to create tts cache file: %w", err)
+	}
+	_, err = tmp.Write(pcm)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write tts cache file: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.entries[key]; ok {
+		c.total -= old.size
+	}
+	c.entries[key] = &entry{size: int64(len(pcm)), used: time.Now()}
+	c.total += int64(len(pcm))
+	c.evictLocked()
+	return nil
+}
+
+// Pin keys を消さずに残す（前に Pin したキーは外れ、普通のLRUに戻る）
+//
+// 残す分が上限を超えても、消すのはそれ以外のものだけ。
+func (c *Cache) Pin(keys []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pinned = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		c.pinned[key] = true
+	}
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pinned := 0
+	for key := range c.pinned {
+		if _, ok := c.entries[key]; ok {
+			pinned++
+		}
+	}
+	return Stats{
+		Entries:  len(c.entries),
+		Pinned:   pinned,
+		Bytes:    c.total,
+		MaxBytes: c.maxBytes,
+		Hits:     c.hits,
+		Misses:   c.misses,
+	}
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+ext)
+}
+
+// evictLocked 合計が上限に収まるまで使われていないものから消す（Pin したものは残す）
+func (c *Cache) evictLocked() {
+	if c.total <= c.maxBytes {
+		return
+	}
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		if !c.pinned[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].used.Before(c.entries[keys[j]].used)
+	})
+
+	evicted := 0
+	for _, key := range keys {
+		if c.total <= c.maxBytes {
+			break
+		}
+		c.removeLocked(key)
+		evicted++
+	}
+	log.Printf("TTS cache: evicted %d entries (%d bytes cached)", evicted, c.total)
+}
+
+func (c *Cache) removeLocked(key string) {
+	if e, ok := c.entries[key]; ok {
+		c.total -= e.size
+		delete(c.entries, key)
+	}
+	os.Remove(c.path(key))
+}
+
+// TTS キャッシュを挟んだ llm.TTS
+type TTS struct {
+	next  llm.TTS
+	model string
+	cache *Cache
+}
+
+// Wrap next の合成結果を cache に残す（model はキーに含めるTTSのモデル名）
+func Wrap(next llm.TTS, model string, cache *Cache) *TTS {
+	return &TTS{next: next, model: model, cache: cache}
+}
+
+func (t *TTS) Synthesize(ctx context.Context, req llm.SpeechRequest) ([]byte, error) {
+	key := Key(t.model, req)
+	if pcm, ok := t.cache.Get(key); ok {
+		return pcm, nil
+	}
+	pcm, err := t.next.Synthesize(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	t.put(key, pcm)
+	return pcm, nil
+}
+
+// SynthesizeStream キャッシュにあればまとめて書き込み、なければ流しながら保存する
+func (t *TTS) SynthesizeStream(ctx context.Context, req llm.SpeechRequest, w io.Writer) error {
+	key := Key(t.model, req)
+	if pcm, ok := t.cache.Get(key); ok {
+		_, err := w.Write(pcm)
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := t.next.SynthesizeStream(ctx, req, io.MultiWriter(w, &buf)); err != nil {
+		// 途中で切れた音声は残さない
+		return err
+	}
+	t.put(key, buf.Bytes())
+	return nil
+}
+
+func (t *TTS) put(key string, pcm []byte) {
+	if err := t.cache.Put(key, pcm); err != nil {
+		log.Printf("TTS cache: %v", err)
+	}
+}
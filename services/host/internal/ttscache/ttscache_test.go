@@ -0,0 +1,160 @@
+package ttscache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/radio24/pkg/llm"
+)
+
+// countingTTS 合成した回数を数えるTTS（fail が立っていれば途中で失敗する）
+type countingTTS struct {
+	llm.Fake
+	calls int
+	fail  bool
+}
+
+func (c *countingTTS) SynthesizeStream(ctx context.Context, req llm.SpeechRequest, w io.Writer) error {
+	c.calls++
+	w.Write([]byte(req.Voice + ":" + req.Text))
+	if c.fail {
+		return errors.New("connection reset")
+	}
+	return nil
+}
+
+func TestKeyDependsOnTextVoiceModelAndSpeed(t *testing.T) {
+	base := llm.SpeechRequest{Text: "こんにちは", Voice: "alloy"}
+	key := Key("tts-1", base)
+
+	if Key("tts-1", llm.SpeechRequest{Text: "こんにちは", Voice: "alloy", Speed: 1.0}) != key {
+		t.Error("default speed should match speed 1.0")
+	}
+	for name, other := range map[string]string{
+		"text":  Key("tts-1", llm.SpeechRequest{Text: "こんばんは", Voice: "alloy"}),
+		"voice": Key("tts-1", llm.SpeechRequest{Text: "こんにちは", Voice: "nova"}),
+		"model": Key("tts-1-hd", base),
+		"speed": Key("tts-1", llm.SpeechRequest{Text: "こんにちは", Voice: "alloy", Speed: 1.2}),
+	} {
+		if other == key {
+			t.Errorf("key does not change with %s", name)
+		}
+	}
+}
+
+func TestWrapServesRepeatsFromCache(t *testing.T) {
+	cache, err := Open(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := &countingTTS{}
+	tts := Wrap(next, "tts-1", cache)
+	req := llm.SpeechRequest{Text: "ラジオ24です。", Voice: "alloy"}
+
+	var first, second bytes.Buffer
+	tts.SynthesizeStream(context.Background(), req, &first)
+	tts.SynthesizeStream(context.Background(), req, &second)
+
+	if next.calls != 1 {
+		t.Errorf("synthesized %d times, want 1", next.calls)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("cached pcm = %q, want %q", second.Bytes(), first.Bytes())
+	}
+	if s := cache.Stats(); s.Hits != 1 || s.Misses != 1 || s.Entries != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestWrapDoesNotCacheFailedStream(t *testing.T) {
+	cache, _ := Open(t.TempDir(), 1<<20)
+	next := &countingTTS{fail: true}
+	tts := Wrap(next, "tts-1", cache)
+	req := llm.SpeechRequest{Text: "途中で切れる", Voice: "alloy"}
+
+	if err := tts.SynthesizeStream(context.Background(), req, io.Discard); err == nil {
+		t.Fatal("expected error")
+	}
+	if s := cache.Stats(); s.Entries != 0 {
+		t.Errorf("partial speech was cached: %+v", s)
+	}
+}
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, _ := Open(t.TempDir(), 10)
+	cache.Put("a", []byte("aaaa"))
+	cache.Put("b", []byte("bbbb"))
+	time.Sleep(10 * time.Millisecond)
+	cache.Get("a") // b より a を後に使う
+	cache.Put("c", []byte("cccc"))
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("least recently used entry was kept")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Error("recently used entry was evicted")
+	}
+	if s := cache.Stats(); s.Bytes > 10 {
+		t.Errorf("bytes = %d, want <= 10", s.Bytes)
+	}
+}
+
+func TestOpenReloadsExistingEntries(t *testing.T) {
+	dir := t.TempDir()
+	cache, _ := Open(dir, 1<<20)
+	cache.Put("station-id", []byte{1, 2, 3, 4})
+
+	reopened, err := Open(dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pcm, ok := reopened.Get("station-id"); !ok || !bytes.Equal(pcm, []byte{1, 2, 3, 4}) {
+		t.Errorf("Get() = %v, %v", pcm, ok)
+	}
+}
+
+func TestPinnedEntriesSurviveEviction(t *testing.T) {
+	cache, _ := Open(t.TempDir(), 10)
+	cache.Pin([]string{"time-signal"})
+	cache.Put("time-signal", []byte("pppp"))
+	for _, key := range []string{"a", "b", "c"} {
+		time.Sleep(time.Millisecond)
+		cache.Put(key, []byte("xxxx"))
+	}
+
+	if _, ok := cache.Get("time-signal"); !ok {
+		t.Error("pinned entry was evicted by one-off lines")
+	}
+	if s := cache.Stats(); s.Bytes > 10 || s.Pinned != 1 {
+		t.Errorf("stats = %+v, want pinned entry kept within the limit", s)
+	}
+
+	// Pin し直すと外れたものは普通に消える
+	cache.Pin(nil)
+	cache.Put("d", []byte("xxxx"))
+	cache.Put("e", []byte("xxxx"))
+	if _, ok := cache.Get("time-signal"); ok {
+		t.Error("unpinned entry was kept")
+	}
+}
+
+func TestOpenRemovesStaleTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "tmp-123")
+	if err := os.WriteFile(stale, []byte("half"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(dir, 1<<20); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale temp file was left: %v", err)
+	}
+}
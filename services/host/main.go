@@ -9,123 +9,436 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/gorilla/websocket"
 	"github.com/joho/godotenv"
 	"github.com/livekit/media-sdk"
 	"github.com/livekit/protocol/auth"
 	lksdk "github.com/livekit/server-sdk-go/v2"
 	lkmedia "github.com/livekit/server-sdk-go/v2/pkg/media"
+	"github.com/radio24/host/internal/banter"
+	"github.com/radio24/host/internal/director"
+	"github.com/radio24/host/internal/lookahead"
+	"github.com/radio24/host/internal/persona"
+	"github.com/radio24/host/internal/speech"
+	"github.com/radio24/host/internal/ttscache"
+	"github.com/radio24/pkg/audio"
+	"github.com/radio24/pkg/callerlink"
+	"github.com/radio24/pkg/dialogue"
+	"github.com/radio24/pkg/llm"
+	"github.com/radio24/pkg/mixer"
 )
 
 type HostAgent struct {
-	openaiConn       *websocket.Conn
 	room             *lksdk.Room
 	pcmTrack         *lkmedia.PCMLocalTrack
-	pcmWriter        *PCMWriter
+	player           *audio.Player
 	reconnectTimer   *time.Timer
 	ctx              context.Context
 	cancel           context.CancelFunc
-	currentPrompt    string
-	scriptTopics     []string
-	currentTopic     int
-	dialogueMode     bool
-	dialogueConn     *websocket.Conn
+	currentPrompt    string // 現在の枠の進行用ガイダンス（Directorが設定）
+	promptMutex      sync.RWMutex
+	director         *director.Director
+	personas         *persona.Set       // DJのペルソナ（枠ごとに切り替え）
+	personaSource    persona.Source     // ペルソナ定義の読み込み元（ファイルまたはAPI）
+	lookahead        *lookahead.Buffer  // 次に喋る台本の先読み（生成＋TTS済みPCM）
+	speechDone       chan struct{}      // DJの発話を再生し終えた通知
+	dialogue         *dialogue.Machine  // 通話の状態（APIと遷移を送りあって揃える）
+	callerLink       *callerlink.Server // APIからの常時接続のストリーム（Hostで起きた遷移もこれで返す）
+	dialogueConn     llm.RealtimeSession
+	llm              llm.Provider    // チャット・TTS・Realtime（OpenAI、テストモードでは Fake）
+	ttsCache         *ttscache.Cache // 合成済み音声のキャッシュ（無効なら nil）
+	breaker          *llm.Breaker    // 上流のAIの障害検知（open の間は自動運行）
+	upstreamChanged  chan struct{}   // breaker の状態が変わった通知
+	automationBed    bool            // 自動運行のために音楽ベッドを流している
 	audioPublication *lksdk.LocalTrackPublication
-	// ユーザー音声用のトラック
-	userPcmTrack         *lkmedia.PCMLocalTrack
-	userPcmWriter        *PCMWriter
-	userAudioPublication *lksdk.LocalTrackPublication
+	// 番組出力のミキサー（DJ・通話・音楽・効果音の各バスを1本のトラックにまとめる）
+	mixer      *mixer.Mixer
+	userPlayer *audio.Player // 通話音声（callerバス）
+	sweeper    []int16       // セグメント間のスイーパー音源
+	bed        []int16       // MUSIC枠の音楽ベッド
+	bedPlaying bool
+	// 時報（音は Host の時計で正時の前から鳴らし、アナウンスは鳴り終わってから）
+	timeSignalMutex sync.Mutex
+	timeSignalEnd   time.Time
+	// 行ごとの字幕の予約（DJの音声を破棄したら世代を進め、流れなかった行の字幕は送らない）
+	subtitleMutex sync.Mutex
+	subtitleGen   uint64
+	// APIへ送る通話の状態遷移（起きた順に送り、届くまで捨てない）
+	dialogueEventsMutex sync.Mutex
+	dialogueEvents      []dialogue.Transition
+	dialogueEventsReady chan struct{}
+	// 通話中の送出ディレイ（番組出力を遅らせ、放送事故はダンプで捨てる）
+	delay          *audio.Delay
+	broadcastDelay time.Duration // BROADCAST_DELAY（0なら無効）
 	// タイマーリセット用チャンネル
 	timerResetChan chan struct{}
-	// 状態管理用
+	// 状態管理用（通話の開始・終了処理を1つずつ行う）
 	dialogueStateMutex sync.RWMutex
-	dialogueStartedAt  time.Time
-	lastActivity       time.Time
-	// 対話モードタイムアウト用
-	dialogueTimeoutChan chan struct{}
+	dialogueTranscript []string // 通話中のDJの応答（終了時に記憶として保存）
+	dialogueBriefing   string   // 放送前のスクリーニングでまとめた通話者の話したい内容（DJの最初の振りに使う）
+	// 音声フォーマット
+	outputFormat      audio.Format // LiveKitへ送出するフォーマット
+	callerInputMutex  sync.Mutex
+	callerInput       *audio.Converter // 発信者音声 → Realtime入力フォーマット
+	callerInputFormat audio.Format
 }
 
-type PCMWriter struct {
-	buf      []byte
-	pcmTrack *lkmedia.PCMLocalTrack
-	mu       sync.Mutex
+// transitionFade 対話モードの切り替えなどで使うフェード時間
+const transitionFade = 500 * time.Millisecond
+
+// speechRate 台本の長さを見積もるための読み上げの速さ（文字/秒）
+const speechRate = 6
+
+// minClosing これより短い時間しか残っていなければクロージングは喋らない
+const minClosing = 5 * time.Second
+
+// lookaheadMaxAge 先読みした台本の有効期限（放送の記憶やリスナーの投稿が古くなるため）
+const lookaheadMaxAge = 3 * time.Minute
+
+// newBusPlayer ミキサーのバスに接続する再生エンジンを作成（クロックはミキサー側）
+func newBusPlayer(format audio.Format) *audio.Player {
+	cfg := audio.DefaultPlayerConfig()
+	cfg.Format = format
+	return audio.NewPlayer(nil, cfg)
 }
 
-const frameBytes = 960 // 20ms @ 24kHz mono 16-bit
+// setupProgramMixer 番組出力のバスを構成
+func (h *HostAgent) setupProgramMixer() {
+	h.mixer = mixer.NewMixer()
+	h.player = newBusPlayer(h.outputFormat)
+	h.userPlayer = newBusPlayer(h.outputFormat)
+	h.sweeper = loadClip("SWEEPER_WAV_PATH", h.outputFormat, func() []int16 {
+		return audio.Sweep(h.outputFormat, 600*time.Millisecond)
+	})
+	h.bed = loadClip("MUSIC_BED_WAV_PATH", h.outputFormat, func() []int16 {
+		return audio.Pad(h.outputFormat, 8*time.Second)
+	})
+
+	h.broadcastDelay = loadBroadcastDelay()
+	h.delay = audio.NewDelay(audio.SinkFunc(h.writeProgram), audio.DefaultPlayerConfig().FrameDuration)
+
+	h.mixer.AddBus(mixer.BusVoice, h.player, false)
+	h.mixer.AddBus(mixer.BusCaller, h.userPlayer, false)
+	h.mixer.AddBus(mixer.BusMusic, nil, true)
+	h.mixer.AddBus(mixer.BusFX, nil, false)
 
-func NewPCMWriter(t *lkmedia.PCMLocalTrack) *PCMWriter {
-	return &PCMWriter{pcmTrack: t}
+	// 音楽ベッドの上で喋る時はダッキングし、喋り終わったら戻す
+	h.player.SetOnIdle(func() {
+		h.mixer.DuckOff()
+		select {
+		case h.speechDone <- struct{}{}:
+		default:
+		}
+	})
+
+	h.mixer.SetOnDuckChange(func(ducked bool) {
+		event := "MIXER_DUCK_OFF"
+		if ducked {
+			event = "MIXER_DUCK_ON"
+		}
+		go h.broadcastMixerState(event)
+	})
 }
 
-func (w *PCMWriter) WriteB64Delta(b64 string) error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
+// broadcastMixerState ミキサーの状態を mixer_state として配信
+func (h *HostAgent) broadcastMixerState(event string) {
+	h.sendBroadcast("mixer_state", map[string]interface{}{
+		"event": event,
+		"mixer": h.mixer.Snapshot(),
+	})
+}
 
-	log.Printf("WriteB64Delta called with base64 length: %d", len(b64))
+// loadClip 環境変数で指定されたWAVを読み込む（未設定・失敗時は fallback で合成）
+func loadClip(envKey string, format audio.Format, fallback func() []int16) []int16 {
+	path := getEnv(envKey, "")
+	if path == "" {
+		return fallback()
+	}
 
-	raw, err := base64.StdEncoding.DecodeString(b64)
+	f, err := os.Open(path)
 	if err != nil {
-		log.Printf("Failed to decode base64 audio data: %v", err)
-		return err
+		log.Printf("Failed to open %s=%s: %v", envKey, path, err)
+		return fallback()
 	}
+	defer f.Close()
 
-	log.Printf("Decoded audio data: %d bytes", len(raw))
-	w.buf = append(w.buf, raw...)
-	log.Printf("Buffer now contains: %d bytes", len(w.buf))
-
-	framesWritten := 0
-	for len(w.buf) >= frameBytes {
-		frame := w.buf[:frameBytes]
-		w.buf = w.buf[frameBytes:]
+	wavFormat, samples, err := audio.DecodeWAV(f)
+	if err != nil {
+		log.Printf("Failed to decode %s=%s: %v", envKey, path, err)
+		return fallback()
+	}
+	log.Printf("Loaded %s (%s)", path, wavFormat)
+	return audio.NewConverter(wavFormat, format).Convert(samples)
+}
 
-		// 音量を下げる（-6dB）
-		attenuateInPlace(frame, 0.5)
+// InsertSweeper セグメント間にスイーパーを挿入
+func (h *HostAgent) InsertSweeper() {
+	clip := audio.NewClip(h.sweeper, h.outputFormat, audio.DefaultPlayerConfig().FrameDuration, false)
+	if err := h.mixer.Play(mixer.BusFX, clip, 0); err != nil {
+		log.Printf("Failed to insert sweeper: %v", err)
+	}
+}
 
-		// PCM16データをPCMLocalTrackに送信
-		// frameは[]byteなので、[]int16に変換
-		pcm16Data := make(media.PCM16Sample, len(frame)/2)
-		for i := 0; i < len(frame); i += 2 {
-			pcm16Data[i/2] = int16(int(frame[i]) | int(frame[i+1])<<8)
+// runTimeSignal 毎正時に時報を鳴らす（正報音が正時ちょうどになるよう TimeSignalLead 前から流す）
+func (h *HostAgent) runTimeSignal() {
+	for {
+		start := timeSignalStart(time.Now())
+		timer := time.NewTimer(time.Until(start))
+		select {
+		case <-h.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			h.playTimeSignalTones()
 		}
+	}
+}
 
-		if err := w.pcmTrack.WriteSample(pcm16Data); err != nil {
-			log.Printf("Failed to write PCM16 sample: %v", err)
-			return err
+// timeSignalStart now より後で、次に時報を鳴らし始める時刻
+func timeSignalStart(now time.Time) time.Time {
+	hour := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, now.Location())
+	for {
+		hour = hour.Add(time.Hour)
+		if start := hour.Add(-audio.TimeSignalLead); start.After(now) {
+			return start
 		}
-		framesWritten++
 	}
-	log.Printf("WriteB64Delta completed: wrote %d frames, %d bytes remaining in buffer", framesWritten, len(w.buf))
-	return nil
 }
 
-// ClearBuffer 音声バッファをクリア
-func (w *PCMWriter) ClearBuffer() {
-	w.mu.Lock()
-	defer w.mu.Unlock()
+// playTimeSignalTones 時報の音を流す（アナウンスは TOP_OF_HOUR で鳴り終わった後に行う）
+func (h *HostAgent) playTimeSignalTones() {
+	if h.inDialogue() {
+		log.Println("Skipping time signal during dialogue mode")
+		return
+	}
+
+	signal := audio.TimeSignal(h.outputFormat)
+	clip := audio.NewClip(signal, h.outputFormat, audio.DefaultPlayerConfig().FrameDuration, false)
+	if err := h.mixer.Play(mixer.BusFX, clip, 0); err != nil {
+		log.Printf("Failed to play time signal: %v", err)
+		return
+	}
 
-	log.Printf("Clearing audio buffer (%d bytes)", len(w.buf))
-	w.buf = w.buf[:0]
+	h.timeSignalMutex.Lock()
+	h.timeSignalEnd = time.Now().Add(h.outputFormat.Duration(len(signal)))
+	h.timeSignalMutex.Unlock()
 }
 
-func attenuateInPlace(b []byte, gain float64) {
-	// b は little-endian の int16 PCM
-	for i := 0; i+1 < len(b); i += 2 {
-		sample := int16(int(b[i]) | int(b[i+1])<<8)
-		v := float64(sample) * gain
-		if v > 32767 {
-			v = 32767
-		}
-		if v < -32768 {
-			v = -32768
+// playTimeSignal 正報音が鳴り終わるのを待って、時刻と次のテーマをアナウンス
+func (h *HostAgent) playTimeSignal(hour int, theme string) {
+	if h.inDialogue() {
+		log.Println("Skipping time signal announcement during dialogue mode")
+		return
+	}
+
+	h.timeSignalMutex.Lock()
+	end := h.timeSignalEnd
+	h.timeSignalMutex.Unlock()
+	if wait := time.Until(end); wait > 0 {
+		time.Sleep(wait)
+	}
+	h.sendMessage(timeSignalText(hour, theme))
+}
+
+// timeSignalText 時報のアナウンス文
+func timeSignalText(hour int, theme string) string {
+	var text string
+	switch {
+	case hour == 12:
+		text = "正午をお知らせします。"
+	case hour < 12:
+		text = fmt.Sprintf("午前%d時をお知らせします。", hour)
+	default:
+		text = fmt.Sprintf("午後%d時をお知らせします。", hour-12)
+	}
+	if theme != "" {
+		text += fmt.Sprintf("ここからは「%s」の時間です。", theme)
+	}
+	return text
+}
+
+// StartBed MUSIC枠の音楽ベッドをフェードインで流す
+func (h *HostAgent) StartBed() {
+	clip := audio.NewClip(h.bed, h.outputFormat, audio.DefaultPlayerConfig().FrameDuration, true)
+	if err := h.mixer.Play(mixer.BusMusic, clip, 2*time.Second); err != nil {
+		log.Printf("Failed to start music bed: %v", err)
+		return
+	}
+	h.bedPlaying = true
+	h.automationBed = false
+}
+
+// StopBed 音楽ベッドをフェードアウトして止める
+func (h *HostAgent) StopBed() {
+	if err := h.mixer.StopBus(mixer.BusMusic, 2*time.Second); err != nil {
+		log.Printf("Failed to stop music bed: %v", err)
+	}
+	h.bedPlaying = false
+	h.automationBed = false
+}
+
+// SetPersona 枠を担当するDJと掛け合いの相手を切り替え
+// （定義は毎回読み直すのでDBの変更は次の枠から反映）
+func (h *HostAgent) SetPersona(id, coHost string) {
+	h.loadPersonas()
+
+	prev := h.personas.Current()
+	prevPartner, _ := h.personas.Partner()
+	dj, ok := h.personas.Select(id)
+	if !ok {
+		log.Printf("Persona %q not found, using %q", id, dj.ID)
+	}
+	partner, ok := h.personas.SelectPartner(coHost)
+	if !ok {
+		log.Printf("Co-host %q not available, %s talks alone", coHost, dj.ID)
+	}
+
+	if dj.ID != prev.ID || partner.ID != prevPartner.ID {
+		log.Printf("Persona switched: %s+%s -> %s+%s", prev.ID, prevPartner.ID, dj.ID, partner.ID)
+		h.lookahead.Invalidate("persona changed")
+	}
+	if dj.TTSVoice != prev.TTSVoice {
+		go h.prewarmTTS()
+	}
+}
+
+// loadPersonas ペルソナ定義を読み込む（失敗時はそれまでの定義を使い続ける）
+func (h *HostAgent) loadPersonas() {
+	ctx, cancel := context.WithTimeout(h.ctx, 5*time.Second)
+	defer cancel()
+
+	cfg, err := h.personaSource.Load(ctx)
+	if err != nil {
+		log.Printf("Failed to load personas, keeping current: %v", err)
+		return
+	}
+	h.personas.Update(cfg)
+}
+
+// SetPrompt 現在の枠の進行用ガイダンスを設定（枠が変わるので先読みした台本は捨てる）
+func (h *HostAgent) SetPrompt(prompt string) {
+	h.promptMutex.Lock()
+	h.currentPrompt = prompt
+	h.promptMutex.Unlock()
+
+	h.lookahead.Invalidate("segment changed")
+}
+
+func (h *HostAgent) segmentPrompt() string {
+	h.promptMutex.RLock()
+	defer h.promptMutex.RUnlock()
+	return h.currentPrompt
+}
+
+// Speak トピックについて台本を生成して読み上げ（Director用）
+func (h *HostAgent) Speak(topic string) {
+	h.generateAndSpeakScript(topic)
+}
+
+// Close セグメントのクロージングを終了までに喋り終わる長さで読み上げる
+//
+// まだ流れていない音声の分を差し引き、台本は読み上げの速さで収まるところまでに切り詰める。
+func (h *HostAgent) Close(topic string, within time.Duration) {
+	within -= h.player.Buffered()
+	if within < minClosing || h.degraded() {
+		log.Printf("Skipping closing for %s (%v left)", topic, within)
+		return
+	}
+	h.playScript(h.composeScript(h.ctx, topic, within))
+}
+
+// fadeOutAndFlush バスをフェードアウトしてから再生エンジンを空にし、フェーダーを戻す
+//
+// DJの声を破棄する場合は、流れずに終わる行の字幕の予約も取り消す。
+func (h *HostAgent) fadeOutAndFlush(busName string, player *audio.Player) {
+	if busName == mixer.BusVoice {
+		h.cancelSubtitles()
+	}
+	h.mixer.FadeOut(busName, transitionFade, func() {
+		player.Flush()
+		if busName == mixer.BusVoice {
+			h.cancelSubtitles() // フェード中に積まれた行の分
 		}
-		s := int16(v)
-		b[i] = byte(s)
-		b[i+1] = byte(uint16(s) >> 8)
+		h.mixer.FadeIn(busName, 0)
+	})
+}
+
+// writeProgram 番組出力をトラックへ書き込む（再接続時は新しいトラックに切り替わる）
+func (h *HostAgent) writeProgram(sample []int16) error {
+	track := h.pcmTrack
+	if track == nil {
+		return nil
+	}
+	return track.WriteSample(media.PCM16Sample(sample))
+}
+
+// loadBroadcastDelay 通話中の送出ディレイ（BROADCAST_DELAY、デフォルト7秒。0で無効）
+func loadBroadcastDelay() time.Duration {
+	d, err := time.ParseDuration(getEnv("BROADCAST_DELAY", "7s"))
+	if err != nil || d < 0 {
+		return 7 * time.Second
+	}
+	return d
+}
+
+// delayFiller ディレイを作る間・ダンプの後に流すつなぎ（音楽ベッドをループ）
+func (h *HostAgent) delayFiller() audio.Source {
+	return audio.NewClip(h.bed, h.outputFormat, audio.DefaultPlayerConfig().FrameDuration, true)
+}
+
+// dumpDelay ディレイの間にたまった音声と字幕を捨て、つなぎを流す
+//
+// 通話中でなく、ディレイもかけていなければ捨てない（生に戻る途中の締めの挨拶などを消さない）。
+func (h *HostAgent) dumpDelay() (time.Duration, bool) {
+	if !h.inDialogue() && h.delay.State().Target == 0 {
+		return 0, false
+	}
+	dumped := h.delay.Dump(h.delayFiller())
+	log.Printf("Dumped %v of delayed broadcast", dumped)
+	h.sendBroadcast("delay_dumped", map[string]interface{}{
+		"dumped_ms": dumped.Milliseconds(),
+	})
+	return dumped, true
+}
+
+// loadOutputFormat 送出フォーマットを環境変数から読み込み（既定は24kHz mono）
+func loadOutputFormat() audio.Format {
+	format := audio.FormatRealtime
+	if v, err := strconv.Atoi(getEnv("AUDIO_OUTPUT_SAMPLE_RATE", "")); err == nil {
+		format.SampleRate = v
+	}
+	if v, err := strconv.Atoi(getEnv("AUDIO_OUTPUT_CHANNELS", "")); err == nil {
+		format.Channels = v
 	}
+
+	if err := format.Validate(); err != nil {
+		log.Printf("Invalid audio output format (%v), falling back to %s", err, audio.FormatRealtime)
+		return audio.FormatRealtime
+	}
+	return format
+}
+
+// MixerUpdateRequest PUT /mixer のリクエスト（指定した項目のみ変更）
+type MixerUpdateRequest struct {
+	Ducked         *bool              `json:"ducked"`
+	DuckLevelDB    *float64           `json:"duck_level_db"`
+	DuckDurationMs *int64             `json:"duck_duration_ms"`
+	Buses          map[string]float64 `json:"buses"` // バス名 → ゲイン(dB)
+}
+
+// HostEvent APIから通知される内部イベント（EVENT.TOP_OF_HOUR など）
+type HostEvent struct {
+	Type  string `json:"type"`
+	Hour  int    `json:"hour"`
+	Block string `json:"block"`
+	Theme string `json:"theme"`
 }
 
 type ScriptRequest struct {
@@ -152,23 +465,35 @@ func main() {
 	defer cancel()
 
 	agent := &HostAgent{
-		ctx:    ctx,
-		cancel: cancel,
-		scriptTopics: []string{
-			"今日の天気予報",
-			"最新のニュース",
-			"音楽の話題",
-			"リスナーからのメッセージ",
-			"今日の出来事",
-			"季節の話題",
-			"テクノロジーの話題",
-			"エンターテイメント",
-		},
-		currentTopic:        0,
-		dialogueMode:        false,
-		timerResetChan:      make(chan struct{}, 10), // バッファを追加して複数の信号を処理可能にする
-		dialogueTimeoutChan: make(chan struct{}, 1),  // 対話モードタイムアウト用
+		ctx:             ctx,
+		cancel:          cancel,
+		timerResetChan:  make(chan struct{}, 10), // バッファを追加して複数の信号を処理可能にする
+		outputFormat:    loadOutputFormat(),
+		speechDone:      make(chan struct{}, 1),
+		upstreamChanged: make(chan struct{}, 1),
+	}
+	agent.setupUpstream(llm.FromEnv())
+	log.Printf("Audio output format: %s", agent.outputFormat)
+	agent.setupTTSCache()
+	agent.setupProgramMixer()
+	agent.personas = persona.NewSet()
+	if path := getEnv("PERSONAS_PATH", ""); path != "" {
+		agent.personaSource = persona.FileSource{Path: path}
+	} else {
+		agent.personaSource = persona.NewAPISource(getEnv("API_BASE", "http://api:8080"))
 	}
+	agent.loadPersonas()
+	agent.personas.Select("")
+	go agent.prewarmTTS()
+	agent.lookahead = lookahead.New(agent.renderScript, loadLookaheadDepth(), lookaheadMaxAge)
+	go agent.lookahead.Run(ctx)
+	agent.director = director.New(
+		director.NewAPISchedule(getEnv("API_BASE", "http://api:8080"), getEnv("CHANNEL_NAME", "Radio-24")),
+		agent,
+	)
+
+	agent.setupDialogue()
+	go agent.forwardDialogueTransitions()
 
 	// HTTPサーバーを起動（Cloud Run用）
 	agent.startHTTPServer()
@@ -185,6 +510,9 @@ func main() {
 	// キュー監視ループを開始
 	go agent.monitorQueue()
 
+	// 時報（正時の TimeSignalLead 前から鳴らす）
+	go agent.runTimeSignal()
+
 	// メインループ
 	agent.run()
 }
@@ -223,15 +551,15 @@ func (h *HostAgent) connectToLiveKit() error {
 		return fmt.Errorf("failed to connect to LiveKit room: %w", err)
 	}
 
-	// PCMオーディオトラックを作成（24kHz, mono）
+	// PCMオーディオトラックを作成（既定は24kHz, mono）
 	log.Println("Creating PCM audio track...")
-	h.pcmTrack, err = lkmedia.NewPCMLocalTrack(24000, 1, nil) // 24kHz, mono
+	h.pcmTrack, err = lkmedia.NewPCMLocalTrack(h.outputFormat.SampleRate, h.outputFormat.Channels, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create PCM audio track: %w", err)
 	}
 
-	// PCMWriterを初期化
-	h.pcmWriter = NewPCMWriter(h.pcmTrack)
+	// ミキサーの出力を送出ディレイ経由でトラックへ送出
+	h.mixer.Run(h.delay, h.outputFormat, audio.DefaultPlayerConfig().FrameDuration)
 
 	// トラックをルームに公開
 	log.Println("Publishing PCM audio track to room...")
@@ -246,37 +574,19 @@ func (h *HostAgent) connectToLiveKit() error {
 
 	// 接続確認のためのログ
 	log.Printf("LiveKit room state: connected=%v", h.room != nil)
-	log.Printf("PCM track state: track=%v, writer=%v", h.pcmTrack != nil, h.pcmWriter != nil)
+	log.Printf("PCM track state: track=%v, mixer=%v", h.pcmTrack != nil, h.mixer != nil)
 
 	return nil
 }
 
-// connectToOpenAIRealtime OpenAI Realtime API接続（対話モード用）
-func (h *HostAgent) connectToOpenAIRealtime() error {
-	apiKey := getEnv("OPENAI_API_KEY", "")
-	if apiKey != "" {
-		log.Printf("OpenAI API key: %s", apiKey[:10]+"...") // 最初の10文字だけ表示
-	}
-
-	if apiKey == "" || apiKey == "your-openai-api-key" || apiKey == "test-mode" {
-		log.Println("OpenAI API key not set, using test mode")
-		h.dialogueConn = nil // テストモード
-		return nil
-	}
-
-	log.Println("Attempting to connect to OpenAI Realtime API for dialogue...")
-
-	// OpenAI Realtime WebSocket接続
-	url := "wss://api.openai.com/v1/realtime?model=gpt-realtime"
-	headers := map[string][]string{
-		"Authorization": {fmt.Sprintf("Bearer %s", apiKey)},
-	}
+// connectRealtime Realtime APIに接続（対話モード用）
+func (h *HostAgent) connectRealtime() error {
+	log.Printf("Attempting to connect to %s Realtime API for dialogue...", h.llm.Name)
 
-	log.Printf("Connecting to: %s", url)
-	conn, _, err := websocket.DefaultDialer.Dial(url, headers)
+	conn, err := h.llm.Realtime.Connect(h.ctx)
 	if err != nil {
-		log.Printf("Failed to connect to OpenAI: %v, using test mode", err)
-		h.dialogueConn = nil // テストモードにフォールバック
+		log.Printf("Failed to connect to Realtime API: %v", err)
+		h.dialogueConn = nil
 		return nil
 	}
 
@@ -299,7 +609,7 @@ func (h *HostAgent) connectToOpenAIRealtime() error {
 	// メッセージ処理ループを開始
 	go h.handleDialogueMessages()
 
-	log.Println("Connected to OpenAI Realtime for dialogue")
+	log.Println("Connected to Realtime API for dialogue")
 	return nil
 }
 
@@ -309,11 +619,12 @@ func (h *HostAgent) setupDialogueSession() error {
 		return fmt.Errorf("dialogue connection not available")
 	}
 
+	dj := h.personas.Current()
 	sessionUpdate := map[string]interface{}{
 		"type": "session.update",
 		"session": map[string]interface{}{
 			"type":              "realtime",
-			"instructions":      "あなたは24時間AIラジオのDJです。リスナーとの対話では、自然で親しみやすい口調で、ラジオDJらしい応答をしてください。短く、親しみやすく、エンターテイメント性のある会話を心がけてください。対話モードが開始されたら、まずは「こんにちは！ラジオ24のDJです。何かお話ししたいことはありますか？」のような挨拶をしてください。",
+			"instructions":      dj.DialogueInstructions(h.dialogueBriefing),
 			"output_modalities": []string{"audio"},
 			"audio": map[string]interface{}{
 				"input": map[string]interface{}{
@@ -323,7 +634,7 @@ func (h *HostAgent) setupDialogueSession() error {
 					},
 				},
 				"output": map[string]interface{}{
-					"voice": "marin",
+					"voice": dj.RealtimeVoice,
 				},
 			},
 		},
@@ -437,7 +748,7 @@ func (h *HostAgent) handleDialogueMessages() {
 				log.Printf("Dialogue connection error: %v", err)
 				// 接続切断時に対話モードを終了
 				log.Println("OpenAI Realtime connection lost, ending dialogue mode")
-				h.endDialogueMode()
+				h.endDialogueMode(dialogue.ReasonConnectionLost)
 				return
 			}
 
@@ -452,13 +763,7 @@ func (h *HostAgent) handleDialogueMessages() {
 						h.publishAudioToLiveKit(audioData)
 
 						// 音声出力時もアクティビティを更新
-						h.dialogueStateMutex.Lock()
-						if h.dialogueMode {
-							h.lastActivity = time.Now()
-							// タイムアウトタイマーをリセット
-							h.resetDialogueTimeout()
-						}
-						h.dialogueStateMutex.Unlock()
+						h.touchDialogue()
 					}
 				case "response.content_part.done":
 					// 対話中のテキスト応答が完了した時に字幕として送信
@@ -466,6 +771,10 @@ func (h *HostAgent) handleDialogueMessages() {
 						if content, ok := contentData["text"].(string); ok && content != "" {
 							log.Printf("Received dialogue text content: %s", content)
 							h.sendSubtitle(content)
+
+							h.dialogueStateMutex.Lock()
+							h.dialogueTranscript = append(h.dialogueTranscript, content)
+							h.dialogueStateMutex.Unlock()
 						}
 					}
 				case "response.done":
@@ -505,120 +814,109 @@ func (h *HostAgent) run() {
 	// LiveKitメッセージハンドリングは不要（SDKが自動処理）
 
 	// 定期発話ループ（30秒ごと）
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(director.TickInterval)
 	defer ticker.Stop()
 
+	// セグメントのクロージングは定期の Tick を待たずに時刻ちょうどに始める
+	closing := time.NewTimer(h.untilClosing())
+	defer closing.Stop()
+
 	for {
 		select {
 		case <-h.ctx.Done():
 			return
 		case <-ticker.C:
-			// 対話モードでない場合のみ台本を生成して発話
-			if !h.dialogueMode {
-				h.generateAndSpeakScript()
+			// 対話モードでない場合のみ時間割に従って進行
+			if !h.inDialogue() {
+				h.director.Tick(h.ctx, time.Now())
+			}
+			closing.Reset(h.untilClosing())
+		case <-closing.C:
+			// 通話中はクロージングを飛ばし、通話が終わった後の Tick に任せる
+			if h.inDialogue() {
+				closing.Reset(30 * time.Second)
+				continue
 			}
+			h.director.Tick(h.ctx, time.Now())
+			closing.Reset(h.untilClosing())
+		case <-h.speechDone:
+			// 先読みした台本があれば間を空けずに次へ進む
+			if !h.inDialogue() && h.lookahead.Ready() {
+				h.director.Tick(h.ctx, time.Now())
+			}
+		case <-h.upstreamChanged:
+			h.applyUpstreamState()
 		case <-h.timerResetChan:
 			// LiveKitアップロード完了時にタイマーをリセット
 			log.Println("Resetting timer due to LiveKit upload completion")
 			ticker.Stop()
-			ticker = time.NewTicker(30 * time.Second)
+			ticker = time.NewTicker(director.TickInterval)
 		}
 	}
 }
 
-func (h *HostAgent) sendMessage(content string) {
-	apiKey := getEnv("OPENAI_API_KEY", "")
-	if apiKey == "" || apiKey == "your-openai-api-key" || apiKey == "test-mode" {
-		log.Printf("Test mode: Message would be sent: %s", content)
-		// テストモードでも字幕は送信
-		h.sendSubtitle(content)
-		return
+// untilClosing 次のクロージングまでの時間（まだ決まっていなければ次の Tick の後に決め直す）
+func (h *HostAgent) untilClosing() time.Duration {
+	at, ok := h.director.ClosingAt()
+	if !ok {
+		return time.Minute
 	}
-
-	log.Printf("Sending message to OpenAI TTS: %s", content)
-
-	// 字幕を先に送信
-	h.sendSubtitle(content)
-
-	// OpenAI TTS APIを使用して音声を生成
-	audioData, err := h.generateTTS(content, apiKey)
-	if err != nil {
-		log.Printf("Failed to generate TTS: %v", err)
-		return
+	if d := time.Until(at); d > 0 {
+		return d
 	}
-
-	// 生成された音声をLiveKitに送信
-	h.publishAudioToLiveKit(audioData)
-	log.Printf("TTS audio generated and published successfully")
+	return 0
 }
 
-// generateTTS OpenAI TTS APIを使用してテキストを音声に変換
-func (h *HostAgent) generateTTS(text, apiKey string) (string, error) {
-	url := "https://api.openai.com/v1/audio/speech"
-
-	requestBody := map[string]interface{}{
-		"model":           "tts-1",
-		"input":           text,
-		"voice":           "nova",
-		"response_format": "pcm",
-		"speed":           1.0,
-	}
-
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
+func (h *HostAgent) sendMessage(content string) {
+	log.Printf("Sending message to TTS: %s", content)
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("TTS API error: %d - %s", resp.StatusCode, string(body))
-	}
+	// 文ごとに合成しながら流す（字幕は音声が流れ始める時に送信）
+	h.speak(h.personas.Current(), content)
+	h.notifySpeechQueued()
+}
 
-	audioBytes, err := io.ReadAll(resp.Body)
+// generateTTS テキストを音声（24kHz mono PCM）に変換
+func (h *HostAgent) generateTTS(ctx context.Context, text, voice string) ([]byte, error) {
+	pcm, err := h.llm.TTS.Synthesize(ctx, llm.SpeechRequest{Text: text, Voice: voice})
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, err
 	}
-
-	// PCMデータをBase64エンコード
-	audioBase64 := base64.StdEncoding.EncodeToString(audioBytes)
-	log.Printf("Generated TTS audio: %d bytes, base64 length: %d", len(audioBytes), len(audioBase64))
-
-	return audioBase64, nil
+	log.Printf("Generated TTS audio: %d bytes", len(pcm))
+	return pcm, nil
 }
 
 func (h *HostAgent) publishAudioToLiveKit(audioData string) {
-	if h.pcmWriter == nil {
-		log.Println("PCM writer not initialized, skipping audio publish")
+	if h.player == nil {
+		log.Println("Player not initialized, skipping audio publish")
 		return
 	}
 
 	log.Printf("publishAudioToLiveKit called with data length: %d", len(audioData))
 
-	// PCMWriterを使用してBase64デルタデータを処理
-	log.Printf("Processing real audio data via PCMWriter, calling WriteB64Delta...")
-	if err := h.pcmWriter.WriteB64Delta(audioData); err != nil {
-		log.Printf("Failed to write audio delta: %v", err)
+	raw, err := base64.StdEncoding.DecodeString(audioData)
+	if err != nil {
+		log.Printf("Failed to decode audio delta: %v", err)
 		return
 	}
 
-	log.Printf("Audio data published via PCMWriter successfully")
+	h.writeSpeech(raw)
+	log.Printf("Audio data queued for playout (buffered: %v)", h.player.Buffered())
+	h.notifySpeechQueued()
+}
+
+// writeSpeech DJの音声（24kHz mono PCM）を再生バッファに追加
+func (h *HostAgent) writeSpeech(pcm []byte) {
+	// 音楽ベッドが流れている場合は喋っている間ダッキング
+	if h.bedPlaying {
+		h.mixer.DuckOn()
+	}
+
+	// 送出フォーマットへの変換と送出はPlayerが行う
+	h.player.WriteFrom(pcm, audio.FormatRealtime)
+}
 
-	// LiveKitアップロード完了を通知（タイマーリセット用）
+// notifySpeechQueued 発話を送出キューに積み終えたことを通知（定期発話タイマーのリセット用）
+func (h *HostAgent) notifySpeechQueued() {
 	select {
 	case h.timerResetChan <- struct{}{}:
 		log.Println("Timer reset signal sent - next script will be generated 30s after this upload")
@@ -629,11 +927,33 @@ func (h *HostAgent) publishAudioToLiveKit(audioData string) {
 
 // sendSubtitle 字幕データをAPIサーバーに送信
 func (h *HostAgent) sendSubtitle(text string) {
+	h.sendSpeakerSubtitle(h.personas.Current(), text)
+}
+
+// sendSpeakerSubtitle 話者付きの字幕データをAPIサーバーに送信
+//
+// 送出ディレイがかかっている間は音声が流れるまで遅らせ、その前にダンプされたら送らない。
+func (h *HostAgent) sendSpeakerSubtitle(speaker persona.Persona, text string) {
+	if state := h.delay.State(); state.Buffered > 0 {
+		time.AfterFunc(state.Buffered, func() {
+			if h.delay.State().Dumps == state.Dumps {
+				h.postSubtitle(speaker, text)
+			}
+		})
+		return
+	}
+	h.postSubtitle(speaker, text)
+}
+
+// postSubtitle 字幕をAPIの /v1/subtitle に送る
+func (h *HostAgent) postSubtitle(speaker persona.Persona, text string) {
 	apiBase := getEnv("API_BASE", "http://api:8080")
 
 	payload := map[string]interface{}{
-		"text": text,
-		"type": "host_speech",
+		"text":         text,
+		"type":         "host_speech",
+		"speaker":      speaker.ID,
+		"speaker_name": speaker.Name,
 	}
 
 	jsonData, err := json.Marshal(payload)
@@ -663,93 +983,227 @@ func (h *HostAgent) sendSubtitle(text string) {
 }
 
 // publishUserAudioToLiveKit ユーザー音声をLiveKitに送信
-func (h *HostAgent) publishUserAudioToLiveKit(audioData string) {
-	if h.userPcmWriter == nil {
-		log.Println("User PCM writer not initialized, skipping user audio publish")
+func (h *HostAgent) publishUserAudioToLiveKit(pcm []byte, format audio.Format) {
+	if h.userPlayer == nil {
+		log.Println("User player not initialized, skipping user audio publish")
 		return
 	}
 
-	log.Printf("publishUserAudioToLiveKit called with data length: %d", len(audioData))
+	log.Printf("publishUserAudioToLiveKit called with %d bytes (%s)", len(pcm), format)
 
-	// ユーザー音声用PCMWriterを使用してBase64デルタデータを処理
-	log.Printf("Processing user audio data via PCMWriter, calling WriteB64Delta...")
-	if err := h.userPcmWriter.WriteB64Delta(audioData); err != nil {
-		log.Printf("Failed to write user audio delta: %v", err)
-		return
-	}
+	// ユーザー音声用の再生エンジンに追加
+	h.userPlayer.WriteFrom(pcm, format)
 
-	log.Printf("User audio data published via PCMWriter successfully")
+	log.Printf("User audio data queued for playout")
 }
 
-// generateScript OpenAI APIを使用して台本を生成
-func (h *HostAgent) generateScript(prompt string) (string, error) {
-	apiKey := getEnv("OPENAI_API_KEY", "")
-	if apiKey == "" || apiKey == "your-openai-api-key" || apiKey == "test-mode" {
-		log.Println("OpenAI API key not set, using test mode")
-		return "テストモードです。ラジオ24をお聞きいただき、ありがとうございます。", nil
+// convertCallerAudio 発信者の音声をRealtimeの入力フォーマット（24kHz mono）に変換
+func (h *HostAgent) convertCallerAudio(pcm []byte, format audio.Format) []byte {
+	h.callerInputMutex.Lock()
+	defer h.callerInputMutex.Unlock()
+
+	if h.callerInput == nil || h.callerInputFormat != format {
+		log.Printf("Caller audio format: %s -> %s", format, audio.FormatRealtime)
+		h.callerInput = audio.NewConverter(format, audio.FormatRealtime)
+		h.callerInputFormat = format
 	}
+	return h.callerInput.ConvertBytes(pcm)
+}
 
-	url := "https://api.openai.com/v1/chat/completions"
+// handleCallerAudio 通話者の音声をRealtimeに送り、放送にも流す
+func (h *HostAgent) handleCallerAudio(pcm []byte, format audio.Format) error {
+	// 対話モードのアクティビティを更新
+	h.touchDialogue()
 
-	requestBody := map[string]interface{}{
-		"model": "gpt-4o-mini",
-		"messages": []map[string]string{
-			{
-				"role":    "system",
-				"content": "あなたは24時間AIラジオのDJです。自然で親しみやすい口調で、リスナーとの距離感を大切にしてください。",
-			},
-			{
-				"role":    "user",
-				"content": prompt,
-			},
-		},
-		"max_tokens":  200,
-		"temperature": 0.8,
-		"top_p":       0.9,
+	log.Printf("Decoded audio data: %d bytes (PCM16 samples: %d)", len(pcm), len(pcm)/2)
+	duration := format.Duration(len(pcm) / 2)
+	log.Printf("Audio duration: %v", duration)
+
+	// 25ms未満の場合は警告
+	if duration < 25*time.Millisecond {
+		log.Printf("WARNING: Audio duration (%v) is less than 25ms, may cause buffer commit errors", duration)
 	}
 
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+	// OpenAI Realtimeに音声データを送信（24kHz monoに変換）
+	if h.dialogueConn != nil {
+		realtimeAudio := base64.StdEncoding.EncodeToString(h.convertCallerAudio(pcm, format))
+		audioMessage := map[string]interface{}{
+			"type":  "input_audio_buffer.append",
+			"audio": realtimeAudio,
+		}
+
+		log.Printf("Sending audio to OpenAI Realtime: base64 length: %d", len(realtimeAudio))
+
+		if err := h.dialogueConn.WriteJSON(audioMessage); err != nil {
+			log.Printf("Failed to send audio to OpenAI: %v", err)
+			return err
+		}
+
+		log.Printf("Audio data sent successfully to OpenAI Realtime")
+	} else {
+		log.Printf("Dialogue connection is nil, cannot send audio")
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+	// ユーザー音声をLiveKitに送信
+	h.publishUserAudioToLiveKit(pcm, format)
+	return nil
+}
+
+// commitCallerAudio OpenAI Realtimeにコミット信号を送信
+func (h *HostAgent) commitCallerAudio() error {
+	if h.dialogueConn == nil {
+		return nil
+	}
+	commitMessage := map[string]interface{}{
+		"type": "input_audio_buffer.commit",
+	}
+	if err := h.dialogueConn.WriteJSON(commitMessage); err != nil {
+		log.Printf("Failed to send commit to OpenAI: %v", err)
+		return err
+	}
+	return nil
+}
+
+// handleCallerFrame APIからのストリームで届いたフレーム（届いた順に1件ずつ呼ばれる）
+func (h *HostAgent) handleCallerFrame(f callerlink.Frame) error {
+	switch f.Kind {
+	case callerlink.KindAudio:
+		format := audio.FormatRealtime
+		if f.SampleRate > 0 {
+			format.SampleRate = f.SampleRate
+		}
+		if f.Channels > 0 {
+			format.Channels = f.Channels
+		}
+		if err := format.Validate(); err != nil {
+			return err
+		}
+		return h.handleCallerAudio(f.Audio, format)
+	case callerlink.KindCommit:
+		return h.commitCallerAudio()
+	case callerlink.KindDialogue:
+		if f.Dialogue == nil {
+			return fmt.Errorf("dialogue frame without transition")
+		}
+		_, err := h.applyDialogueEvent(*f.Dialogue)
+		return err
 	}
+	return fmt.Errorf("unknown frame kind: %s", f.Kind)
+}
+
+// systemPrompt DJの基本設定に現在の枠の進行用ガイダンスを加えたシステムプロンプト
+func (h *HostAgent) systemPrompt(ctx context.Context) string {
+	return h.personas.Current().MonologuePrompt() + h.programContext(ctx)
+}
+
+// programContext 枠の進行・セグメント情報・放送の記憶（1人でも掛け合いでも共通）
+//
+// 先読みの台本はいつ流れるか決まっていないので、残り時間や進行段階は入れずに枠の名前だけ伝える。
+func (h *HostAgent) programContext(ctx context.Context) string {
+	var guide string
+	if segment := h.segmentPrompt(); segment != "" {
+		guide += "\n\n現在の枠の進行: " + segment
+	}
+	if prefetching(ctx) {
+		if seg, ok := h.director.Segment(); ok {
+			guide += "\nこのセグメント：" + seg.Name() + "。"
+		}
+	} else if line := h.director.PromptLine(time.Now()); line != "" {
+		guide += "\n" + line
+	}
+	if memory := h.memoryPrompt(); memory != "" {
+		guide += "\n\n" + memory
+	}
+	return guide
+}
 
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
+// memoryPrompt APIから放送の記憶（直近の台本・リスナー・要約）を取得（失敗時は空）
+func (h *HostAgent) memoryPrompt() string {
+	apiBase := getEnv("API_BASE", "http://api:8080")
+	query := url.Values{
+		"channel": {getEnv("CHANNEL_NAME", "Radio-24")},
+		"budget":  {getEnv("MEMORY_TOKEN_BUDGET", "1200")},
+	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(apiBase + "/v1/memory/context?" + query.Encode())
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		log.Printf("Failed to load on-air memory: %v", err)
+		return ""
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("OpenAI API error: %d - %s", resp.StatusCode, string(body))
+		log.Printf("Memory API returned status: %d", resp.StatusCode)
+		return ""
+	}
+
+	var memory struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&memory); err != nil {
+		log.Printf("Failed to decode memory response: %v", err)
+		return ""
+	}
+	return memory.Prompt
+}
+
+// recordMemory 放送した内容を記憶としてAPIに保存（kind: script / caller）
+func (h *HostAgent) recordMemory(kind, topic, text string) {
+	if text == "" {
+		return
+	}
+	apiBase := getEnv("API_BASE", "http://api:8080")
+	query := url.Values{"channel": {getEnv("CHANNEL_NAME", "Radio-24")}}
+
+	jsonData, err := json.Marshal(map[string]string{
+		"kind":  kind,
+		"topic": topic,
+		"text":  text,
+	})
+	if err != nil {
+		log.Printf("Failed to marshal memory: %v", err)
+		return
 	}
 
-	var response struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(apiBase+"/v1/memory?"+query.Encode(), "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		log.Printf("Failed to record memory: %v", err)
+		return
 	}
+	defer resp.Body.Close()
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	if resp.StatusCode != http.StatusCreated {
+		log.Printf("Memory API returned status: %d", resp.StatusCode)
 	}
+}
+
+// generateScript 台本を生成
+func (h *HostAgent) generateScript(ctx context.Context, prompt string) (string, error) {
+	return h.chatCompletion(ctx, h.systemPrompt(ctx), prompt, 200, false)
+}
 
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no response from OpenAI")
+// generateBanter 2人のDJの掛け合い台本を生成
+func (h *HostAgent) generateBanter(ctx context.Context, prompt string, lead, partner persona.Persona) ([]banter.Line, error) {
+	prompt += "\n掛け合いの台本にしてください。"
+	raw, err := h.chatCompletion(ctx, banter.SystemPrompt(lead, partner)+h.programContext(ctx), prompt, 600, true)
+	if err != nil {
+		return nil, err
 	}
+	return banter.Parse(raw, lead, partner)
+}
 
-	return response.Choices[0].Message.Content, nil
+// chatCompletion チャットで生成（jsonMode ではJSONオブジェクトで返させる）
+func (h *HostAgent) chatCompletion(ctx context.Context, system, prompt string, maxTokens int, jsonMode bool) (string, error) {
+	return h.llm.Chat.Complete(ctx, llm.ChatRequest{
+		System:      system,
+		Prompt:      prompt,
+		MaxTokens:   maxTokens,
+		Temperature: 0.8,
+		TopP:        0.9,
+		JSON:        jsonMode,
+	})
 }
 
 func (h *HostAgent) reconnectLiveKit() {
@@ -768,39 +1222,601 @@ func (h *HostAgent) reconnectLiveKit() {
 	})
 }
 
-// generateAndSpeakScript 台本を生成してTTSで読み上げ
-func (h *HostAgent) generateAndSpeakScript() {
-	// 現在のトピックを取得
-	topic := h.scriptTopics[h.currentTopic]
-	h.currentTopic = (h.currentTopic + 1) % len(h.scriptTopics)
+// generateAndSpeakScript 台本を読み上げ、次のトピックを先読みする
+//
+// 先読み済みならすぐに再生し、なければその場で生成・レンダリングする。
+func (h *HostAgent) generateAndSpeakScript(topic string) {
+	if h.degraded() {
+		h.runAutomation(topic)
+		return
+	}
 
-	// 台本生成用のプロンプトを作成
-	prompt := fmt.Sprintf("%s トピック「%s」について、ラジオDJとして30秒程度の内容を話してください。自然で親しみやすい口調で、リスナーとの距離感を大切にしてください。", h.currentPrompt, topic)
+	script, ok := h.lookahead.Take(h.ctx, topic)
+	if ok {
+		log.Printf("Using lookahead script for topic: %s", topic)
+	} else {
+		// 先読みがなければ台本だけ作り、音声は合成しながら流す
+		script = h.composeScript(h.ctx, topic, 0)
+		// 台本の生成で上流の障害が分かったら、フォールバックの文は喋らず自動運行に切り替える
+		if h.degraded() {
+			h.runAutomation(topic)
+			return
+		}
+	}
 
-	log.Printf("Generating script for topic: %s", topic)
+	h.playScript(script)
+	h.lookahead.Prefetch(h.director.Upcoming(time.Now(), lookaheadMaxAge, loadLookaheadDepth())...)
+}
 
-	// OpenAI APIを使用して台本を生成
-	script, err := h.generateScript(prompt)
-	if err != nil {
-		log.Printf("Failed to generate script: %v", err)
-		// フォールバック用の簡単なメッセージ
-		script = fmt.Sprintf("こんにちは、ラジオ24です。%sについてお話しします。", topic)
+// setupUpstream 上流のAIの呼び出しにリトライとサーキットブレーカーを付ける
+// （UPSTREAM_BREAKER_THRESHOLD 回続けて失敗したら UPSTREAM_BREAKER_COOLDOWN の間は自動運行）
+func (h *HostAgent) setupUpstream(provider llm.Provider) {
+	threshold, err := strconv.Atoi(getEnv("UPSTREAM_BREAKER_THRESHOLD", "3"))
+	if err != nil || threshold < 1 {
+		threshold = 3
+	}
+	cooldown, err := time.ParseDuration(getEnv("UPSTREAM_BREAKER_COOLDOWN", "30s"))
+	if err != nil || cooldown <= 0 {
+		cooldown = 30 * time.Second
 	}
 
-	log.Printf("Generated script: %s", script)
+	h.breaker = llm.NewBreaker(threshold, cooldown)
+	h.breaker.OnChange = func(llm.BreakerState) {
+		select {
+		case h.upstreamChanged <- struct{}{}:
+		default:
+		}
+	}
+	h.llm = llm.Resilient(provider, llm.DefaultRetryPolicy, h.breaker)
+}
 
-	// 生成された台本をTTSで読み上げ
-	h.sendMessage(script)
+// degraded 上流のAIが使えず自動運行中か（冷却期間が明けたら回復を確かめるため false）
+func (h *HostAgent) degraded() bool {
+	return h.breaker != nil && h.breaker.State() == llm.BreakerOpen
 }
 
-func (h *HostAgent) startHTTPServer() {
-	port := getEnv("HOST_PORT", "8080")
+// upstreamState /health 用の上流の状態
+func (h *HostAgent) upstreamState() llm.BreakerState {
+	if h.breaker == nil {
+		return llm.BreakerClosed
+	}
+	return h.breaker.State()
+}
 
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
+// applyUpstreamState 障害中は音楽ベッドでつなぎ、回復したら自動運行で流したベッドを止めて通常進行に戻る
+func (h *HostAgent) applyUpstreamState() {
+	switch h.upstreamState() {
+	case llm.BreakerOpen:
+		log.Println("Upstream AI unavailable: switching to automation mode")
+		if !h.inDialogue() {
+			h.startAutomationBed()
+		}
+	case llm.BreakerClosed:
+		log.Println("Upstream AI recovered: leaving automation mode")
+		if h.automationBed {
+			h.StopBed()
+		}
+		h.lookahead.Prefetch(h.director.Upcoming(time.Now(), lookaheadMaxAge, loadLookaheadDepth())...)
+	}
+}
+
+// runAutomation 自動運行：レンダリング済みの台本があれば流し、なければ音楽ベッドでつなぐ
+func (h *HostAgent) runAutomation(topic string) {
+	if h.lookahead.Ready() {
+		if script, ok := h.lookahead.Take(h.ctx, topic); ok {
+			log.Printf("Automation: playing pre-rendered script for topic: %s", topic)
+			h.playScript(script)
+			return
+		}
+	}
+	log.Printf("Automation: no pre-rendered script for %s, keeping music bed", topic)
+	h.startAutomationBed()
+}
+
+func (h *HostAgent) startAutomationBed() {
+	if h.bedPlaying {
+		return
+	}
+	h.StartBed()
+	h.automationBed = h.bedPlaying
+}
+
+// prefetchKey 先読みバッファからの生成であることを示す context のキー
+type prefetchKey struct{}
+
+// prefetching 先読みの台本を生成しているか
+func prefetching(ctx context.Context) bool {
+	v, _ := ctx.Value(prefetchKey{}).(bool)
+	return v
+}
+
+// renderScript 先読みバッファから呼ばれ、台本を生成してTTSでPCMにレンダリングする
+//
+// 台本生成に失敗した場合はフォールバックの文面を使い、TTSに失敗した場合は
+// 音声なしの台本とエラーを返す。先読みを捨てた（ctx がキャンセルされた）場合は途中でやめる。
+func (h *HostAgent) renderScript(ctx context.Context, topic string) (lookahead.Script, error) {
+	ctx = context.WithValue(ctx, prefetchKey{}, true)
+	script := h.composeScript(ctx, topic, 0)
+	if ctx.Err() != nil {
+		return script, ctx.Err()
+	}
+
+	// 行ごとに話者の声でレンダリング
+	cast := h.personas.Cast()
+	for i := range script.Lines {
+		line := &script.Lines[i]
+		pcm, err := h.generateTTS(ctx, line.Text, h.speakerOf(cast, line.Speaker).TTSVoice)
+		if err != nil {
+			return script, fmt.Errorf("failed to generate TTS: %w", err)
+		}
+		line.Audio = pcm
+	}
+	return script, nil
+}
+
+// composeScript トピックの台本を生成（音声はまだ付けない）
+//
+// limit を指定すると、読み上げがその時間に収まるように台本を切り詰める（0なら30秒程度）。
+func (h *HostAgent) composeScript(ctx context.Context, topic string, limit time.Duration) lookahead.Script {
+	// 台本生成用のプロンプトを作成
+	length := "30秒程度"
+	if limit > 0 {
+		length = fmt.Sprintf("%d秒以内", int(limit.Seconds()))
+	}
+	prompt := fmt.Sprintf("トピック「%s」について、ラジオDJとして%sの内容を話してください。自然で親しみやすい口調で、リスナーとの距離感を大切にしてください。", topic, length)
+
+	// トピックに近いリスナーの投稿があれば紹介してもらう
+	submissions := h.relevantSubmissions(topic)
+	if len(submissions) > 0 {
+		prompt += "\n\nリスナーからこの話題に関する次の投稿が届いています。「何人かの方から〜についてメッセージをいただきました」のように自然に触れて紹介してください。"
+		for _, sub := range submissions {
+			prompt += "\n- " + sub.Text
+		}
+	}
+
+	log.Printf("Generating script for topic: %s (%d submissions)", topic, len(submissions))
+
+	// LLMで台本を生成
+	var embedding string
+	lines, err := h.compose(ctx, prompt)
+	if err != nil {
+		log.Printf("Failed to generate script: %v", err)
+		// フォールバック用の簡単なメッセージ
+		lines = []banter.Line{{
+			Speaker: h.personas.Current().ID,
+			Text:    fallbackText(topic),
+		}}
+	} else {
+		lines, embedding = h.avoidRepetition(ctx, prompt, lines)
+	}
+
+	cast := h.personas.Cast()
+	text := banter.Text(lines, castNames(cast))
+	if limit > 0 {
+		lines = banter.Fit(lines, int(limit.Seconds()*speechRate))
+		if fitted := banter.Text(lines, castNames(cast)); fitted != text {
+			// 切り詰めた台本はチェックした文と違うので、埋め込みは放送後に作り直す
+			text, embedding = fitted, ""
+		}
+	}
+	log.Printf("Generated script: %s", text)
+
+	script := lookahead.Script{Topic: topic, Text: text, Rendered: time.Now(), Embedding: embedding}
+	if err == nil {
+		// 投稿を紹介したのはプロンプトで渡して生成できた台本だけ（フォールバックの文では紹介していない）
+		for _, sub := range submissions {
+			script.Submissions = append(script.Submissions, sub.ID)
+		}
+	}
+	for _, l := range lines {
+		script.Lines = append(script.Lines, lookahead.Line{Speaker: l.Speaker, Text: l.Text})
+	}
+	return script
+}
+
+// speakerOf 行の話者（キャストにいなければ担当のDJの声で読む）
+func (h *HostAgent) speakerOf(cast map[string]persona.Persona, id string) persona.Persona {
+	if p, ok := cast[id]; ok {
+		return p
+	}
+	return persona.Persona{ID: id, TTSVoice: h.personas.Current().TTSVoice}
+}
+
+// compose 台本を生成（掛け合いの相手がいれば話者付きの掛け合い、いなければ1人語り）
+func (h *HostAgent) compose(ctx context.Context, prompt string) ([]banter.Line, error) {
+	lead := h.personas.Current()
+	if partner, ok := h.personas.Partner(); ok {
+		lines, err := h.generateBanter(ctx, prompt, lead, partner)
+		if err == nil {
+			return lines, nil
+		}
+		log.Printf("Failed to generate banter, falling back to monologue: %v", err)
+	}
+
+	text, err := h.generateScript(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return []banter.Line{{Speaker: lead.ID, Text: text}}, nil
+}
+
+// castNames 話者IDから名前を引くマップ
+func castNames(cast map[string]persona.Persona) map[string]string {
+	names := make(map[string]string, len(cast))
+	for id, p := range cast {
+		names[id] = p.Name
+	}
+	return names
+}
+
+// Submission 台本で紹介するリスナーの投稿
+type Submission struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// relevantSubmissions トピックに近い、まだ紹介していない最近の投稿をAPIから取得
+func (h *HostAgent) relevantSubmissions(topic string) []Submission {
+	apiBase := getEnv("API_BASE", "http://api:8080")
+	query := url.Values{
+		"topic": {topic},
+		"limit": {"3"},
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(apiBase + "/v1/submissions/relevant?" + query.Encode())
+	if err != nil {
+		log.Printf("Failed to get relevant submissions: %v", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Submissions API returned status: %d", resp.StatusCode)
+		return nil
+	}
+
+	var result struct {
+		Submissions []Submission `json:"submissions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		log.Printf("Failed to decode submissions response: %v", err)
+		return nil
+	}
+	return result.Submissions
+}
+
+// markSubmissionsFeatured 放送で紹介した投稿を紹介済みにする
+func (h *HostAgent) markSubmissionsFeatured(ids []string, topic string) {
+	apiBase := getEnv("API_BASE", "http://api:8080")
+
+	jsonData, err := json.Marshal(map[string]interface{}{"ids": ids, "topic": topic})
+	if err != nil {
+		log.Printf("Failed to marshal featured submissions: %v", err)
+		return
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(apiBase+"/v1/submissions/featured", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		log.Printf("Failed to mark submissions as featured: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Submissions API returned status: %d", resp.StatusCode)
+	}
+}
+
+// maxRepeatRetries 最近の放送と似ていた場合に作り直す回数
+const maxRepeatRetries = 2
+
+// repetitionCheck 繰り返しチェックの結果
+type repetitionCheck struct {
+	Duplicate  bool
+	Previous   string  // 似ていた台本
+	Similarity float64 // 最も似ていた台本との類似度
+	Embedding  string  // チェックした台本の埋め込み（取れなかったら空）
+}
+
+// avoidRepetition 最近放送した台本・放送待ちの台本と似すぎていれば「別の話を」と指示して作り直す
+//
+// 作り直した台本もチェックし、最後まで似ていれば最も似ていないものを使う。
+// 使う台本の埋め込みも返す。
+func (h *HostAgent) avoidRepetition(ctx context.Context, prompt string, lines []banter.Line) ([]banter.Line, string) {
+	pending := h.lookahead.Queued()
+	names := castNames(h.personas.Cast())
+
+	best, bestCheck := lines, repetitionCheck{}
+	for i := 0; ; i++ {
+		check := h.findRepetition(banter.Text(lines, names), pending)
+		if !check.Duplicate {
+			return lines, check.Embedding
+		}
+		if i == 0 || check.Similarity < bestCheck.Similarity {
+			best, bestCheck = lines, check
+		}
+		if i == maxRepeatRetries {
+			log.Printf("Script still repeats after %d retries, using the least similar one (similarity %.3f)", maxRepeatRetries, bestCheck.Similarity)
+			return best, bestCheck.Embedding
+		}
+		log.Printf("Script repeats a recent one, regenerating (%d/%d)", i+1, maxRepeatRetries)
+
+		retry := prompt + fmt.Sprintf("\n\nただし、少し前に「%s」と話したばかりです。同じ内容や言い回しは避け、別の切り口や違う話題について話してください。", check.Previous)
+		next, err := h.compose(ctx, retry)
+		if err != nil {
+			log.Printf("Failed to regenerate script: %v", err)
+			return best, bestCheck.Embedding
+		}
+		lines = next
+	}
+}
+
+// findRepetition APIで最近放送した台本・放送待ちの台本 pending との類似度をチェック
+func (h *HostAgent) findRepetition(text string, pending []lookahead.Script) repetitionCheck {
+	apiBase := getEnv("API_BASE", "http://api:8080")
+	query := url.Values{"channel": {getEnv("CHANNEL_NAME", "Radio-24")}}
+
+	type pendingScript struct {
+		Topic     string `json:"topic,omitempty"`
+		Text      string `json:"text"`
+		Embedding string `json:"embedding"`
+	}
+	req := struct {
+		Text    string          `json:"text"`
+		Pending []pendingScript `json:"pending,omitempty"`
+	}{Text: text}
+	for _, s := range pending {
+		if s.Embedding != "" {
+			req.Pending = append(req.Pending, pendingScript{Topic: s.Topic, Text: s.Text, Embedding: s.Embedding})
+		}
+	}
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return repetitionCheck{}
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(apiBase+"/v1/scripts/check?"+query.Encode(), "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		log.Printf("Failed to check script repetition: %v", err)
+		return repetitionCheck{}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Script check API returned status: %d", resp.StatusCode)
+		return repetitionCheck{}
+	}
+
+	var result struct {
+		Duplicate bool   `json:"duplicate"`
+		Embedding string `json:"embedding"`
+		Match     struct {
+			Text       string  `json:"text"`
+			Similarity float64 `json:"similarity"`
+		} `json:"match"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		log.Printf("Failed to decode script check response: %v", err)
+		return repetitionCheck{}
+	}
+	check := repetitionCheck{Duplicate: result.Duplicate, Embedding: result.Embedding}
+	if result.Duplicate {
+		log.Printf("Similar recent script (similarity %.3f): %s", result.Match.Similarity, result.Match.Text)
+		check.Previous, check.Similarity = result.Match.Text, result.Match.Similarity
+	}
+	return check
+}
+
+// recordAiredScript 放送した台本を記憶と繰り返しチェック用の履歴に保存
+//
+// embedding はチェックの時に得た埋め込み（空ならAPIで作る）。
+func (h *HostAgent) recordAiredScript(topic, text, embedding string) {
+	h.recordMemory("script", topic, text)
+
+	apiBase := getEnv("API_BASE", "http://api:8080")
+	query := url.Values{"channel": {getEnv("CHANNEL_NAME", "Radio-24")}}
+
+	jsonData, err := json.Marshal(map[string]string{"topic": topic, "text": text, "embedding": embedding})
+	if err != nil {
+		log.Printf("Failed to marshal aired script: %v", err)
+		return
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(apiBase+"/v1/scripts?"+query.Encode(), "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		log.Printf("Failed to record aired script: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		log.Printf("Scripts API returned status: %d", resp.StatusCode)
+	}
+}
+
+// playScript 台本の字幕を送り、音声をキューに積む
+//
+// 行ごとに、その行の音声が流れ始めるタイミングで話者付きの字幕を送る。
+// 先読みでレンダリングしていない行は文ごとに合成しながら流す。
+func (h *HostAgent) playScript(script lookahead.Script) {
+	go h.recordAiredScript(script.Topic, script.Text, script.Embedding)
+	if len(script.Submissions) > 0 {
+		go h.markSubmissionsFeatured(script.Submissions, script.Topic)
+	}
+
+	cast := h.personas.Cast()
+	for _, line := range script.Lines {
+		speaker := h.speakerOf(cast, line.Speaker)
+		if len(line.Audio) == 0 {
+			h.speak(speaker, line.Text)
+			continue
+		}
+
+		h.subtitleWhenAired(speaker, line.Text)
+		h.writeSpeech(line.Audio)
+	}
+	h.notifySpeechQueued()
+}
+
+// speak 文ごとにTTSで合成し、届いた音声から順に流す
+func (h *HostAgent) speak(speaker persona.Persona, text string) {
+	h.subtitleWhenAired(speaker, text)
+
+	w := speechWriter(h.writeSpeech)
+	if err := speech.Stream(h.ctx, h.llm.TTS, llm.SpeechRequest{Text: text, Voice: speaker.TTSVoice}, w); err != nil {
+		log.Printf("Failed to stream TTS: %v", err)
+	}
+}
+
+// speechWriter PCMを書き込む関数を io.Writer として扱う
+type speechWriter func(pcm []byte)
+
+func (f speechWriter) Write(p []byte) (int, error) {
+	f(p)
+	return len(p), nil
+}
+
+// subtitleWhenAired 今キューに積む音声が流れ始めるタイミングで字幕を送る
+//
+// それまでに音声が破棄されたら送らない。
+func (h *HostAgent) subtitleWhenAired(speaker persona.Persona, text string) {
+	h.subtitleMutex.Lock()
+	gen := h.subtitleGen
+	h.subtitleMutex.Unlock()
+
+	time.AfterFunc(h.player.Buffered(), func() {
+		h.subtitleMutex.Lock()
+		cancelled := h.subtitleGen != gen
+		h.subtitleMutex.Unlock()
+		if !cancelled {
+			h.sendSpeakerSubtitle(speaker, text)
+		}
+	})
+}
+
+// cancelSubtitles 予約済みでまだ送っていない字幕を取り消す
+func (h *HostAgent) cancelSubtitles() {
+	h.subtitleMutex.Lock()
+	defer h.subtitleMutex.Unlock()
+	h.subtitleGen++
+}
+
+// outroText 通話を終えて通常放送へ戻るときの決まり文句
+const outroText = "ありがとうございました。通常のラジオ放送に戻ります。"
+
+// setupTTSCache 合成済み音声のディスクキャッシュを挟む
+// （TTS_CACHE_DIR、TTS_CACHE_MAX_MB=0 で無効、デフォルト256MB）
+func (h *HostAgent) setupTTSCache() {
+	maxMB, err := strconv.Atoi(getEnv("TTS_CACHE_MAX_MB", "256"))
+	if err != nil || maxMB < 0 {
+		maxMB = 256
+	}
+	if maxMB == 0 {
+		log.Println("TTS cache disabled")
+		return
+	}
+
+	dir := getEnv("TTS_CACHE_DIR", filepath.Join(os.TempDir(), "radio24-tts-cache"))
+	cache, err := ttscache.Open(dir, int64(maxMB)<<20)
+	if err != nil {
+		log.Printf("TTS cache disabled: %v", err)
+		return
+	}
+	h.ttsCache = cache
+	h.llm.TTS = ttscache.Wrap(h.llm.TTS, h.llm.TTSModel, cache)
+	log.Printf("TTS cache: %s (%d entries, max %dMB)", dir, cache.Stats().Entries, maxMB)
+}
+
+// fallbackText 台本の生成に失敗した時に読む文
+func fallbackText(topic string) string {
+	return fmt.Sprintf("こんにちは、ラジオ24です。%sについてお話しします。", topic)
+}
+
+// prewarmPhrases 毎回同じ文を読み上げる定型文
+// （時報・通話明けの挨拶・番組のトピックごとのフォールバックの文と TTS_PREWARM_PATH の各行）
+func prewarmPhrases() []string {
+	phrases := []string{outroText}
+	for hour := 0; hour < 24; hour++ {
+		phrases = append(phrases, timeSignalText(hour, ""))
+	}
+	for _, topic := range director.Topics() {
+		phrases = append(phrases, fallbackText(topic))
+	}
+
+	path := getEnv("TTS_PREWARM_PATH", "")
+	if path == "" {
+		return phrases
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Failed to read TTS prewarm list: %v", err)
+		return phrases
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			phrases = append(phrases, line)
+		}
+	}
+	return phrases
+}
+
+// prewarmTTS 定型文を現在のDJの声で合成してキャッシュに載せる
+// （放送時と同じく文単位で合成するので、そのままキャッシュに当たる）
+func (h *HostAgent) prewarmTTS() {
+	if h.ttsCache == nil {
+		return
+	}
+	voice := h.personas.Current().TTSVoice
+	phrases := prewarmPhrases()
+
+	// 一度きりの台本の文に押し出されないよう、放送時と同じ文単位のキーで残しておく
+	var keys []string
+	for _, text := range phrases {
+		for _, sentence := range speech.Split(text) {
+			keys = append(keys, ttscache.Key(h.llm.TTSModel, llm.SpeechRequest{Text: sentence, Voice: voice}))
+		}
+	}
+	h.ttsCache.Pin(keys)
+
+	before := h.ttsCache.Stats().Misses
+	for _, text := range phrases {
+		if err := speech.Stream(h.ctx, h.llm.TTS, llm.SpeechRequest{Text: text, Voice: voice}, io.Discard); err != nil {
+			log.Printf("TTS prewarm stopped: %v", err)
+			return
+		}
+	}
+	log.Printf("TTS prewarm done for voice %s (%d sentences synthesized)", voice, h.ttsCache.Stats().Misses-before)
+}
+
+// loadLookaheadDepth 先読みする台本の件数（SCRIPT_LOOKAHEAD、デフォルト2）
+func loadLookaheadDepth() int {
+	depth, err := strconv.Atoi(getEnv("SCRIPT_LOOKAHEAD", "2"))
+	if err != nil || depth < 1 {
+		return 2
+	}
+	return depth
+}
+
+func (h *HostAgent) startHTTPServer() {
+	port := getEnv("HOST_PORT", "8080")
+
+	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		// 上流のAIの障害中（回復の確認中を含む）も放送は続けているので 200 のまま degraded を返す
+		upstream := h.upstreamState()
+		status := "healthy"
+		if upstream != llm.BreakerClosed {
+			status = "degraded"
+		}
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(map[string]string{
-			"status":    "healthy",
+			"status":    status,
+			"upstream":  string(upstream),
 			"timestamp": time.Now().Format(time.RFC3339),
 		})
 	})
@@ -821,13 +1837,13 @@ func (h *HostAgent) startHTTPServer() {
 		log.Printf("Received script generation request: topic=%s, style=%s", req.Topic, req.Style)
 
 		// プロンプトを作成
-		prompt := fmt.Sprintf("%s トピック「%s」について、ラジオDJとして30秒程度の内容を話してください。必要に応じて最新の情報を検索して取り込んでください。", h.currentPrompt, req.Topic)
+		prompt := fmt.Sprintf("トピック「%s」について、ラジオDJとして30秒程度の内容を話してください。必要に応じて最新の情報を検索して取り込んでください。", req.Topic)
 		if req.Style != "" {
 			prompt += fmt.Sprintf(" スタイル: %s", req.Style)
 		}
 
 		// 台本を生成
-		script, err := h.generateScript(prompt)
+		script, err := h.generateScript(h.ctx, prompt)
 		if err != nil {
 			log.Printf("Failed to generate script: %v", err)
 			http.Error(w, "Failed to generate script", http.StatusInternalServerError)
@@ -867,7 +1883,10 @@ func (h *HostAgent) startHTTPServer() {
 		})
 	})
 
-	// 対話モード用の音声入力エンドポイント
+	// APIからの通話音声・コミット・状態遷移のストリーム（/audio/input・/audio/commit・/dialogue/events と同じ処理）
+	http.Handle(callerlink.Path, h.callerLink)
+
+	// 対話モード用の音声入力エンドポイント（ストリームが使えない時の予備）
 	http.HandleFunc("/audio/input", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -875,8 +1894,10 @@ func (h *HostAgent) startHTTPServer() {
 		}
 
 		var req struct {
-			Type  string `json:"type"`
-			Audio string `json:"audio"`
+			Type       string `json:"type"`
+			Audio      string `json:"audio"`
+			SampleRate int    `json:"sample_rate,omitempty"` // 省略時は24kHz
+			Channels   int    `json:"channels,omitempty"`    // 省略時はmono
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -884,127 +1905,286 @@ func (h *HostAgent) startHTTPServer() {
 			return
 		}
 
-		log.Printf("Received audio input: %d bytes", len(req.Audio))
-
-		// 対話モードのアクティビティを更新
-		h.dialogueStateMutex.Lock()
-		if h.dialogueMode {
-			h.lastActivity = time.Now()
-			// タイムアウトタイマーをリセット
-			h.resetDialogueTimeout()
+		format := audio.FormatRealtime
+		if req.SampleRate > 0 {
+			format.SampleRate = req.SampleRate
 		}
-		h.dialogueStateMutex.Unlock()
+		if req.Channels > 0 {
+			format.Channels = req.Channels
+		}
+		if err := format.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("Received audio input: %d bytes (%s)", len(req.Audio), format)
 
 		// Base64デコードして実際のバイト数を確認
-		if decoded, err := base64.StdEncoding.DecodeString(req.Audio); err == nil {
-			log.Printf("Decoded audio data: %d bytes (PCM16 samples: %d)", len(decoded), len(decoded)/2)
-			// 24kHzで100ms = 2400サンプル = 4800バイト
-			durationMs := float64(len(decoded)/2) / 24000.0 * 1000.0
-			log.Printf("Audio duration: %.2f ms", durationMs)
-
-			// 25ms未満の場合は警告
-			if durationMs < 25.0 {
-				log.Printf("WARNING: Audio duration (%.2f ms) is less than 25ms, may cause buffer commit errors", durationMs)
-			}
-		} else {
+		decoded, err := base64.StdEncoding.DecodeString(req.Audio)
+		if err != nil {
 			log.Printf("Failed to decode base64 audio data: %v", err)
+			http.Error(w, "Invalid audio data", http.StatusBadRequest)
+			return
+		}
+		if err := h.handleCallerAudio(decoded, format); err != nil {
+			http.Error(w, "Failed to send audio", http.StatusInternalServerError)
+			return
 		}
 
-		// OpenAI Realtimeに音声データを送信
-		if h.dialogueConn != nil {
-			audioMessage := map[string]interface{}{
-				"type":  "input_audio_buffer.append",
-				"audio": req.Audio,
-			}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status": "audio_sent",
+		})
+	})
+
+	// 対話モード用の音声コミットエンドポイント
+	http.HandleFunc("/audio/commit", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		log.Printf("Received audio commit request")
+
+		if err := h.commitCallerAudio(); err != nil {
+			http.Error(w, "Failed to send commit", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status": "commit_sent",
+		})
+	})
+
+	// 対話終了エンドポイント
+	http.HandleFunc("/dialogue/end", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		log.Printf("Received dialogue end request")
+
+		// 対話モードを終了
+		h.endDialogueMode(dialogue.ReasonHostEnded)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status": "dialogue_ended",
+		})
+	})
 
-			log.Printf("Sending audio to OpenAI Realtime: %d bytes, base64 length: %d", len(req.Audio), len(req.Audio))
+	// 対話状態確認エンドポイント
+	http.HandleFunc("/dialogue/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 
-			if err := h.dialogueConn.WriteJSON(audioMessage); err != nil {
-				log.Printf("Failed to send audio to OpenAI: %v", err)
-				http.Error(w, "Failed to send audio", http.StatusInternalServerError)
+		current, waiting := h.dialogue.Status()
+		status := map[string]interface{}{
+			"active":  current != nil && current.Live(),
+			"session": current,
+			"waiting": waiting,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	})
+
+	// APIで起きた通話の状態遷移（リクエスト・終了の要求）を受け取るエンドポイント
+	http.HandleFunc("/dialogue/events", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var t dialogue.Transition
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil || t.ID == "" || t.Event == "" {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		applied, err := h.applyDialogueEvent(t)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(applied)
+	})
+
+	// 再生状態確認エンドポイント
+	http.HandleFunc("/playout/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		player := h.player
+		if player == nil {
+			http.Error(w, "Player not initialized", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(player.Status())
+	})
+
+	// 再生制御エンドポイント（一時停止・再開・破棄）
+	playoutControls := map[string]func(*audio.Player){
+		"/playout/pause":  (*audio.Player).Pause,
+		"/playout/resume": (*audio.Player).Resume,
+		"/playout/flush": func(p *audio.Player) {
+			h.fadeOutAndFlush(mixer.BusVoice, p)
+		},
+	}
+	for path, control := range playoutControls {
+		http.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			player := h.player
+			if player == nil {
+				http.Error(w, "Player not initialized", http.StatusServiceUnavailable)
 				return
 			}
 
-			log.Printf("Audio data sent successfully to OpenAI Realtime")
-		} else {
-			log.Printf("Dialogue connection is nil, cannot send audio")
+			log.Printf("Received playout control request: %s", r.URL.Path)
+			control(player)
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(player.Status())
+		})
+	}
+
+	// 内部イベント受信エンドポイント
+	http.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var event HostEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
 		}
 
-		// ユーザー音声をLiveKitに送信
-		h.publishUserAudioToLiveKit(req.Audio)
+		log.Printf("Received event: EVENT.%s", event.Type)
+		switch event.Type {
+		case "TOP_OF_HOUR":
+			go h.playTimeSignal(event.Hour, event.Theme)
+		default:
+			http.Error(w, "Unknown event type", http.StatusBadRequest)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{
-			"status": "audio_sent",
-		})
+		json.NewEncoder(w).Encode(map[string]string{"status": "accepted"})
 	})
 
-	// 対話モード用の音声コミットエンドポイント
-	http.HandleFunc("/audio/commit", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "POST" {
+	// TTSキャッシュの状態確認エンドポイント
+	http.HandleFunc("/tts/cache", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		if h.ttsCache == nil {
+			http.Error(w, "TTS cache disabled", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.ttsCache.Stats())
+	})
 
-		log.Printf("Received audio commit request")
+	// 番組進行（現在のセグメント）確認エンドポイント
+	http.HandleFunc("/director/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 
-		// OpenAI Realtimeにコミット信号を送信
-		if h.dialogueConn != nil {
-			commitMessage := map[string]interface{}{
-				"type": "input_audio_buffer.commit",
-			}
-			if err := h.dialogueConn.WriteJSON(commitMessage); err != nil {
-				log.Printf("Failed to send commit to OpenAI: %v", err)
-				http.Error(w, "Failed to send commit", http.StatusInternalServerError)
-				return
-			}
+		segment, ok := h.director.Segment()
+		if !ok {
+			http.Error(w, "No segment on air yet", http.StatusServiceUnavailable)
+			return
 		}
 
+		now := time.Now()
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{
-			"status": "commit_sent",
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"block":        segment.Slot.Block,
+			"segment":      segment.Name(),
+			"prompt":       segment.Slot.Prompt,
+			"start":        segment.Start.Format(time.RFC3339),
+			"end":          segment.End().Format(time.RFC3339),
+			"remaining_ms": segment.Remaining(now).Milliseconds(),
+			"phase":        segment.Phase(now),
+			"lookahead":    h.lookahead.Status(),
+			"persona":      h.personas.Current(),
 		})
 	})
 
-	// 対話終了エンドポイント
-	http.HandleFunc("/dialogue/end", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "POST" {
+	// 送出ディレイの状態確認エンドポイント
+	http.HandleFunc("/delay", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		log.Printf("Received dialogue end request")
-
-		// 対話モードを終了
-		h.endDialogueMode()
-
+		state := h.delay.State()
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{
-			"status": "dialogue_ended",
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"target_ms":   state.Target.Milliseconds(),
+			"buffered_ms": state.Buffered.Milliseconds(),
+			"dumps":       state.Dumps,
 		})
 	})
 
-	// 対話状態確認エンドポイント
-	http.HandleFunc("/dialogue/status", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "GET" {
+	// 送出ディレイのダンプエンドポイント（まだ流れていない音声を捨ててつなぎを流す）
+	http.HandleFunc("/delay/dump", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		h.dialogueStateMutex.RLock()
-		active := h.dialogueMode
-		startedAt := h.dialogueStartedAt
-		lastActivity := h.lastActivity
-		h.dialogueStateMutex.RUnlock()
+		dumped, ok := h.dumpDelay()
+		if !ok {
+			http.Error(w, "Broadcast delay is not active", http.StatusConflict)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":    "dumped",
+			"dumped_ms": dumped.Milliseconds(),
+		})
+	})
 
-		status := map[string]interface{}{
-			"active":        active,
-			"started_at":    startedAt.Format(time.RFC3339),
-			"last_activity": lastActivity.Format(time.RFC3339),
+	// ミキサー状態の取得・変更エンドポイント
+	http.HandleFunc("/mixer", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+		case "PUT":
+			var req MixerUpdateRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+			if err := h.updateMixer(req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(status)
+		json.NewEncoder(w).Encode(h.mixer.Snapshot())
 	})
 
 	log.Printf("Starting HTTP server on port %s", port)
@@ -1018,6 +2198,45 @@ func (h *HostAgent) startHTTPServer() {
 	time.Sleep(1 * time.Second)
 }
 
+// updateMixer PUT /mixer の内容をミキサーに反映
+//
+// 途中まで反映された状態を残さないよう、先にすべての値を検証してから変更する。
+func (h *HostAgent) updateMixer(req MixerUpdateRequest) error {
+	for name := range req.Buses {
+		if !h.mixer.HasBus(name) {
+			return fmt.Errorf("unknown bus: %s", name)
+		}
+	}
+	if req.DuckDurationMs != nil && *req.DuckDurationMs <= 0 {
+		return fmt.Errorf("duck_duration_ms must be positive")
+	}
+
+	for name, gain := range req.Buses {
+		if err := h.mixer.SetBusGain(name, gain); err != nil {
+			return err
+		}
+	}
+	if req.DuckLevelDB != nil {
+		h.mixer.SetDuckLevel(*req.DuckLevelDB)
+	}
+	if req.DuckDurationMs != nil {
+		h.mixer.SetDuckDuration(time.Duration(*req.DuckDurationMs) * time.Millisecond)
+	}
+
+	// ダッキングのON/OFFはコールバック経由で MIXER_DUCK_ON/OFF として配信される
+	if req.Ducked != nil && *req.Ducked != h.mixer.IsDucked() {
+		if *req.Ducked {
+			h.mixer.DuckOn()
+		} else {
+			h.mixer.DuckOff()
+		}
+		return nil
+	}
+
+	go h.broadcastMixerState("MIXER_UPDATED")
+	return nil
+}
+
 // monitorQueue キューを監視して対話リクエストを処理
 func (h *HostAgent) monitorQueue() {
 	ticker := time.NewTicker(5 * time.Second)
@@ -1031,14 +2250,20 @@ func (h *HostAgent) monitorQueue() {
 			log.Println("Queue monitoring stopped")
 			return
 		case <-ticker.C:
+			h.expireDialogue()
 			h.checkQueue()
 		}
 	}
 }
 
-// checkQueue キューをチェックして対話リクエストを処理
+// checkQueue 通話が空いていれば待合室から次の通話を始める
 func (h *HostAgent) checkQueue() {
-	// APIサーバーのキューエンドポイントをチェック
+	// 通話中なら終わるまで待合室で待ってもらう
+	if h.inDialogue() {
+		return
+	}
+
+	// APIサーバーの待合室をチェック
 	apiBase := getEnv("API_BASE", "http://api:8080")
 
 	// HTTPクライアントにタイムアウトを設定
@@ -1059,174 +2284,269 @@ func (h *HostAgent) checkQueue() {
 		return
 	}
 
-	resp, err := client.Get(apiBase + "/v1/queue/peek")
+	// 待合室の先頭（番組側で選ばれた人、いなければ最初にリクエストした人）
+	resp, err := client.Get(apiBase + "/v1/dialogue/next")
 	if err != nil {
-		log.Printf("Failed to check queue: %v", err)
+		log.Printf("Failed to check dialogue waiting room: %v", err)
 		return
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("Queue peek returned status: %d", resp.StatusCode)
+		log.Printf("Dialogue waiting room returned status: %d", resp.StatusCode)
+		return
+	}
+
+	var next struct {
+		Session *dialogue.Session `json:"session"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&next); err != nil {
+		log.Printf("Failed to decode waiting room response: %v", err)
 		return
 	}
 
+	if next.Session != nil {
+		log.Printf("Next caller from waiting room: %s (client %s, topic %q)", next.Session.ID, next.Session.ClientID, next.Session.Topic)
+		h.startDialogueMode(next.Session.ID, next.Session.ClientID, next.Session.Summary)
+	}
+}
+
+// AnswerQuestion キュー先頭のリスナー投稿を取り出して答える（QANDA枠用）
+func (h *HostAgent) AnswerQuestion() bool {
+	apiBase := getEnv("API_BASE", "http://api:8080")
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+	}
+
+	resp, err := client.Get(apiBase + "/v1/queue/peek")
+	if err != nil {
+		log.Printf("Failed to check queue: %v", err)
+		return false
+	}
+	defer resp.Body.Close()
+
 	var queueData struct {
 		Item *struct {
 			ID     string `json:"id"`
-			UserID string `json:"user_id"`
 			Kind   string `json:"kind"`
 			Text   string `json:"text"`
 			Status string `json:"status"`
 		} `json:"item"`
 	}
-
 	if err := json.NewDecoder(resp.Body).Decode(&queueData); err != nil {
 		log.Printf("Failed to decode queue response: %v", err)
-		return
+		return false
 	}
 
-	if queueData.Item != nil && queueData.Item.Kind == "dialogue" && queueData.Item.Status == "queued" {
-		log.Printf("Dialogue request found in queue: %s", queueData.Item.ID)
+	item := queueData.Item
+	if item == nil || item.Status != "queued" || item.Kind == "dialogue" || item.Text == "" {
+		return false
+	}
 
-		// クライアントIDを取得してからキューから削除
-		clientID := queueData.Item.UserID
-		log.Printf("Found client ID for dialogue request %s: %s", queueData.Item.ID, clientID)
+	h.dequeueItem(item.ID)
+	log.Printf("Answering listener question: %s", item.Text)
+	go h.recordMemory("caller", "リスナーの投稿", item.Text)
 
-		// キューからアイテムを削除して対話モードを開始
-		h.dequeueDialogueRequest(queueData.Item.ID)
-		h.startDialogueModeWithClientID(queueData.Item.ID, clientID)
+	prompt := fmt.Sprintf("リスナーから「%s」という投稿が届きました。ラジオDJとして30秒程度で紹介し、答えてください。", item.Text)
+	script, err := h.generateScript(h.ctx, prompt)
+	if err != nil {
+		log.Printf("Failed to generate answer: %v", err)
+		script = fmt.Sprintf("リスナーの方から「%s」というメッセージをいただきました。ありがとうございます。", item.Text)
 	}
+	h.sendMessage(script)
+	go h.recordAiredScript("リスナーへの回答", script, "")
+	return true
 }
 
-// startDialogueModeWithClientID クライアントIDを指定して対話モードを開始
-func (h *HostAgent) startDialogueModeWithClientID(requestID, clientID string) {
-	h.dialogueStateMutex.Lock()
-	defer h.dialogueStateMutex.Unlock()
+// setupDialogue 通話の状態管理（無音タイムアウトは DIALOGUE_IDLE_TIMEOUT、デフォルト3分）
+func (h *HostAgent) setupDialogue() {
+	timeout, err := time.ParseDuration(getEnv("DIALOGUE_IDLE_TIMEOUT", dialogue.DefaultIdleTimeout.String()))
+	if err != nil || timeout <= 0 {
+		timeout = dialogue.DefaultIdleTimeout
+	}
+	h.dialogue = dialogue.NewMachine(timeout)
+	h.dialogueEventsReady = make(chan struct{}, 1)
+	h.dialogue.OnTransition = h.queueDialogueTransition
+	h.callerLink = callerlink.NewServer(h.handleCallerFrame)
+}
 
-	if h.dialogueMode {
-		log.Printf("Dialogue mode already active, ignoring request: %s", requestID)
-		return // 既に対話モード中
+// queueDialogueTransition Hostで起きた遷移をAPIへ送る列に積む（Fire を呼んだ側は待たせない）
+func (h *HostAgent) queueDialogueTransition(t dialogue.Transition) {
+	h.dialogueEventsMutex.Lock()
+	h.dialogueEvents = append(h.dialogueEvents, t)
+	h.dialogueEventsMutex.Unlock()
+	select {
+	case h.dialogueEventsReady <- struct{}{}:
+	default:
 	}
+}
 
-	log.Printf("Starting dialogue mode for request: %s, client: %s", requestID, clientID)
-	h.dialogueMode = true
-	h.dialogueStartedAt = time.Now()
-	h.lastActivity = time.Now()
+// nextDialogueTransition 次にAPIへ送る遷移（送り終えるまで列から外さない）
+func (h *HostAgent) nextDialogueTransition() (dialogue.Transition, bool) {
+	h.dialogueEventsMutex.Lock()
+	defer h.dialogueEventsMutex.Unlock()
+	if len(h.dialogueEvents) == 0 {
+		return dialogue.Transition{}, false
+	}
+	return h.dialogueEvents[0], true
+}
 
-	// 3分のタイムアウトタイマーを開始
-	go h.startDialogueTimeout()
+// doneDialogueTransition 先頭の遷移を送り終えた
+func (h *HostAgent) doneDialogueTransition() {
+	h.dialogueEventsMutex.Lock()
+	defer h.dialogueEventsMutex.Unlock()
+	h.dialogueEvents = h.dialogueEvents[1:]
+	if len(h.dialogueEvents) == 0 {
+		h.dialogueEvents = nil
+	}
+}
 
-	// 現在のTTSを停止（必要に応じて）
-	log.Println("Stopping current TTS for dialogue mode")
-	h.stopCurrentAudio()
+// inDialogue 通話の接続中・放送中・終了処理中か
+func (h *HostAgent) inDialogue() bool {
+	_, ok := h.dialogue.Live()
+	return ok
+}
 
-	// 音声バッファをクリア
-	if h.pcmWriter != nil {
-		h.pcmWriter.ClearBuffer()
+// touchDialogue 通話の音声が流れた（無音タイムアウトを延ばす）
+func (h *HostAgent) touchDialogue() {
+	if session, ok := h.dialogue.Live(); ok {
+		h.dialogue.Touch(session.ID)
 	}
+}
 
-	// 新しい音声トラックを作成（対話モード用）
-	if err := h.createNewAudioTrack(); err != nil {
-		log.Printf("Failed to create new audio track: %v", err)
-		h.dialogueMode = false
-		return
+// expireDialogue 無音のまま時間が経った通話を終了する
+func (h *HostAgent) expireDialogue() {
+	for _, t := range h.dialogue.Expire() {
+		log.Printf("Dialogue %s timed out (%s)", t.ID, t.From)
+		if t.To == dialogue.Ended {
+			go h.abortDialogue(t.Reason)
+		} else {
+			go h.endDialogueMode(t.Reason)
+		}
+	}
+}
+
+// applyDialogueEvent APIで起きた通話の状態遷移を反映する
+func (h *HostAgent) applyDialogueEvent(t dialogue.Transition) (dialogue.Transition, error) {
+	applied, err := h.dialogue.Apply(t)
+	if err != nil {
+		log.Printf("Dialogue event ignored: %v", err)
+		return applied, err
 	}
+	log.Printf("Dialogue %s (%s): %s -> %s", applied.ID, applied.Event, applied.From, applied.To)
 
-	// ユーザー音声トラックを作成
-	if err := h.createUserAudioTrack(); err != nil {
-		log.Printf("Failed to create user audio track: %v", err)
-		h.dialogueMode = false
-		return
+	switch {
+	case applied.To == dialogue.WrappingUp:
+		// 終了が決まったら通話音声を下げて締めの挨拶へ
+		go h.endDialogueMode(applied.Reason)
+	case applied.To == dialogue.Ended && (applied.From == dialogue.Connecting || applied.From == dialogue.OnAir):
+		// 締めの挨拶をせずに終わった
+		go h.abortDialogue(applied.Reason)
 	}
+	return applied, nil
+}
 
-	// OpenAI Realtime接続を開始
-	if err := h.connectToOpenAIRealtime(); err != nil {
-		log.Printf("Failed to connect to OpenAI Realtime: %v", err)
-		h.dialogueMode = false
+// abortDialogue 締めの挨拶をせずに終わった通話を片付けて通常放送へ戻る
+func (h *HostAgent) abortDialogue(reason string) {
+	h.dialogueStateMutex.Lock()
+	defer h.dialogueStateMutex.Unlock()
+
+	if h.inDialogue() {
+		// 片付ける前に次の通話が始まっている
 		return
 	}
+	log.Printf("Dialogue aborted (%s)", reason)
+	h.teardownDialogue()
+}
 
-	// 対話開始の通知を送信（クライアントIDを含む）
-	log.Printf("Sending dialogue_ready notification for request: %s, client: %s", requestID, clientID)
-	h.sendDialogueNotificationWithClientID("dialogue_ready", requestID, clientID)
+// teardownDialogue Realtimeの接続を切り、通話音声と送出ディレイを外す（dialogueStateMutex を保持して呼ぶ）
+func (h *HostAgent) teardownDialogue() {
+	if h.dialogueConn != nil {
+		log.Println("Closing OpenAI Realtime connection")
+		h.dialogueConn.Close()
+		h.dialogueConn = nil
+	}
+	h.dialogueTranscript = nil
+	h.dialogueBriefing = ""
+	h.fadeOutAndFlush(mixer.BusCaller, h.userPlayer)
+	h.delay.Set(0, nil)
 }
 
-// startDialogueMode 対話モードを開始（後方互換性のため）
-func (h *HostAgent) startDialogueMode(requestID string) {
+// startDialogueMode 待合室から取り出した対話リクエストの通話を始める（briefing はスクリーニングの要約）
+func (h *HostAgent) startDialogueMode(requestID, clientID, briefing string) {
 	h.dialogueStateMutex.Lock()
 	defer h.dialogueStateMutex.Unlock()
 
-	if h.dialogueMode {
-		log.Printf("Dialogue mode already active, ignoring request: %s", requestID)
-		return // 既に対話モード中
+	if _, err := h.dialogue.Accept(requestID, clientID); err != nil {
+		log.Printf("Cannot start dialogue %s: %v", requestID, err)
+		return
 	}
 
-	log.Printf("Starting dialogue mode for request: %s", requestID)
-	h.dialogueMode = true
-	h.dialogueStartedAt = time.Now()
-	h.lastActivity = time.Now()
-
-	// 3分のタイムアウトタイマーを開始
-	go h.startDialogueTimeout()
+	log.Printf("Starting dialogue mode for request: %s, client: %s", requestID, clientID)
+	h.dialogueTranscript = nil
+	h.dialogueBriefing = briefing
 
-	// 現在のTTSを停止（必要に応じて）
-	log.Println("Stopping current TTS for dialogue mode")
-	h.stopCurrentAudio()
+	// 通話の後は状況が変わるので先読みした台本は使わない
+	h.lookahead.Invalidate("dialogue started")
 
-	// 音声バッファをクリア
-	if h.pcmWriter != nil {
-		h.pcmWriter.ClearBuffer()
+	// 通話の間は番組出力を遅らせる（遅延ができるまでは音楽ベッドでつなぐ）
+	if h.broadcastDelay > 0 {
+		h.delay.Set(h.broadcastDelay, h.delayFiller())
 	}
 
-	// 新しい音声トラックを作成（対話モード用）
-	if err := h.createNewAudioTrack(); err != nil {
-		log.Printf("Failed to create new audio track: %v", err)
-		h.dialogueMode = false
-		return
-	}
+	// 現在のTTSをフェードアウトして破棄し、通話音声をフェードインで立ち上げる
+	log.Println("Fading out current TTS for dialogue mode")
+	h.fadeOutAndFlush(mixer.BusVoice, h.player)
+	h.userPlayer.Flush()
+	h.mixer.FadeIn(mixer.BusCaller, transitionFade)
 
-	// ユーザー音声トラックを作成
-	if err := h.createUserAudioTrack(); err != nil {
-		log.Printf("Failed to create user audio track: %v", err)
-		h.dialogueMode = false
-		return
-	}
+	// 前回の発信者音声の変換状態を破棄
+	h.callerInputMutex.Lock()
+	h.callerInput = nil
+	h.callerInputMutex.Unlock()
 
 	// OpenAI Realtime接続を開始
-	if err := h.connectToOpenAIRealtime(); err != nil {
+	if err := h.connectRealtime(); err != nil {
 		log.Printf("Failed to connect to OpenAI Realtime: %v", err)
-		h.dialogueMode = false
+		h.dialogue.Fire(requestID, dialogue.EventEnd, dialogue.ReasonConnectFailed)
+		h.teardownDialogue()
 		return
 	}
 
-	// 対話開始の通知を送信
-	log.Printf("Sending dialogue_ready notification for request: %s", requestID)
-	h.sendDialogueNotification("dialogue_ready", requestID)
+	// 放送開始（APIが dialogue_ready をクライアントに配信する）
+	if _, err := h.dialogue.Fire(requestID, dialogue.EventConnected, ""); err != nil {
+		// 接続している間に終わった（取り下げ・APIに受け付けられなかったなど）
+		log.Printf("Dialogue %s ended while connecting: %v", requestID, err)
+		h.teardownDialogue()
+	}
 }
 
-// endDialogueMode 対話モードを終了
-func (h *HostAgent) endDialogueMode() {
+// endDialogueMode 通話を終了処理に進め、通常放送へ戻る
+func (h *HostAgent) endDialogueMode(reason string) {
 	h.dialogueStateMutex.Lock()
 	defer h.dialogueStateMutex.Unlock()
 
-	if !h.dialogueMode {
+	session, ok := h.dialogue.Live()
+	if !ok {
 		log.Println("Dialogue mode not active, nothing to end")
 		return
 	}
+	if session.State != dialogue.WrappingUp {
+		h.dialogue.Fire(session.ID, dialogue.EventWrapUp, reason)
+	}
 
-	log.Println("Ending dialogue mode")
-	h.dialogueMode = false
+	log.Printf("Ending dialogue mode (%s)", reason)
 
-	// タイムアウトチャンネルをクリア
-	select {
-	case <-h.dialogueTimeoutChan:
-		// チャンネルが既に空の場合は何もしない
-	default:
-		// チャンネルに何かある場合はクリア
+	// 通話の内容を記憶に残す
+	if len(h.dialogueTranscript) > 0 {
+		text := "通話でのDJの応答: " + strings.Join(h.dialogueTranscript, " ")
+		if h.dialogueBriefing != "" {
+			text = "リスナーの話: " + h.dialogueBriefing + " / " + text
+		}
+		go h.recordMemory("caller", "リスナーとの通話", text)
+		h.dialogueTranscript = nil
 	}
-	// 新しい空のチャンネルを作成
-	h.dialogueTimeoutChan = make(chan struct{}, 1)
+	h.dialogueBriefing = ""
 
 	// OpenAI Realtime接続を切断
 	if h.dialogueConn != nil {
@@ -1235,89 +2555,139 @@ func (h *HostAgent) endDialogueMode() {
 		h.dialogueConn = nil
 	}
 
-	// 対話モード用の音声トラックを停止
-	h.stopCurrentAudio()
+	// 通話音声をフェードアウトし、スイーパーを挟んで通常放送へ戻る
+	h.fadeOutAndFlush(mixer.BusCaller, h.userPlayer)
+	h.InsertSweeper()
 
-	// ユーザー音声トラックを停止
-	h.stopUserAudio()
-
-	// 通常のラジオ放送用の音声トラックを作成
-	if err := h.createNewAudioTrack(); err != nil {
-		log.Printf("Failed to create normal audio track: %v", err)
-	} else {
-		log.Println("Normal audio track created")
-	}
+	// 対話中に生成が終わった台本も捨てる
+	h.lookahead.Invalidate("dialogue ended")
 
 	// 通常のラジオ放送を再開
 	log.Println("Resuming normal radio broadcast")
-	h.sendMessage("ありがとうございました。通常のラジオ放送に戻ります。")
+	h.sendMessage(outroText)
+
+	// 送出ディレイを外す（静かなところを詰めて生に戻る）
+	h.delay.Set(0, nil)
 
-	// 対話終了の通知を送信
-	log.Printf("Sending dialogue_ended notification")
-	h.sendDialogueNotification("dialogue_ended", "")
+	// 終了（APIが dialogue_ended をクライアントに配信する）
+	h.dialogue.Fire(session.ID, dialogue.EventEnd, "")
 }
 
-// sendDialogueNotification 対話状態の変更を通知
-func (h *HostAgent) sendDialogueNotification(notificationType, requestID string) {
-	apiBase := getEnv("API_BASE", "http://api:8080")
+// dialogueRetryMax APIへ状態遷移を送り直す間隔の上限
+const dialogueRetryMax = 10 * time.Second
 
-	payload := map[string]interface{}{
-		"type": notificationType,
-	}
-	if requestID != "" {
-		payload["id"] = requestID
+// forwardDialogueTransitions Hostで起きた通話の状態遷移を起きた順にAPIへ送る
+//
+// 送れなかった遷移は捨てずに、間隔を空けながら届くまで送り直す（後の遷移はその後に送る）。
+func (h *HostAgent) forwardDialogueTransitions() {
+	wait := 500 * time.Millisecond
+	for {
+		t, ok := h.nextDialogueTransition()
+		if !ok {
+			select {
+			case <-h.ctx.Done():
+				return
+			case <-h.dialogueEventsReady:
+			}
+			continue
+		}
+
+		status, err := h.sendDialogueTransition(t)
+		if err != nil {
+			log.Printf("Failed to send dialogue %s for %s (retrying in %v): %v", t.Event, t.ID, wait, err)
+			select {
+			case <-h.ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			if wait *= 2; wait > dialogueRetryMax {
+				wait = dialogueRetryMax
+			}
+			continue
+		}
+		wait = 500 * time.Millisecond
+		h.doneDialogueTransition()
+
+		switch {
+		case status == http.StatusConflict && t.Event == dialogue.EventAccept:
+			// APIの待合室にもういない（取り下げ済みなど）ので、始めかけた通話をやめる
+			log.Printf("Dialogue %s rejected by API", t.ID)
+			h.dialogue.Fire(t.ID, dialogue.EventEnd, dialogue.ReasonRejected)
+			go h.abortDialogue(dialogue.ReasonRejected)
+		case status == http.StatusConflict:
+			// APIの方が先に進んでいる（重複・終了済みなど）。送り直しても受け付けられない
+			log.Printf("Dialogue %s for %s ignored by API", t.Event, t.ID)
+		default:
+			log.Printf("Dialogue %s sent: %s -> %s (request: %s)", t.Event, t.From, t.To, t.ID)
+		}
 	}
+}
 
-	jsonData, _ := json.Marshal(payload)
+// sendDialogueTransition 状態遷移を1件APIへ送り、受け付けた（2xx）か 409 ならそのステータスを返す
+//
+// APIからのストリームがつながっていれば同じ接続で送り、つながっていない・ack が来ない時はHTTPで送る。
+func (h *HostAgent) sendDialogueTransition(t dialogue.Transition) (int, error) {
+	if h.callerLink.Connected() {
+		ctx, cancel := context.WithTimeout(h.ctx, 2*time.Second)
+		ack, err := h.callerLink.Send(ctx, callerlink.Frame{Kind: callerlink.KindControl, Dialogue: &t})
+		cancel()
+		if err == nil {
+			return acceptedStatus(ack.Status)
+		}
+		log.Printf("Caller stream unavailable for dialogue %s, sending over HTTP: %v", t.Event, err)
+	}
 
-	// HTTPクライアントにタイムアウトを設定
+	apiBase := getEnv("API_BASE", "http://api:8080")
 	client := &http.Client{
 		Timeout: 5 * time.Second,
 	}
 
-	resp, err := client.Post(apiBase+"/v1/broadcast", "application/json", bytes.NewBuffer(jsonData))
+	jsonData, _ := json.Marshal(t)
+	resp, err := client.Post(apiBase+"/v1/dialogue/events", "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
-		log.Printf("Failed to send dialogue notification: %v", err)
-		return
+		return 0, err
 	}
-	defer resp.Body.Close()
+	resp.Body.Close()
+	return acceptedStatus(resp.StatusCode)
+}
 
-	log.Printf("Dialogue notification sent: %s (requestID: %s)", notificationType, requestID)
+// acceptedStatus 2xx と 409 以外は送れなかったものとして扱う
+func acceptedStatus(status int) (int, error) {
+	if status != http.StatusConflict && (status < 200 || status >= 300) {
+		return status, fmt.Errorf("API returned status %d", status)
+	}
+	return status, nil
 }
 
-// sendDialogueNotificationWithClientID クライアントIDを指定して対話状態の変更を通知
-func (h *HostAgent) sendDialogueNotificationWithClientID(notificationType, requestID, clientID string) {
+// sendBroadcast API経由で全クライアントにメッセージを配信
+func (h *HostAgent) sendBroadcast(msgType string, data map[string]interface{}) {
 	apiBase := getEnv("API_BASE", "http://api:8080")
 
-	payload := map[string]interface{}{
-		"type": notificationType,
-	}
-	if requestID != "" {
-		payload["id"] = requestID
-	}
-	if clientID != "" {
-		payload["client_id"] = clientID
+	payload := map[string]interface{}{"type": msgType}
+	for k, v := range data {
+		payload[k] = v
 	}
 
-	jsonData, _ := json.Marshal(payload)
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to marshal %s broadcast: %v", msgType, err)
+		return
+	}
 
-	// HTTPクライアントにタイムアウトを設定
 	client := &http.Client{
 		Timeout: 5 * time.Second,
 	}
 
 	resp, err := client.Post(apiBase+"/v1/broadcast", "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
-		log.Printf("Failed to send dialogue notification: %v", err)
+		log.Printf("Failed to send %s broadcast: %v", msgType, err)
 		return
 	}
 	defer resp.Body.Close()
-
-	log.Printf("Dialogue notification sent: %s (requestID: %s, clientID: %s)", notificationType, requestID, clientID)
 }
 
-// dequeueDialogueRequest キューから対話リクエストを削除
-func (h *HostAgent) dequeueDialogueRequest(requestID string) {
+// dequeueItem キューからアイテム（対話リクエスト・投稿）を削除
+func (h *HostAgent) dequeueItem(requestID string) {
 	apiBase := getEnv("API_BASE", "http://api:8080")
 
 	payload := map[string]interface{}{
@@ -1341,166 +2711,6 @@ func (h *HostAgent) dequeueDialogueRequest(requestID string) {
 	log.Printf("Dialogue request dequeued: %s", requestID)
 }
 
-// stopCurrentAudio 現在の音声トラックを停止・削除
-func (h *HostAgent) stopCurrentAudio() {
-	if h.audioPublication != nil {
-		log.Println("Stopping and unpublishing current audio track")
-
-		// トラックを停止
-		if h.pcmTrack != nil {
-			h.pcmTrack.Close()
-		}
-
-		// トラックをアンパブリッシュ
-		err := h.room.LocalParticipant.UnpublishTrack(h.audioPublication.SID())
-		if err != nil {
-			log.Printf("Failed to unpublish track: %v", err)
-		} else {
-			log.Println("Audio track unpublished successfully")
-		}
-
-		h.audioPublication = nil
-		h.pcmTrack = nil
-		h.pcmWriter = nil
-	}
-}
-
-// stopUserAudio ユーザー音声トラックを停止
-func (h *HostAgent) stopUserAudio() {
-	if h.userAudioPublication != nil {
-		log.Println("Stopping and unpublishing user audio track")
-
-		// ユーザートラックを停止
-		if h.userPcmTrack != nil {
-			h.userPcmTrack.Close()
-		}
-
-		// ユーザートラックをアンパブリッシュ
-		err := h.room.LocalParticipant.UnpublishTrack(h.userAudioPublication.SID())
-		if err != nil {
-			log.Printf("Failed to unpublish user track: %v", err)
-		} else {
-			log.Println("User audio track unpublished successfully")
-		}
-
-		h.userAudioPublication = nil
-		h.userPcmTrack = nil
-		h.userPcmWriter = nil
-	}
-}
-
-// createNewAudioTrack 新しい音声トラックを作成
-func (h *HostAgent) createNewAudioTrack() error {
-	log.Println("Creating new audio track for dialogue mode")
-
-	// 新しいPCMオーディオトラックを作成
-	var err error
-	h.pcmTrack, err = lkmedia.NewPCMLocalTrack(24000, 1, nil) // 24kHz, mono
-	if err != nil {
-		return fmt.Errorf("failed to create new PCM audio track: %w", err)
-	}
-
-	// PCMWriterを初期化
-	h.pcmWriter = NewPCMWriter(h.pcmTrack)
-
-	// 新しいトラックをルームに公開
-	h.audioPublication, err = h.room.LocalParticipant.PublishTrack(h.pcmTrack, &lksdk.TrackPublicationOptions{
-		Name: "radio-24-host-dialogue",
-	})
-	if err != nil {
-		return fmt.Errorf("failed to publish new track: %w", err)
-	}
-
-	log.Println("New audio track created and published successfully")
-	return nil
-}
-
-// createUserAudioTrack ユーザー音声用のトラックを作成
-func (h *HostAgent) createUserAudioTrack() error {
-	log.Println("Creating user audio track")
-
-	// ユーザー音声用のPCMオーディオトラックを作成
-	var err error
-	h.userPcmTrack, err = lkmedia.NewPCMLocalTrack(24000, 1, nil) // 24kHz, mono
-	if err != nil {
-		return fmt.Errorf("failed to create user PCM audio track: %w", err)
-	}
-
-	// ユーザー音声用PCMWriterを初期化
-	h.userPcmWriter = NewPCMWriter(h.userPcmTrack)
-
-	// ユーザー音声トラックをルームに公開
-	h.userAudioPublication, err = h.room.LocalParticipant.PublishTrack(h.userPcmTrack, &lksdk.TrackPublicationOptions{
-		Name: "radio-24-user-input",
-	})
-	if err != nil {
-		return fmt.Errorf("failed to publish user track: %w", err)
-	}
-
-	log.Println("User audio track created and published successfully")
-	return nil
-}
-
-// startDialogueTimeout 対話モードの3分タイムアウト処理
-func (h *HostAgent) startDialogueTimeout() {
-	log.Println("Starting dialogue timeout timer (3 minutes)")
-
-	// 3分後にタイムアウトチャンネルに信号を送信
-	time.AfterFunc(3*time.Minute, func() {
-		select {
-		case h.dialogueTimeoutChan <- struct{}{}:
-			log.Println("Dialogue timeout signal sent")
-		default:
-			// チャンネルが満杯の場合は何もしない（既にタイムアウト処理が実行中）
-		}
-	})
-
-	// タイムアウト信号を待機
-	select {
-	case <-h.dialogueTimeoutChan:
-		// タイムアウトが発生した場合
-		h.dialogueStateMutex.Lock()
-		if h.dialogueMode {
-			log.Println("Dialogue mode timeout (3 minutes) - ending dialogue")
-			h.dialogueMode = false
-			h.dialogueStateMutex.Unlock()
-
-			// 対話モードを終了
-			h.endDialogueMode()
-
-			// タイムアウト通知を送信
-			h.sendDialogueNotification("dialogue_timeout", "timeout")
-		} else {
-			h.dialogueStateMutex.Unlock()
-		}
-	case <-h.ctx.Done():
-		// コンテキストがキャンセルされた場合
-		log.Println("Dialogue timeout cancelled due to context cancellation")
-	}
-}
-
-// resetDialogueTimeout 対話モードのタイムアウトタイマーをリセット
-func (h *HostAgent) resetDialogueTimeout() {
-	// タイムアウトチャンネルをクリアして新しいタイマーを開始
-	select {
-	case <-h.dialogueTimeoutChan:
-		// 既存のタイムアウト信号をクリア
-	default:
-		// チャンネルが空の場合は何もしない
-	}
-
-	// 新しい3分タイマーを開始
-	go func() {
-		time.Sleep(3 * time.Minute)
-		select {
-		case h.dialogueTimeoutChan <- struct{}{}:
-			log.Println("Dialogue timeout signal sent (after reset)")
-		default:
-			// チャンネルが満杯の場合は何もしない
-		}
-	}()
-}
-
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -1,7 +1,26 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/radio24/host/internal/banter"
+	"github.com/radio24/host/internal/director"
+	"github.com/radio24/host/internal/lookahead"
+	"github.com/radio24/host/internal/persona"
+	"github.com/radio24/pkg/audio"
+	"github.com/radio24/pkg/dialogue"
+	"github.com/radio24/pkg/llm"
+	"github.com/radio24/pkg/mixer"
 )
 
 func TestMain(t *testing.T) {
@@ -9,3 +28,454 @@ func TestMain(t *testing.T) {
 	// This is a placeholder test that will pass
 	t.Log("Main package test passed")
 }
+
+func TestTimeSignalText(t *testing.T) {
+	cases := []struct {
+		hour  int
+		theme string
+		want  string
+	}{
+		{0, "", "午前0時をお知らせします。"},
+		{12, "", "正午をお知らせします。"},
+		{15, "ニュース", "午後3時をお知らせします。ここからは「ニュース」の時間です。"},
+	}
+	for _, c := range cases {
+		if got := timeSignalText(c.hour, c.theme); got != c.want {
+			t.Errorf("timeSignalText(%d, %q) = %q, want %q", c.hour, c.theme, got, c.want)
+		}
+	}
+}
+
+func TestTimeSignalStartsBeforeTheHour(t *testing.T) {
+	cases := []struct {
+		now, want time.Time
+	}{
+		{time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC), time.Date(2026, 10, 18, 9, 59, 58, 500000000, time.UTC)},
+		{time.Date(2026, 10, 18, 9, 59, 59, 0, time.UTC), time.Date(2026, 10, 18, 10, 59, 58, 500000000, time.UTC)},
+	}
+	for _, c := range cases {
+		if got := timeSignalStart(c.now); !got.Equal(c.want) {
+			t.Errorf("timeSignalStart(%s) = %s, want %s", c.now.Format("15:04:05"), got.Format("15:04:05.000"), c.want.Format("15:04:05.000"))
+		}
+	}
+}
+
+// newTestAgent Fake のLLMと空の応答を返すAPIで動くホスト
+func newTestAgent(t *testing.T, fake *llm.Fake) *HostAgent {
+	t.Helper()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(api.Close)
+	t.Setenv("API_BASE", api.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	h := &HostAgent{
+		ctx:            ctx,
+		cancel:         cancel,
+		llm:            llm.NewFakeProvider(fake),
+		personas:       persona.NewSet(),
+		outputFormat:   audio.FormatRealtime,
+		speechDone:     make(chan struct{}, 1),
+		timerResetChan: make(chan struct{}, 10),
+	}
+	h.setupProgramMixer()
+	h.setupDialogue()
+	h.director = director.New(nil, h)
+	h.lookahead = lookahead.New(h.renderScript, 1, time.Minute)
+	return h
+}
+
+func TestRenderScriptWithFakeProvider(t *testing.T) {
+	h := newTestAgent(t, llm.NewFake())
+
+	script, err := h.renderScript(context.Background(), "天気")
+	if err != nil {
+		t.Fatalf("renderScript() = %v", err)
+	}
+	if script.Text != llm.FakeReply || len(script.Lines) != 1 || len(script.Lines[0].Audio) == 0 {
+		t.Errorf("script = %+v", script)
+	}
+}
+
+// fixedSchedule いつでも同じ枠を返す時間割
+type fixedSchedule struct{ slot director.Slot }
+
+func (s fixedSchedule) SlotAt(context.Context, time.Time) (director.Slot, error) {
+	return s.slot, nil
+}
+
+func TestPrefetchedScriptLeavesOutRemainingTime(t *testing.T) {
+	fake := llm.NewFake()
+	h := newTestAgent(t, fake)
+	h.personaSource = persona.FileSource{Path: filepath.Join(t.TempDir(), "none.json")}
+	h.director = director.New(fixedSchedule{director.Slot{Block: director.BlockNews}}, h)
+	h.director.Tick(context.Background(), time.Now())
+
+	if _, err := h.renderScript(context.Background(), "天気"); err != nil {
+		t.Fatalf("renderScript() = %v", err)
+	}
+	chats := fake.Chats()
+	system := chats[len(chats)-1].System
+	if !strings.Contains(system, "このセグメント：ニュース。") || strings.Contains(system, "残り") {
+		t.Errorf("prefetch system prompt = %q, want segment name without remaining time", system)
+	}
+}
+
+func TestRenderBanterWithFakeProvider(t *testing.T) {
+	fake := llm.NewFake()
+	fake.Reply = func(req llm.ChatRequest) (string, error) {
+		return `{"lines":[{"speaker":"aoi","text":"こんばんは"},{"speaker":"けん","text":"晴れですね"}]}`, nil
+	}
+	h := newTestAgent(t, fake)
+	h.personas.Update(persona.Config{Personas: []persona.Persona{
+		{ID: "aoi", Name: "あおい", TTSVoice: "shimmer"},
+		{ID: "ken", Name: "けん", TTSVoice: "onyx"},
+	}})
+	h.personas.Select("aoi")
+	h.personas.SelectPartner("ken")
+
+	script, err := h.renderScript(context.Background(), "天気")
+	if err != nil {
+		t.Fatalf("renderScript() = %v", err)
+	}
+	if len(script.Lines) != 2 || script.Lines[1].Speaker != "ken" || len(script.Lines[1].Audio) == 0 {
+		t.Fatalf("lines = %+v", script.Lines)
+	}
+	if !strings.Contains(script.Text, "けん「晴れですね」") {
+		t.Errorf("text = %q", script.Text)
+	}
+	if chats := fake.Chats(); len(chats) != 1 || !chats[0].JSON {
+		t.Errorf("chats = %+v, want one JSON request", chats)
+	}
+}
+
+func TestPlayScriptStreamsUnrenderedLines(t *testing.T) {
+	h := newTestAgent(t, llm.NewFake())
+
+	script := h.composeScript(context.Background(), "天気", 0)
+	if len(script.Lines) != 1 || len(script.Lines[0].Audio) != 0 {
+		t.Fatalf("composed script = %+v, want one line without audio", script)
+	}
+	h.playScript(script)
+
+	// Fake のTTSは1文字20msの無音を返す
+	want := audio.FormatRealtime.Duration(len([]rune(llm.FakeReply)) * 480)
+	if got := h.player.Buffered(); got != want {
+		t.Errorf("buffered = %v, want %v", got, want)
+	}
+}
+
+func TestAvoidRepetitionChecksEveryAttempt(t *testing.T) {
+	fake := llm.NewFake()
+	h := newTestAgent(t, fake)
+
+	// 放送待ちの台本を1件用意しておく
+	h.lookahead = lookahead.New(func(ctx context.Context, topic string) (lookahead.Script, error) {
+		return lookahead.Script{Text: "放送待ち", Embedding: "[1,0]"}, nil
+	}, 1, time.Minute)
+	go h.lookahead.Run(h.ctx)
+	h.lookahead.Prefetch("天気")
+	for !h.lookahead.Ready() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 3回とも似ていると返し、2回目が最も似ていない
+	similarities := []float64{0.99, 0.92, 0.97}
+	var checks []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/scripts/check" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		var req struct {
+			Pending []struct{ Text string } `json:"pending"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Pending) != 1 || req.Pending[0].Text != "放送待ち" {
+			t.Errorf("pending = %+v, want the buffered script", req.Pending)
+		}
+		n := len(checks)
+		checks = append(checks, r.URL.Path)
+		fmt.Fprintf(w, `{"duplicate":true,"embedding":"[%d]","match":{"text":"前の台本","similarity":%v}}`, n, similarities[n])
+	}))
+	defer api.Close()
+	t.Setenv("API_BASE", api.URL)
+
+	lines, embedding := h.avoidRepetition(context.Background(), "天気の話", []banter.Line{{Text: "晴れです"}})
+	if len(checks) != maxRepeatRetries+1 {
+		t.Fatalf("checks = %d, want every attempt including the last one checked", len(checks))
+	}
+	if embedding != "[1]" || len(lines) != 1 {
+		t.Errorf("embedding = %q, want the least similar attempt", embedding)
+	}
+}
+
+// submissionsAPI 投稿を1件返すAPI
+func submissionsAPI(t *testing.T) {
+	t.Helper()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/submissions/relevant" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		if r.URL.Query().Get("topic") != "天気" {
+			t.Errorf("topic = %q, want 天気", r.URL.Query().Get("topic"))
+		}
+		w.Write([]byte(`{"submissions":[{"id":"sub_1","text":"雨が好きです","similarity":0.8}]}`))
+	}))
+	t.Cleanup(api.Close)
+	t.Setenv("API_BASE", api.URL)
+}
+
+func TestRelevantSubmissions(t *testing.T) {
+	h := newTestAgent(t, llm.NewFake())
+	submissionsAPI(t)
+
+	subs := h.relevantSubmissions("天気")
+	if len(subs) != 1 || subs[0].ID != "sub_1" || subs[0].Text != "雨が好きです" {
+		t.Errorf("submissions = %+v", subs)
+	}
+}
+
+func TestComposeScriptFeaturesSubmissionsOnlyWhenGenerated(t *testing.T) {
+	fake := llm.NewFake()
+	h := newTestAgent(t, fake)
+	submissionsAPI(t)
+
+	script := h.composeScript(context.Background(), "天気", 0)
+	if len(script.Submissions) != 1 || !strings.Contains(fake.Chats()[0].Prompt, "雨が好きです") {
+		t.Fatalf("submissions = %v, want the submission introduced in the script", script.Submissions)
+	}
+
+	// 生成に失敗したフォールバックの文では紹介していない
+	fake.Reply = func(req llm.ChatRequest) (string, error) {
+		return "", errors.New("connection refused")
+	}
+	script = h.composeScript(context.Background(), "天気", 0)
+	if len(script.Submissions) != 0 {
+		t.Errorf("fallback script submissions = %v, want none", script.Submissions)
+	}
+}
+
+func TestFlushCancelsPendingSubtitles(t *testing.T) {
+	h := newTestAgent(t, llm.NewFake())
+	subtitles := make(chan string, 10)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/subtitle" {
+			subtitles <- r.URL.Path
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer api.Close()
+	t.Setenv("API_BASE", api.URL)
+
+	// 前の行の音声が流れ終わる前に破棄されると、後ろの行は流れない
+	h.writeSpeech(make([]byte, audio.FormatRealtime.Samples(200*time.Millisecond)*2))
+	h.subtitleWhenAired(persona.Persona{ID: "aoi"}, "流れない行")
+	h.fadeOutAndFlush(mixer.BusVoice, h.player)
+
+	select {
+	case <-subtitles:
+		t.Error("subtitle sent for a line flushed before it aired")
+	case <-time.After(400 * time.Millisecond):
+	}
+}
+
+func TestCloseFitsRemainingTime(t *testing.T) {
+	fake := llm.NewFake()
+	h := newTestAgent(t, fake)
+
+	h.Close("トークのクロージング", 3*time.Second)
+	if chats := fake.Chats(); len(chats) != 0 {
+		t.Fatalf("closing with 3s left should be skipped, got %d chats", len(chats))
+	}
+
+	h.Close("トークのクロージング", 20*time.Second)
+	chats := fake.Chats()
+	if len(chats) != 1 || !strings.Contains(chats[0].Prompt, "20秒以内") {
+		t.Fatalf("chats = %+v, want one closing limited to 20s", chats)
+	}
+}
+
+func TestAutomationModeWhileUpstreamFails(t *testing.T) {
+	fake := llm.NewFake()
+	fake.Reply = func(req llm.ChatRequest) (string, error) {
+		return "", errors.New("connection refused")
+	}
+	h := newTestAgent(t, fake)
+	h.upstreamChanged = make(chan struct{}, 1)
+	t.Setenv("UPSTREAM_BREAKER_THRESHOLD", "1")
+	t.Setenv("UPSTREAM_BREAKER_COOLDOWN", "20ms")
+	h.setupUpstream(llm.NewFakeProvider(fake))
+	h.llm = llm.Resilient(llm.NewFakeProvider(fake), llm.RetryPolicy{Attempts: 1}, h.breaker) // リトライを待たない
+
+	// 台本の生成に失敗したらフォールバックの文は喋らず、音楽ベッドでつなぐ
+	h.generateAndSpeakScript("天気")
+	if h.upstreamState() != llm.BreakerOpen || !h.automationBed {
+		t.Fatalf("upstream = %s, automation bed = %v", h.upstreamState(), h.automationBed)
+	}
+	if got := h.player.Buffered(); got != 0 {
+		t.Errorf("buffered = %v, want no speech while degraded", got)
+	}
+	<-h.upstreamChanged
+
+	// 冷却期間が明けた次の発話で回復を確かめ、通常進行に戻る
+	fake.Reply = nil
+	time.Sleep(30 * time.Millisecond)
+	h.generateAndSpeakScript("天気")
+	<-h.upstreamChanged
+	h.applyUpstreamState()
+	if h.upstreamState() != llm.BreakerClosed || h.bedPlaying {
+		t.Errorf("upstream = %s, bed playing = %v after recovery", h.upstreamState(), h.bedPlaying)
+	}
+	if h.player.Buffered() == 0 {
+		t.Error("no speech after recovery")
+	}
+}
+
+func TestDialogueTimeoutEndsCallAndNotifiesAPI(t *testing.T) {
+	h := newTestAgent(t, llm.NewFake())
+	h.dialogue.IdleTimeout = time.Millisecond
+
+	h.dialogue.Accept("dialogue_1", "client_1")
+	h.dialogue.Fire("dialogue_1", dialogue.EventConnected, "")
+	time.Sleep(5 * time.Millisecond)
+
+	expired := h.dialogue.Expire()
+	if len(expired) != 1 {
+		t.Fatalf("expired = %+v", expired)
+	}
+	h.endDialogueMode(expired[0].Reason)
+
+	// Hostで起きた遷移はすべてAPIへ送る順に並んでいる
+	var got []dialogue.State
+	for _, t := range h.dialogueEvents {
+		got = append(got, t.To)
+	}
+	want := []dialogue.State{dialogue.Connecting, dialogue.OnAir, dialogue.WrappingUp, dialogue.Ended}
+	if len(got) != len(want) {
+		t.Fatalf("transitions = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("transitions = %v, want %v", got, want)
+		}
+	}
+	if s, _ := h.dialogue.Get("dialogue_1"); s.Reason != dialogue.ReasonTimeout {
+		t.Errorf("reason = %q, want timeout", s.Reason)
+	}
+	if h.inDialogue() {
+		t.Error("still in dialogue after timeout")
+	}
+}
+
+func TestEndedWhileConnectingTearsDown(t *testing.T) {
+	h := newTestAgent(t, llm.NewFake())
+	h.dialogue.Accept("dialogue_1", "client_1")
+	h.delay.Set(7*time.Second, nil)
+
+	// リスナーが取り下げ、APIから接続中の通話の終了が届く
+	if _, err := h.applyDialogueEvent(dialogue.Transition{ID: "dialogue_1", Event: dialogue.EventEnd, Reason: dialogue.ReasonRequesterEnded}); err != nil {
+		t.Fatalf("applyDialogueEvent() = %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for h.delay.State().Target != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if target := h.delay.State().Target; target != 0 {
+		t.Errorf("delay target = %v, want removed after the call ended", target)
+	}
+	if h.inDialogue() {
+		t.Error("still in dialogue")
+	}
+}
+
+func TestDialogueTransitionsRetriedUntilAccepted(t *testing.T) {
+	h := newTestAgent(t, llm.NewFake())
+	var (
+		mu   sync.Mutex
+		sent []dialogue.Event
+	)
+	failed := false
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !failed {
+			// 1回目はAPIの障害
+			failed = true
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		var tr dialogue.Transition
+		json.NewDecoder(r.Body).Decode(&tr)
+		sent = append(sent, tr.Event)
+		w.Write([]byte(`{}`))
+	}))
+	defer api.Close()
+	t.Setenv("API_BASE", api.URL)
+
+	// 送る側が動き出す前に起きた遷移も捨てない
+	h.dialogue.Accept("dialogue_1", "client_1")
+	h.dialogue.Fire("dialogue_1", dialogue.EventConnected, "")
+	h.dialogue.Fire("dialogue_1", dialogue.EventEnd, "")
+	go h.forwardDialogueTransitions()
+
+	want := []dialogue.Event{dialogue.EventAccept, dialogue.EventConnected, dialogue.EventEnd}
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(sent)
+		mu.Unlock()
+		if n == len(want) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != len(want) {
+		t.Fatalf("sent = %v, want %v after retrying the failed one", sent, want)
+	}
+	for i := range want {
+		if sent[i] != want[i] {
+			t.Fatalf("sent = %v, want %v in order", sent, want)
+		}
+	}
+}
+
+func TestDumpDelayOutsideCallKeepsOutro(t *testing.T) {
+	h := newTestAgent(t, llm.NewFake())
+	frame := make([]int16, 480)
+	h.delay.Set(time.Second, nil)
+	for i := 0; i < 3; i++ {
+		h.delay.WriteSample(frame)
+	}
+	// 通話が終わって生に戻る途中（締めの挨拶がまだ残っている）
+	h.delay.Set(0, nil)
+
+	if _, ok := h.dumpDelay(); ok {
+		t.Fatal("dumpDelay() dumped outside a call")
+	}
+	if buffered := h.delay.State().Buffered; buffered == 0 {
+		t.Error("outro was thrown away")
+	}
+}
+
+func TestUpdateMixerValidatesBeforeApplying(t *testing.T) {
+	h := &HostAgent{mixer: mixer.NewMixer()}
+	defer h.mixer.Stop()
+	h.mixer.AddBus(mixer.BusVoice, nil, false)
+
+	err := h.updateMixer(MixerUpdateRequest{Buses: map[string]float64{
+		mixer.BusVoice: -6,
+		"unknown":      -6,
+	}})
+	if err == nil {
+		t.Fatal("updateMixer() with an unknown bus should fail")
+	}
+	if gain := h.mixer.Snapshot().Buses[0].GainDB; gain != 0 {
+		t.Errorf("voice gain = %v, want unchanged 0", gain)
+	}
+}